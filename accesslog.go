@@ -0,0 +1,133 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogConfig selects the optional fields AccessLog appends to each
+// Combined Log Format line.
+type AccessLogConfig struct {
+	// Duration appends the time taken to serve the request in
+	// microseconds, like Apache's %D.
+	Duration bool
+	// RequestID appends the request ID assigned by the RequestID
+	// middleware, quoted, or "-" when there is none.
+	RequestID bool
+}
+
+// AccessLog writes one Apache Combined Log Format line per request to out:
+//
+//	host - user [time] "request" status bytes "referer" "user-agent"
+//
+// The host is ClientIP, so install RealIPMiddleware first when running behind
+// proxies. The user is the Basic auth username, if any. Writes to out are
+// serialized.
+func AccessLog(out io.Writer, cfg AccessLogConfig) Middleware {
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			cw := getCommitWriter(w)
+			defer func() {
+				buf := clfBufferPool.Get().(*[]byte)
+				line := appendCLF((*buf)[:0], r, cw, start, cfg)
+				releaseCommitWriter(cw)
+				mu.Lock()
+				out.Write(line)
+				mu.Unlock()
+				*buf = line
+				clfBufferPool.Put(buf)
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+var clfBufferPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 256)
+	return &b
+}}
+
+func appendCLF(b []byte, r *http.Request, cw *commitWriter, start time.Time, cfg AccessLogConfig) []byte {
+	status := cw.status
+	if !cw.committed {
+		status = http.StatusOK
+	}
+
+	if ip := ClientIP(r); ip.IsValid() {
+		b = ip.AppendTo(b)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, " - "...)
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		b = appendCLFEscaped(b, user)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, " ["...)
+	b = start.AppendFormat(b, clfTimeFormat)
+	b = append(b, "] \""...)
+	b = appendCLFEscaped(b, r.Method)
+	b = append(b, ' ')
+	b = appendCLFEscaped(b, r.RequestURI)
+	b = append(b, ' ')
+	b = appendCLFEscaped(b, r.Proto)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, ' ')
+	if cw.written > 0 {
+		b = strconv.AppendInt(b, cw.written, 10)
+	} else {
+		b = append(b, '-')
+	}
+	b = appendCLFQuoted(b, r.Referer())
+	b = appendCLFQuoted(b, r.UserAgent())
+	if cfg.Duration {
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, time.Since(start).Microseconds(), 10)
+	}
+	if cfg.RequestID {
+		id := RequestIDFromContext(r.Context())
+		if id == "" {
+			// RequestID installed inside AccessLog: take the echoed header.
+			id = cw.Header().Get(RequestIDHeader)
+		}
+		b = appendCLFQuoted(b, id)
+	}
+	return append(b, '\n')
+}
+
+func appendCLFQuoted(b []byte, s string) []byte {
+	b = append(b, " \""...)
+	if s == "" {
+		b = append(b, '-')
+	} else {
+		b = appendCLFEscaped(b, s)
+	}
+	return append(b, '"')
+}
+
+// appendCLFEscaped escapes quotes, backslashes and control characters the
+// way Apache does, so client supplied values cannot forge log lines.
+func appendCLFEscaped(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20 || c == 0x7f:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
@@ -0,0 +1,269 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// alarmBuckets is the number of slices a window is counted in; the window
+// slides by one slice at a time.
+const alarmBuckets = 10
+
+type (
+	// AlarmConfig configures NewErrorAlarms. Zero values select the
+	// defaults noted on each field.
+	AlarmConfig struct {
+		// Window is the sliding period rates are measured over; 1m if zero.
+		Window time.Duration
+		// MinRequests is the number of requests a route needs within Window
+		// before it can alarm; 20 if zero.
+		MinRequests int
+		// ErrorRate alarms when the share of 5xx responses reaches it; 0.5
+		// if zero.
+		ErrorRate float64
+		// PanicRate alarms when the share of requests that panicked reaches
+		// it; 0.05 if zero.
+		PanicRate float64
+		// Cooldown is how long Guard rejects requests to a route after its
+		// alarm fired; 30s if zero.
+		Cooldown time.Duration
+		// OnAlarm is called when an alarm fires and again, with Firing
+		// false, when it resolves. It runs on the request's goroutine and
+		// should not block.
+		OnAlarm func(Alarm)
+	}
+
+	// Alarm describes the rates of a route, or of all routes when Route is
+	// empty, over the alarm window.
+	Alarm struct {
+		Route     string
+		Firing    bool
+		Requests  int
+		Errors    int
+		Panics    int
+		ErrorRate float64
+		PanicRate float64
+	}
+
+	// ErrorAlarms tracks the 5xx and panic rates of the routes served by a
+	// HandlerAdapter, see NewErrorAlarms.
+	ErrorAlarms struct {
+		cfg    AlarmConfig
+		bucket time.Duration
+
+		mu     sync.Mutex
+		global *alarmCounter
+		routes map[string]*alarmCounter
+	}
+
+	alarmCounter struct {
+		buckets [alarmBuckets]alarmBucket
+		firing  bool
+		// until is when a Guard cooldown ends.
+		until time.Time
+	}
+
+	alarmBucket struct {
+		slot                     int64
+		requests, errors, panics int
+	}
+)
+
+// NewErrorAlarms tracks the 5xx and panic rates of every route served by
+// adapter's Handle and Wrap, and of all of them together, over a sliding
+// window, calling cfg.OnAlarm when a rate crosses its limit. Routes are
+// told apart by their pattern, so requests no router matched only count
+// globally. Panics are counted as HandleError sees them, which excludes
+// panics after the response was committed.
+//
+// Deployments can page on alarms, or stop serving a failing route with
+// Guard. Alarms must be created before serving.
+func NewErrorAlarms(adapter *HandlerAdapter, cfg AlarmConfig) *ErrorAlarms {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.ErrorRate <= 0 {
+		cfg.ErrorRate = 0.5
+	}
+	if cfg.PanicRate <= 0 {
+		cfg.PanicRate = 0.05
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+
+	al := &ErrorAlarms{
+		cfg:    cfg,
+		bucket: cfg.Window / alarmBuckets,
+		global: &alarmCounter{},
+		routes: map[string]*alarmCounter{},
+	}
+	adapter.OnError(func(r *http.Request, err error) {
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			al.record(r, func(b *alarmBucket) { b.panics++ })
+		}
+	})
+	adapter.OnResponse(func(r *http.Request, status int, _ time.Duration) {
+		al.record(r, func(b *alarmBucket) {
+			b.requests++
+			if status >= 500 {
+				b.errors++
+			}
+		})
+	})
+	return al
+}
+
+// Stats returns the current rates of route, or of all routes when route is
+// empty.
+func (al *ErrorAlarms) Stats(route string) Alarm {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	c := al.global
+	if route != "" {
+		c = al.routes[route]
+		if c == nil {
+			return Alarm{Route: route}
+		}
+	}
+	return c.alarm(route, al.slot(clockNow()))
+}
+
+// Guard rejects requests to a route with a 503 AppError (code
+// "route_unavailable") for AlarmConfig.Cooldown after its alarm fired,
+// sparing clients and dependencies a route that keeps failing. Once the
+// cooldown ends the route's rates start over, so it alarms again if it is
+// still failing. Guard must run after routing, e.g. wrapping the handlers
+// of a Group, to see the route pattern.
+func (al *ErrorAlarms) Guard(adapter *HandlerAdapter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wait := al.cooldown(alarmRoute(r)); wait > 0 {
+				adapter.HandleError(w, r, AppError{
+					Err:        errors.New("route temporarily unavailable"),
+					StatusCode: http.StatusServiceUnavailable,
+					Code:       "route_unavailable",
+					RetryAfter: wait,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// cooldown returns how long requests to route are still rejected, resetting
+// the route once its cooldown ended.
+func (al *ErrorAlarms) cooldown(route string) time.Duration {
+	if route == "" {
+		return 0
+	}
+	al.mu.Lock()
+	c := al.routes[route]
+	if c == nil || c.until.IsZero() {
+		al.mu.Unlock()
+		return 0
+	}
+	now := clockNow()
+	if wait := c.until.Sub(now); wait > 0 {
+		al.mu.Unlock()
+		return wait
+	}
+	*c = alarmCounter{}
+	resolved := Alarm{Route: route}
+	al.mu.Unlock()
+
+	if al.cfg.OnAlarm != nil {
+		al.cfg.OnAlarm(resolved)
+	}
+	return 0
+}
+
+// record counts a request event globally and for its route, and notifies
+// OnAlarm of alarms that fired or resolved.
+func (al *ErrorAlarms) record(r *http.Request, count func(*alarmBucket)) {
+	now := clockNow()
+	slot := al.slot(now)
+	route := alarmRoute(r)
+
+	var changed [2]Alarm
+	n := 0
+	al.mu.Lock()
+	if alarm, ok := al.update(al.global, "", slot, now, count); ok {
+		changed[n], n = alarm, n+1
+	}
+	if route != "" {
+		c := al.routes[route]
+		if c == nil {
+			c = &alarmCounter{}
+			al.routes[route] = c
+		}
+		if alarm, ok := al.update(c, route, slot, now, count); ok {
+			changed[n], n = alarm, n+1
+		}
+	}
+	al.mu.Unlock()
+
+	if al.cfg.OnAlarm != nil {
+		for _, alarm := range changed[:n] {
+			al.cfg.OnAlarm(alarm)
+		}
+	}
+}
+
+// update counts an event in c and reports the alarm when it fired or
+// resolved.
+func (al *ErrorAlarms) update(c *alarmCounter, route string, slot int64, now time.Time, count func(*alarmBucket)) (Alarm, bool) {
+	b := &c.buckets[slot%alarmBuckets]
+	if b.slot != slot {
+		*b = alarmBucket{slot: slot}
+	}
+	count(b)
+
+	alarm := c.alarm(route, slot)
+	firing := alarm.Requests >= al.cfg.MinRequests &&
+		(alarm.ErrorRate >= al.cfg.ErrorRate || alarm.PanicRate >= al.cfg.PanicRate)
+	if firing == c.firing {
+		return Alarm{}, false
+	}
+	c.firing, alarm.Firing = firing, firing
+	if firing && route != "" {
+		c.until = now.Add(al.cfg.Cooldown)
+	}
+	return alarm, true
+}
+
+func (al *ErrorAlarms) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(al.bucket)
+}
+
+// alarm sums the buckets of c within the window ending at slot.
+func (c *alarmCounter) alarm(route string, slot int64) Alarm {
+	alarm := Alarm{Route: route, Firing: c.firing}
+	for _, b := range c.buckets {
+		if b.slot > slot-alarmBuckets {
+			alarm.Requests += b.requests
+			alarm.Errors += b.errors
+			alarm.Panics += b.panics
+		}
+	}
+	if alarm.Requests > 0 {
+		alarm.ErrorRate = float64(alarm.Errors) / float64(alarm.Requests)
+		alarm.PanicRate = float64(alarm.Panics) / float64(alarm.Requests)
+	}
+	return alarm
+}
+
+// alarmRoute is the pattern of the route serving r, empty when unrouted.
+func alarmRoute(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return routePattern(r.Context())
+}
@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// Assets serves static files under content-hashed names so they can be
+// cached forever: "app.js" is published as "app.9f3a1c2e.js" and a new
+// deploy changing the file changes its URL.
+type Assets struct {
+	fsys   fs.FS
+	prefix string
+
+	// manifest maps logical names to fingerprinted names, reverse the other
+	// way around.
+	manifest map[string]string
+	reverse  map[string]string
+}
+
+// NewAssets fingerprints every file in fsys (typically an embed.FS). URLs are
+// generated under prefix, e.g. "/static/".
+func NewAssets(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		fsys:     fsys,
+		prefix:   "/" + strings.Trim(prefix, "/") + "/",
+		manifest: map[string]string{},
+		reverse:  map[string]string{},
+	}
+	if a.prefix == "//" {
+		a.prefix = "/"
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return fmt.Errorf("hashing asset %s: %w", name, err)
+		}
+
+		ext := path.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(h.Sum(nil))[:8] + ext
+		a.manifest[name] = hashed
+		a.reverse[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building asset manifest: %w", err)
+	}
+
+	return a, nil
+}
+
+// AssetPath returns the fingerprinted URL path of the named asset. Unknown
+// names are returned unhashed so a typo shows up as a 404 rather than a
+// template error.
+func (a *Assets) AssetPath(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := a.manifest[name]; ok {
+		return a.prefix + hashed
+	}
+	return a.prefix + name
+}
+
+// Manifest returns a copy of the logical to fingerprinted name mapping.
+func (a *Assets) Manifest() map[string]string {
+	m := make(map[string]string, len(a.manifest))
+	for k, v := range a.manifest {
+		m[k] = v
+	}
+	return m
+}
+
+// FuncMap exposes AssetPath to templates as {{ asset "app.js" }}.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{"asset": a.AssetPath}
+}
+
+// ServeHTTP serves assets below the prefix. Fingerprinted names get
+// far-future immutable caching; logical names are still served but must be
+// revalidated.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, a.prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if logical, ok := a.reverse[name]; ok {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+		name = logical
+	} else if _, ok := a.manifest[name]; ok {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeFileFS(w, r, a.fsys, name)
+}
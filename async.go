@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultAsyncQueueSize is the queue capacity of NewAsyncReporter unless
+// overridden.
+const DefaultAsyncQueueSize = 1024
+
+type (
+	// AsyncReporter hands errors to a background goroutine so slow reporting
+	// backends never add latency to requests. When the queue is full reports
+	// are dropped, or with WithAsyncBlock the caller waits.
+	AsyncReporter struct {
+		next  ErrorReporter
+		queue chan asyncReport
+		block bool
+
+		mu      sync.RWMutex
+		closed  bool
+		done    chan struct{}
+		dropped atomic.Int64
+	}
+
+	AsyncOption func(*AsyncReporter)
+
+	asyncReport struct {
+		ctx context.Context
+		err error
+	}
+)
+
+func WithAsyncQueueSize(n int) AsyncOption {
+	return func(a *AsyncReporter) {
+		a.queue = make(chan asyncReport, n)
+	}
+}
+
+// WithAsyncBlock makes ReportError wait for queue space instead of dropping.
+func WithAsyncBlock() AsyncOption {
+	return func(a *AsyncReporter) {
+		a.block = true
+	}
+}
+
+// NewAsyncReporter starts the goroutine forwarding reports to next. Close
+// it on shutdown, e.g. with Server.OnShutdown("reporter", r.Close).
+func NewAsyncReporter(next ErrorReporter, opts ...AsyncOption) *AsyncReporter {
+	a := &AsyncReporter{
+		next:  next,
+		queue: make(chan asyncReport, DefaultAsyncQueueSize),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	go a.run()
+	return a
+}
+
+// ReportError queues err. The context is detached from cancellation since
+// the request has usually finished by the time the report is sent. Reports
+// after Close are dropped.
+func (a *AsyncReporter) ReportError(ctx context.Context, err error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+
+	r := asyncReport{ctx: context.WithoutCancel(ctx), err: err}
+	if a.block {
+		a.queue <- r
+		return
+	}
+	select {
+	case a.queue <- r:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of reports lost to a full queue or to Close.
+func (a *AsyncReporter) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Close stops accepting reports and waits until the queued ones were sent
+// or ctx is done.
+func (a *AsyncReporter) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *AsyncReporter) run() {
+	defer close(a.done)
+	for r := range a.queue {
+		a.next.ReportError(r.ctx, r.err)
+	}
+}
@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type (
+	// Principal is the authenticated identity attached to a request.
+	Principal struct {
+		Subject string
+		Scopes  []string
+		Roles   []string
+		// Attributes carries authenticator specific data (e.g. token claims).
+		Attributes map[string]interface{}
+	}
+
+	// Authenticator resolves the Principal for a request. Implementations
+	// return ErrNoCredentials when the request carries no credentials at all,
+	// and an AppError (usually 401) when the credentials are invalid.
+	Authenticator interface {
+		Authenticate(r *http.Request) (*Principal, error)
+	}
+
+	AuthenticatorFunc func(r *http.Request) (*Principal, error)
+
+	// Challenger is implemented by authenticators that advertise a
+	// WWW-Authenticate challenge on 401 responses.
+	Challenger interface {
+		Challenge() string
+	}
+
+	// CredentialHeaderer is implemented by authenticators reading
+	// credentials from request headers other than Authorization, so the
+	// routes they guard declare them to CORS (see DescribeRoute).
+	CredentialHeaderer interface {
+		CredentialHeaders() []string
+	}
+)
+
+var ErrNoCredentials = errors.New("no credentials")
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return f(r)
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return principalKey.WithValue(ctx, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := principalKey.Value(ctx)
+	return p, ok && p != nil
+}
+
+// AuthMiddleware authenticates every request and stores the Principal in the
+// request context. Requests without valid credentials are rejected through
+// the adapter.
+func AuthMiddleware(adapter *HandlerAdapter, authn Authenticator) Middleware {
+	return authMiddleware(adapter, authn, true)
+}
+
+// OptionalAuthMiddleware behaves like AuthMiddleware but lets requests without
+// any credentials through anonymously. Invalid credentials are still rejected.
+func OptionalAuthMiddleware(adapter *HandlerAdapter, authn Authenticator) Middleware {
+	return authMiddleware(adapter, authn, false)
+}
+
+func authMiddleware(adapter *HandlerAdapter, authn Authenticator, required bool) Middleware {
+	info := RouteInfo{Headers: credentialHeaders(authn), Credentials: true}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := authn.Authenticate(r)
+			if errors.Is(err, ErrNoCredentials) && !required {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err == nil && p == nil {
+				err = ErrNoCredentials
+			}
+
+			if err != nil {
+				if errors.Is(err, ErrNoCredentials) {
+					err = UnauthorizedError("authentication required").WithCode("unauthenticated")
+				}
+				if c, ok := authn.(Challenger); ok {
+					if e, ok := err.(AppError); ok && e.StatusCode == http.StatusUnauthorized {
+						w.Header().Set("WWW-Authenticate", c.Challenge())
+					}
+				}
+				adapter.HandleError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	})
+}
+
+// credentialHeaders lists the request headers authn reads credentials from:
+// those of a CredentialHeaderer, Authorization otherwise.
+func credentialHeaders(authn Authenticator) []string {
+	if h, ok := authn.(CredentialHeaderer); ok {
+		return h.CredentialHeaders()
+	}
+	return []string{"Authorization"}
+}
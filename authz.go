@@ -0,0 +1,84 @@
+package httpx
+
+import (
+	"net/http"
+	"slices"
+)
+
+type (
+	// Authorizer decides whether an authenticated Principal may perform
+	// the request. It returns nil to allow it and an error, usually a 403
+	// AppError, to deny it.
+	Authorizer interface {
+		Authorize(r *http.Request, p *Principal) error
+	}
+
+	AuthorizerFunc func(r *http.Request, p *Principal) error
+)
+
+func (f AuthorizerFunc) Authorize(r *http.Request, p *Principal) error {
+	return f(r, p)
+}
+
+// Authorize checks every request against authz, on top of the Principal
+// stored by AuthMiddleware or Security. Requests without a Principal get a
+// 401 AppError (code "unauthenticated"); denials are passed to the adapter
+// as returned.
+func Authorize(adapter *HandlerAdapter, authz Authorizer) Middleware {
+	info := RouteInfo{Credentials: true}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				adapter.HandleError(w, r, UnauthorizedError("authentication required").WithCode("unauthenticated"))
+				return
+			}
+			if err := authz.Authorize(r, p); err != nil {
+				adapter.HandleError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+// RequireScopes lets through principals holding all scopes. Others get a
+// 403 AppError whose code names the first missing scope, e.g.
+// "insufficient_scope:orders.write".
+func RequireScopes(adapter *HandlerAdapter, scopes ...string) Middleware {
+	return Authorize(adapter, ScopesAuthorizer(scopes...))
+}
+
+// RequireRoles lets through principals holding at least one of roles.
+// Others get a 403 AppError with code "insufficient_role:" followed by the
+// first role.
+func RequireRoles(adapter *HandlerAdapter, roles ...string) Middleware {
+	return Authorize(adapter, RolesAuthorizer(roles...))
+}
+
+// ScopesAuthorizer is the Authorizer of RequireScopes.
+func ScopesAuthorizer(scopes ...string) Authorizer {
+	return AuthorizerFunc(func(_ *http.Request, p *Principal) error {
+		for _, scope := range scopes {
+			if !slices.Contains(p.Scopes, scope) {
+				return ForbiddenError("missing scope %q", scope).WithCode("insufficient_scope:" + scope)
+			}
+		}
+		return nil
+	})
+}
+
+// RolesAuthorizer is the Authorizer of RequireRoles.
+func RolesAuthorizer(roles ...string) Authorizer {
+	return AuthorizerFunc(func(_ *http.Request, p *Principal) error {
+		if len(roles) == 0 {
+			return nil
+		}
+		for _, role := range roles {
+			if slices.Contains(p.Roles, role) {
+				return nil
+			}
+		}
+		return ForbiddenError("requires one of the roles %q", roles).WithCode("insufficient_role:" + roles[0])
+	})
+}
@@ -0,0 +1,101 @@
+// Package autocert enables automatic TLS certificates from Let's Encrypt (or
+// any ACME CA) on an httpx.Server.
+package autocert
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/radim/httpx"
+)
+
+// Config configures certificate management.
+type Config struct {
+	// Hosts are the domains certificates may be requested for. Ignored when
+	// HostPolicy is set.
+	Hosts      []string
+	HostPolicy autocert.HostPolicy
+	// CacheDir persists certificates and the account key across restarts.
+	// Strongly recommended, CAs rate limit certificate issuance.
+	CacheDir string
+	Email    string
+	// DirectoryURL selects the ACME CA, Let's Encrypt production by default.
+	DirectoryURL string
+	// HTTPAddr is the address of the HTTP-01 challenge listener, ":80" by
+	// default. It redirects all other traffic to HTTPS. Set to "-" to
+	// disable it when challenges are answered elsewhere (TLS-ALPN-01 works
+	// on the TLS listener without it).
+	HTTPAddr string
+}
+
+// Option returns a server option obtaining and renewing certificates for the
+// configured hosts. The server then listens with TLS on its address.
+func Option(cfg Config) httpx.ServerOption {
+	return func(s *httpx.Server) {
+		m := Manager(cfg)
+		s.HTTP.TLSConfig = m.TLSConfig()
+
+		if cfg.HTTPAddr == "-" {
+			return
+		}
+		addr := cfg.HTTPAddr
+		if addr == "" {
+			addr = ":80"
+		}
+
+		challenge := &http.Server{
+			Addr:              addr,
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		s.OnStart(func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := challenge.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logf(s.HTTP.ErrorLog, "httpx/autocert: challenge listener: %v", err)
+				}
+			}()
+			return nil
+		})
+		s.OnShutdown("autocert", func(ctx context.Context) error {
+			return challenge.Shutdown(ctx)
+		})
+	}
+}
+
+// Manager builds the autocert.Manager for cfg, for setups that wire it
+// themselves.
+func Manager(cfg Config) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: cfg.HostPolicy,
+		Email:      cfg.Email,
+	}
+	if m.HostPolicy == nil {
+		m.HostPolicy = autocert.HostWhitelist(cfg.Hosts...)
+	}
+	if cfg.CacheDir != "" {
+		m.Cache = autocert.DirCache(cfg.CacheDir)
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+	return m
+}
+
+func logf(l *log.Logger, format string, args ...interface{}) {
+	if l == nil {
+		l = log.Default()
+	}
+	l.Printf(format, args...)
+}
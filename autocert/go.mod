@@ -0,0 +1,15 @@
+module github.com/radim/httpx/autocert
+
+go 1.24
+
+require (
+	github.com/radim/httpx v0.0.0
+	golang.org/x/crypto v0.36.0
+)
+
+require (
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
+
+replace github.com/radim/httpx => ../
@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+type (
+	// BatchResult collects the outcome of every item of a bulk request, so
+	// one bad item does not fail the whole request. Record items with
+	// Succeed and Fail, concurrently if needed, then write the result with
+	// Respond.
+	//
+	//	batch := httpx.NewBatchResult()
+	//	for _, u := range users {
+	//		if err := store.Create(ctx, u); err != nil {
+	//			if err := batch.Fail(u.ID, err); err != nil {
+	//				return err
+	//			}
+	//			continue
+	//		}
+	//		batch.Succeed(u.ID, http.StatusCreated, u)
+	//	}
+	//	return batch.Respond(w, r)
+	BatchResult struct {
+		mu    sync.Mutex
+		items []BatchItem
+	}
+
+	// BatchItem is the outcome of one item.
+	BatchItem struct {
+		ID     string      `json:"id"`
+		Status int         `json:"status"`
+		Result interface{} `json:"result,omitempty"`
+		Error  string      `json:"error,omitempty"`
+		Code   string      `json:"code,omitempty"`
+		// Fields lists the failed constraints of a ValidationError.
+		Fields []FieldError `json:"fields,omitempty"`
+	}
+
+	batchBody struct {
+		Status    int         `json:"status"`
+		Succeeded int         `json:"succeeded"`
+		Failed    int         `json:"failed"`
+		Items     []BatchItem `json:"items"`
+	}
+)
+
+func NewBatchResult() *BatchResult {
+	return &BatchResult{}
+}
+
+// Succeed records a successful item with its status and optional result.
+func (b *BatchResult) Succeed(id string, status int, result interface{}) {
+	b.add(BatchItem{ID: id, Status: status, Result: result})
+}
+
+// Fail records an item that failed with an AppError. Other errors are not
+// recorded but returned, so the handler can abort the whole request and
+// let the adapter handle them as internal errors. Messages of 5xx
+// AppErrors are replaced by the status text.
+func (b *BatchResult) Fail(id string, err error) error {
+	var appErr AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+	item := BatchItem{ID: id, Status: appErr.StatusCode, Code: appErr.Code}
+	if appErr.StatusCode < 500 {
+		item.Error = appErr.Error()
+		if v, ok := AsValidationError(appErr); ok {
+			item.Fields = v.Fields
+		}
+	} else {
+		item.Error = http.StatusText(appErr.StatusCode)
+	}
+	b.add(item)
+	return nil
+}
+
+// Status returns the overall status: 200 when every item succeeded, 207
+// Multi-Status otherwise.
+func (b *BatchResult) Status() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, item := range b.items {
+		if item.Status >= 400 {
+			return http.StatusMultiStatus
+		}
+	}
+	return http.StatusOK
+}
+
+// Items returns the recorded items in recording order.
+func (b *BatchResult) Items() []BatchItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BatchItem(nil), b.items...)
+}
+
+// Respond writes the result with Respond and Status. The body holds the
+// overall status, the number of succeeded and failed items and the items.
+func (b *BatchResult) Respond(w http.ResponseWriter, r *http.Request, codecs ...Codec) error {
+	body := batchBody{Status: b.Status(), Items: b.Items()}
+	if body.Items == nil {
+		body.Items = []BatchItem{}
+	}
+	for _, item := range body.Items {
+		if item.Status >= 400 {
+			body.Failed++
+		} else {
+			body.Succeeded++
+		}
+	}
+	return Respond(w, r, body.Status, body, codecs...)
+}
+
+func (b *BatchResult) add(item BatchItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = append(b.items, item)
+}
@@ -0,0 +1,290 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultSpillThreshold is the file size above which BindMultipart moves
+// uploads from memory to a temporary file, unless overridden.
+const DefaultSpillThreshold = 1 << 20
+
+type (
+	// UploadedFile is a file received by BindMultipart. Small files are kept
+	// in memory, larger ones in a temporary file removed when the request
+	// ends.
+	UploadedFile struct {
+		FileName string
+		Header   textproto.MIMEHeader
+		Size     int64
+		// ContentType is sniffed from the content, not taken from the client.
+		ContentType string
+
+		data []byte
+		path string
+	}
+
+	MultipartOption func(*multipartConfig)
+
+	multipartConfig struct {
+		maxFileSize    int64
+		maxTotalSize   int64
+		maxParts       int
+		spillThreshold int64
+		tempDir        string
+		allowedTypes   []string
+	}
+)
+
+// WithMaxFileSize limits each uploaded file. Defaults to DefaultMaxPartSize.
+func WithMaxFileSize(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxFileSize = n
+	}
+}
+
+// WithMaxTotalSize limits the sum of all parts. Zero means no limit.
+func WithMaxTotalSize(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxTotalSize = n
+	}
+}
+
+func WithMaxFormParts(n int) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxParts = n
+	}
+}
+
+// WithSpillThreshold sets the size above which files go to a temporary
+// file in dir (os.TempDir when empty).
+func WithSpillThreshold(n int64, dir string) MultipartOption {
+	return func(c *multipartConfig) {
+		c.spillThreshold = n
+		c.tempDir = dir
+	}
+}
+
+// WithAllowedTypes restricts files to the given sniffed media types. A
+// trailing slash matches a whole family ("image/").
+func WithAllowedTypes(types ...string) MultipartOption {
+	return func(c *multipartConfig) {
+		c.allowedTypes = types
+	}
+}
+
+// BindMultipart streams a multipart/form-data body into dst, a pointer to a
+// struct whose fields are tagged `form:"name"`. Fields may be strings,
+// bools, numbers, slices of those, *UploadedFile or []*UploadedFile.
+// Unknown parts are discarded.
+//
+// Malformed bodies, disallowed file types and unparsable values are 400
+// AppErrors; exceeded limits are 413.
+func BindMultipart(r *http.Request, dst interface{}, opts ...MultipartOption) error {
+	cfg := multipartConfig{maxFileSize: DefaultMaxPartSize, spillThreshold: DefaultSpillThreshold}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("httpx: BindMultipart needs a pointer to a struct, got %T", dst)
+	}
+	fields := formFields(v.Elem())
+
+	var total int64
+	for part, err := range Parts(r, WithMaxPartSize(cfg.maxFileSize), WithMaxParts(cfg.maxParts)) {
+		if err != nil {
+			return err
+		}
+
+		field, ok := fields[part.FormName]
+		if !ok {
+			continue
+		}
+
+		body := part.Body
+		if cfg.maxTotalSize > 0 {
+			body = io.LimitReader(body, cfg.maxTotalSize-total+1)
+		}
+
+		if part.IsFile() {
+			f, err := cfg.receiveFile(r.Context(), part, body)
+			if err != nil {
+				return err
+			}
+			total += f.Size
+			if cfg.maxTotalSize > 0 && total > cfg.maxTotalSize {
+				return StatusError(http.StatusRequestEntityTooLarge, "multipart body exceeds %d bytes", cfg.maxTotalSize)
+			}
+			if err := setFile(field, f); err != nil {
+				return BadRequestError("field %q: %v", part.FormName, err)
+			}
+			continue
+		}
+
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		total += int64(len(b))
+		if cfg.maxTotalSize > 0 && total > cfg.maxTotalSize {
+			return StatusError(http.StatusRequestEntityTooLarge, "multipart body exceeds %d bytes", cfg.maxTotalSize)
+		}
+		if err := setFormValue(field, string(b)); err != nil {
+			return BadRequestError("field %q: %v", part.FormName, err)
+		}
+	}
+	return nil
+}
+
+// Open returns the file content.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	if f.path != "" {
+		return os.Open(f.path)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (c *multipartConfig) receiveFile(ctx context.Context, part Part, body io.Reader) (*UploadedFile, error) {
+	f := &UploadedFile{FileName: part.FileName, Header: part.Header}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	f.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	if !c.typeAllowed(f.ContentType) {
+		return nil, BadRequestError("file %q has disallowed type %s", part.FileName, f.ContentType).WithCode("file_type_not_allowed")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(head)
+	if _, err := io.CopyN(&buf, body, c.spillThreshold-int64(n)+1); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if int64(buf.Len()) <= c.spillThreshold {
+		f.data, f.Size = buf.Bytes(), int64(buf.Len())
+		return f, nil
+	}
+
+	tmp, err := os.CreateTemp(c.tempDir, "httpx-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	f.path = tmp.Name()
+	// Uploads live as long as the request.
+	context.AfterFunc(ctx, func() { os.Remove(f.path) })
+
+	f.Size, err = io.Copy(tmp, io.MultiReader(&buf, body))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (c *multipartConfig) typeAllowed(ctype string) bool {
+	if len(c.allowedTypes) == 0 {
+		return true
+	}
+	for _, t := range c.allowedTypes {
+		if t == ctype || (strings.HasSuffix(t, "/") && strings.HasPrefix(ctype, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// formFields maps form names to the settable fields of struct v.
+func formFields(v reflect.Value) map[string]reflect.Value {
+	fields := map[string]reflect.Value{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		if name == "-" || !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields[name] = v.Field(i)
+	}
+	return fields
+}
+
+var uploadedFileType = reflect.TypeOf((*UploadedFile)(nil))
+
+func setFile(field reflect.Value, f *UploadedFile) error {
+	switch {
+	case field.Type() == uploadedFileType:
+		field.Set(reflect.ValueOf(f))
+	case field.Kind() == reflect.Slice && field.Type().Elem() == uploadedFileType:
+		field.Set(reflect.Append(field, reflect.ValueOf(f)))
+	default:
+		return errors.New("unexpected file")
+	}
+	return nil
+}
+
+func setFormValue(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setScalar(elem, s); err != nil {
+			return err
+		}
+		field.Set(reflect.Append(field, elem))
+		return nil
+	}
+	return setScalar(field, s)
+}
+
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("invalid boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
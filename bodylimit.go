@@ -0,0 +1,37 @@
+package httpx
+
+import (
+	"net/http"
+)
+
+// BodyLimit limits request bodies to n bytes. Requests announcing a larger
+// Content-Length are rejected up front; bodies that turn out larger fail on
+// read with *http.MaxBytesError, which the adapter renders as a 413 AppError
+// when the handler returns it.
+func BodyLimit(adapter *HandlerAdapter, n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limitBody(adapter, w, r, n) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// limitBody wraps r.Body in place and reports whether the request may
+// proceed.
+func limitBody(adapter *HandlerAdapter, w http.ResponseWriter, r *http.Request, n int64) bool {
+	if r.ContentLength > n {
+		adapter.HandleError(w, r, bodyTooLargeError(n))
+		return false
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+	}
+	return true
+}
+
+func bodyTooLargeError(limit int64) AppError {
+	return StatusError(http.StatusRequestEntityTooLarge, "request body too large (max %d bytes)", limit).WithCode("body_too_large")
+}
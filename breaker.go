@@ -0,0 +1,230 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+type (
+	CircuitState int
+
+	// BreakerConfig configures NewBreakerTransport. Zero values select the
+	// defaults noted on each field.
+	BreakerConfig struct {
+		// ConsecutiveFailures opens the circuit after this many failures in
+		// a row; 5 if zero.
+		ConsecutiveFailures int
+		// FailureRate opens the circuit when the share of failures within
+		// Window reaches it, once MinRequests were seen. Disabled if zero.
+		FailureRate float64
+		MinRequests int
+		// Window is the period failure rates are measured over; 10s if zero.
+		Window time.Duration
+		// OpenFor is how long an open circuit fails fast before letting a
+		// probe through; 30s if zero.
+		OpenFor time.Duration
+		// IsFailure decides whether an attempt counts against the upstream.
+		// By default transport errors and 5xx responses do.
+		IsFailure func(resp *http.Response, err error) bool
+		// OnStateChange is called when a host's circuit changes state. It runs
+		// under the breaker's lock and must not block.
+		OnStateChange func(host string, from, to CircuitState)
+	}
+
+	// CircuitOpenError is returned while a host's circuit is open. Map it
+	// with errors.As, e.g. to a 503 AppError.
+	CircuitOpenError struct {
+		Host string
+		// RetryAt is when the circuit lets the next probe through.
+		RetryAt time.Time
+	}
+
+	breakerTransport struct {
+		next http.RoundTripper
+		cfg  BreakerConfig
+
+		mu    sync.Mutex
+		hosts map[string]*circuit
+	}
+
+	circuit struct {
+		state       CircuitState
+		consecutive int
+		windowStart time.Time
+		requests    int
+		failures    int
+		openedAt    time.Time
+		probing     bool
+	}
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// CircuitStates returns the state of every host's circuit in a transport
+// built by NewBreakerTransport, or nil for any other RoundTripper.
+func CircuitStates(rt http.RoundTripper) map[string]CircuitState {
+	t, ok := rt.(*breakerTransport)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	states := make(map[string]CircuitState, len(t.hosts))
+	for host, c := range t.hosts {
+		states[host] = c.state
+	}
+	return states
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "httpx: circuit open for " + e.Host
+}
+
+// AppError maps the error to a 503 with code "circuit_open".
+func (e *CircuitOpenError) AppError() AppError {
+	return WrapStatus(e, http.StatusServiceUnavailable, "upstream temporarily unavailable").WithCode("circuit_open")
+}
+
+// NewBreakerTransport isolates failing upstreams: each host gets its own
+// circuit, which opens on too many failures, fails fast with a
+// *CircuitOpenError while open, and lets a single probe through after
+// OpenFor to decide whether to close again.
+func NewBreakerTransport(next http.RoundTripper, cfg BreakerConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = 30 * time.Second
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		}
+	}
+	return &breakerTransport{next: next, cfg: cfg, hosts: map[string]*circuit{}}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.allow(host); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// The caller gave up; that says nothing about the upstream.
+		t.release(host)
+		return resp, err
+	}
+	t.record(host, t.cfg.IsFailure(resp, err))
+	return resp, err
+}
+
+func (t *breakerTransport) allow(host string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.circuit(host)
+	switch c.state {
+	case CircuitOpen:
+		retryAt := c.openedAt.Add(t.cfg.OpenFor)
+		if clockNow().Before(retryAt) {
+			return &CircuitOpenError{Host: host, RetryAt: retryAt}
+		}
+		t.transition(host, c, CircuitHalfOpen)
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return &CircuitOpenError{Host: host, RetryAt: clockNow().Add(t.cfg.OpenFor)}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+func (t *breakerTransport) release(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.circuit(host).probing = false
+}
+
+func (t *breakerTransport) record(host string, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.circuit(host)
+	now := clockNow()
+
+	if c.state == CircuitHalfOpen {
+		c.probing = false
+		if failed {
+			c.openedAt = now
+			t.transition(host, c, CircuitOpen)
+		} else {
+			c.consecutive, c.requests, c.failures, c.windowStart = 0, 0, 0, now
+			t.transition(host, c, CircuitClosed)
+		}
+		return
+	}
+
+	if now.Sub(c.windowStart) > t.cfg.Window {
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+	c.requests++
+	if !failed {
+		c.consecutive = 0
+		return
+	}
+	c.failures++
+	c.consecutive++
+
+	rateTripped := t.cfg.FailureRate > 0 && c.requests >= t.cfg.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= t.cfg.FailureRate
+	if c.state == CircuitClosed && (c.consecutive >= t.cfg.ConsecutiveFailures || rateTripped) {
+		c.openedAt = now
+		t.transition(host, c, CircuitOpen)
+	}
+}
+
+func (t *breakerTransport) circuit(host string) *circuit {
+	c, ok := t.hosts[host]
+	if !ok {
+		c = &circuit{windowStart: clockNow()}
+		t.hosts[host] = c
+	}
+	return c
+}
+
+func (t *breakerTransport) transition(host string, c *circuit, to CircuitState) {
+	from := c.state
+	c.state = to
+	if t.cfg.OnStateChange != nil && from != to {
+		t.cfg.OnStateChange(host, from, to)
+	}
+}
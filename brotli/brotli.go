@@ -0,0 +1,61 @@
+// Package brotli provides the brotli ("br") response encoding for the httpx
+// Compress middleware.
+//
+//	httpx.Compress(httpx.CompressConfig{Encodings: []httpx.Encoding{
+//		brotli.Encoding(brotli.DefaultQuality),
+//		zstd.Encoding(kzstd.SpeedDefault),
+//		httpx.GzipEncoding(gzip.DefaultCompression),
+//	}})
+package brotli
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	abrotli "github.com/andybalholm/brotli"
+
+	"github.com/radim/httpx"
+)
+
+// Quality levels, from fastest to smallest output. Qualities above
+// DefaultQuality cost far more CPU than they save in bytes for dynamic
+// responses; reserve them for precompressed assets.
+const (
+	BestSpeed       = abrotli.BestSpeed
+	DefaultQuality  = 5
+	BestCompression = abrotli.BestCompression
+)
+
+type pooledWriter struct {
+	*abrotli.Writer
+	pool *sync.Pool
+}
+
+// Encoding returns the "br" encoding at the given quality, 0 to 11.
+func Encoding(quality int) httpx.Encoding {
+	pool := &sync.Pool{
+		New: func() interface{} {
+			return abrotli.NewWriterLevel(nil, quality)
+		},
+	}
+
+	return httpx.Encoding{
+		Name: "br",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			if quality < BestSpeed || quality > BestCompression {
+				return nil, fmt.Errorf("invalid brotli quality %d", quality)
+			}
+			bw := pool.Get().(*abrotli.Writer)
+			bw.Reset(w)
+			return &pooledWriter{Writer: bw, pool: pool}, nil
+		},
+	}
+}
+
+func (p *pooledWriter) Close() error {
+	err := p.Writer.Close()
+	p.Writer.Reset(nil)
+	p.pool.Put(p.Writer)
+	return err
+}
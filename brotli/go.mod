@@ -0,0 +1,10 @@
+module github.com/radim/httpx/brotli
+
+go 1.24
+
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/radim/httpx v0.0.0
+)
+
+replace github.com/radim/httpx => ../
@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultBufferBodyMax is the body size BufferBody accepts when no limit is
+// given.
+const DefaultBufferBodyMax = 1 << 20
+
+var (
+	bufferedBodyKey = NewContextKey[[]byte]("buffered body")
+
+	bodyBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+)
+
+// BufferBody reads the request body once, up to max bytes (0 selects
+// DefaultBufferBodyMax), so signature verification, audit logging and
+// binding can each read it in full. Larger bodies get a 413 AppError.
+//
+// The bytes are available through BufferedBody, and r.Body and r.GetBody
+// replay them; Bind and WebhookSignature use them directly. Code reading
+// r.Body itself calls ReplayBody first when something before it may have
+// read it already.
+//
+// Buffers are pooled: the bytes are valid until the handler chain returns,
+// so copy them when they must outlive the request.
+func BufferBody(adapter *HandlerAdapter, max int64) Middleware {
+	if max <= 0 {
+		max = DefaultBufferBodyMax
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > max {
+				adapter.HandleError(w, r, bodyTooLargeError(max))
+				return
+			}
+
+			buf := bodyBufferPool.Get().(*bytes.Buffer)
+			buf.Reset()
+			defer func() {
+				if int64(buf.Cap()) <= max {
+					bodyBufferPool.Put(buf)
+				}
+			}()
+			if _, err := buf.ReadFrom(io.LimitReader(r.Body, max+1)); err != nil {
+				adapter.HandleError(w, r, err)
+				return
+			}
+			if int64(buf.Len()) > max {
+				adapter.HandleError(w, r, bodyTooLargeError(max))
+				return
+			}
+			r.Body.Close()
+
+			data := buf.Bytes()
+			r = r.WithContext(bufferedBodyKey.WithValue(r.Context(), data))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+			ReplayBody(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BufferedBody returns the body read by BufferBody. Callers must not
+// modify it.
+func BufferedBody(ctx context.Context) ([]byte, bool) {
+	return bufferedBodyKey.Value(ctx)
+}
+
+// ReplayBody resets r.Body to the start of the body read by BufferBody and
+// reports whether there was one.
+func ReplayBody(r *http.Request) bool {
+	data, ok := BufferedBody(r.Context())
+	if ok {
+		r.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	return ok
+}
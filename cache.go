@@ -0,0 +1,225 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// CachePolicy builds Cache-Control (and Vary) headers. It is an immutable
+	// value: every method returns a modified copy, so policies can be shared
+	// and specialized per route.
+	//
+	//	httpx.Cache().Public().MaxAge(5*time.Minute).StaleWhileRevalidate(30*time.Second).Apply(w)
+	//
+	// Used as route middleware it also exposes itself through the request
+	// context so cache middleware derives keys from the same Vary list.
+	CachePolicy struct {
+		visibility           string
+		maxAge               time.Duration
+		sMaxAge              time.Duration
+		staleWhileRevalidate time.Duration
+		staleIfError         time.Duration
+		noCache              bool
+		noStore              bool
+		mustRevalidate       bool
+		immutable            bool
+		vary                 []string
+	}
+
+	cachePolicyKey struct{}
+)
+
+// Cache starts an empty caching policy.
+func Cache() CachePolicy {
+	return CachePolicy{maxAge: -1, sMaxAge: -1}
+}
+
+// NoStore is the policy for responses that must never be cached.
+func NoStore() CachePolicy {
+	return Cache().NoStore()
+}
+
+func CachePolicyFromContext(ctx context.Context) (CachePolicy, bool) {
+	p, ok := ctx.Value(cachePolicyKey{}).(CachePolicy)
+	return p, ok
+}
+
+func (p CachePolicy) Public() CachePolicy {
+	p.visibility = "public"
+	return p
+}
+
+func (p CachePolicy) Private() CachePolicy {
+	p.visibility = "private"
+	return p
+}
+
+func (p CachePolicy) MaxAge(d time.Duration) CachePolicy {
+	p.maxAge = d
+	return p
+}
+
+// SMaxAge sets the shared (proxy/CDN) cache lifetime.
+func (p CachePolicy) SMaxAge(d time.Duration) CachePolicy {
+	p.sMaxAge = d
+	return p
+}
+
+func (p CachePolicy) StaleWhileRevalidate(d time.Duration) CachePolicy {
+	p.staleWhileRevalidate = d
+	return p
+}
+
+func (p CachePolicy) StaleIfError(d time.Duration) CachePolicy {
+	p.staleIfError = d
+	return p
+}
+
+func (p CachePolicy) NoCache() CachePolicy {
+	p.noCache = true
+	return p
+}
+
+// NoStore overrides every other directive.
+func (p CachePolicy) NoStore() CachePolicy {
+	p.noStore = true
+	return p
+}
+
+func (p CachePolicy) MustRevalidate() CachePolicy {
+	p.mustRevalidate = true
+	return p
+}
+
+func (p CachePolicy) Immutable() CachePolicy {
+	p.immutable = true
+	return p
+}
+
+// Vary adds request headers the response varies on.
+func (p CachePolicy) Vary(headers ...string) CachePolicy {
+	vary := make([]string, 0, len(p.vary)+len(headers))
+	vary = append(vary, p.vary...)
+	for _, h := range headers {
+		vary = append(vary, http.CanonicalHeaderKey(h))
+	}
+	sort.Strings(vary)
+	p.vary = vary
+	return p
+}
+
+// VaryHeaders returns the canonicalized Vary header names.
+func (p CachePolicy) VaryHeaders() []string {
+	return append([]string(nil), p.vary...)
+}
+
+// String returns the Cache-Control header value.
+func (p CachePolicy) String() string {
+	if p.noStore {
+		return "no-store"
+	}
+
+	var directives []string
+	if p.visibility != "" {
+		directives = append(directives, p.visibility)
+	}
+	if p.noCache {
+		directives = append(directives, "no-cache")
+	}
+	if p.maxAge >= 0 {
+		directives = append(directives, "max-age="+seconds(p.maxAge))
+	}
+	if p.sMaxAge >= 0 {
+		directives = append(directives, "s-maxage="+seconds(p.sMaxAge))
+	}
+	if p.staleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+seconds(p.staleWhileRevalidate))
+	}
+	if p.staleIfError > 0 {
+		directives = append(directives, "stale-if-error="+seconds(p.staleIfError))
+	}
+	if p.mustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	if p.immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Apply sets the policy headers on w. A Cache-Control header already set by
+// the handler wins.
+func (p CachePolicy) Apply(w http.ResponseWriter) {
+	p.applyHeader(w.Header())
+}
+
+func (p CachePolicy) applyHeader(h http.Header) {
+	if cc := p.String(); cc != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", cc)
+	}
+	for _, v := range p.vary {
+		h.Add("Vary", v)
+	}
+}
+
+// CacheKey derives the cache key for r: method, request URI and the values
+// of the headers the response varies on.
+func (p CachePolicy) CacheKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.RequestURI())
+	for _, v := range p.vary {
+		b.WriteByte('\n')
+		b.WriteString(v)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(v), ","))
+	}
+	return b.String()
+}
+
+// Middleware applies the policy to responses with a status below 400, so
+// error responses are never marked cacheable.
+func (p CachePolicy) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hw := &hookWriter{
+				ResponseWriter: w,
+				beforeHeader: func(h http.Header, status int) {
+					if status < http.StatusBadRequest {
+						p.applyHeader(h)
+					}
+				},
+			}
+			next.ServeHTTP(hw, r.WithContext(context.WithValue(r.Context(), cachePolicyKey{}, p)))
+		})
+	}
+}
+
+// CacheMiddleware applies per-route policies: it looks up the policy for
+// each request with policyFor and applies it like CachePolicy.Middleware.
+// Requests for which policyFor returns false are passed through unchanged.
+func CacheMiddleware(policyFor func(r *http.Request) (CachePolicy, bool)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := policyFor(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p.Middleware()(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+func seconds(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
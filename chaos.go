@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ChaosHeader names the fault Chaos injected into a response: "latency" or
+// "error". Dropped connections have no response to carry it.
+const ChaosHeader = "X-Chaos"
+
+// ChaosConfig configures Chaos. Probabilities are fractions of requests
+// between 0 and 1.
+type ChaosConfig struct {
+	// Latency is the probability of delaying a request by a random
+	// duration up to MaxLatency before serving it.
+	Latency    float64
+	MaxLatency time.Duration
+	// Errors is the probability of answering with a 5xx AppError (code
+	// "chaos") instead of calling the handler.
+	Errors float64
+	// Statuses are the statuses of injected errors, picked at random. They
+	// default to 500, 502, 503 and 504.
+	Statuses []int
+	// Drops is the probability of aborting the request without a
+	// response, as if the connection broke.
+	Drops float64
+	// Seed makes the sequence of faults repeatable. Zero picks a random
+	// seed.
+	Seed uint64
+}
+
+// Chaos injects latency, 5xx errors and dropped connections into a share
+// of the requests, so client retries, timeouts and circuit breakers can be
+// tested against a real server. Delayed requests may also fail.
+//
+// It only runs in the development and staging environments of config and
+// passes requests through unchanged otherwise. Wrap individual routes, or
+// combine it with When, to target some of them:
+//
+//	mux.Handle("GET /orders/{id}", httpx.Chaos(cfg, adapter, httpx.ChaosConfig{
+//		Errors: 0.1, Latency: 0.2, MaxLatency: 2 * time.Second, Seed: 42,
+//	})(orders))
+func Chaos(config AppConfig, adapter *HandlerAdapter, cfg ChaosConfig) Middleware {
+	if !chaosAllowed(config) {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if len(cfg.Statuses) == 0 {
+		cfg.Statuses = []int{
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		}
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Draw everything up front, so each request consumes the same
+			// number of values and the sequence stays repeatable.
+			mu.Lock()
+			delay := time.Duration(rng.Float64() * float64(cfg.MaxLatency))
+			delayed := rng.Float64() < cfg.Latency && delay > 0
+			fault := rng.Float64()
+			status := cfg.Statuses[rng.IntN(len(cfg.Statuses))]
+			mu.Unlock()
+
+			if delayed {
+				w.Header().Set(ChaosHeader, "latency")
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+
+			switch {
+			case fault < cfg.Drops:
+				panic(http.ErrAbortHandler)
+			case fault < cfg.Drops+cfg.Errors:
+				w.Header().Set(ChaosHeader, "error")
+				adapter.HandleError(w, r, StatusError(status, "injected failure").WithCode("chaos"))
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// chaosAllowed reports whether config is a development or staging
+// configuration.
+func chaosAllowed(config AppConfig) bool {
+	if config == nil {
+		return false
+	}
+	if config.IsDevelopment() {
+		return true
+	}
+	env, ok := config.(interface{ Environment() string })
+	return ok && env.Environment() == EnvStaging
+}
@@ -0,0 +1,43 @@
+// Package chi registers httpx handlers on chi routers.
+package chi
+
+import (
+	"net/http"
+
+	chiv5 "github.com/go-chi/chi/v5"
+
+	"github.com/radim/httpx"
+)
+
+// Handle registers h for method and pattern on r through adapter. An empty
+// method matches every method. Handlers see the route in Request.Pattern, in
+// ServeMux form ("GET /users/{id}"), so pattern-labelled metrics and logs
+// work as with ServeMux.
+func Handle(r chiv5.Router, adapter *httpx.HandlerAdapter, method, pattern string, h httpx.HTTPHandlerExt, opts ...httpx.HandleOption) {
+	handler := withPattern(adapter.Handle(h, opts...))
+	if method == "" {
+		r.Handle(pattern, handler)
+		return
+	}
+	r.Method(method, pattern, handler)
+}
+
+// Wire renders unmatched routes and methods through adapter instead of
+// chi's plain-text defaults.
+func Wire(r chiv5.Router, adapter *httpx.HandlerAdapter) {
+	r.NotFound(adapter.NotFoundHandler().ServeHTTP)
+	r.MethodNotAllowed(adapter.MethodNotAllowedHandler().ServeHTTP)
+}
+
+func withPattern(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chiv5.RouteContext(r.Context()); rctx != nil {
+			pattern := rctx.RoutePattern()
+			if rctx.RouteMethod != "" {
+				pattern = rctx.RouteMethod + " " + pattern
+			}
+			r.Pattern = pattern
+		}
+		next.ServeHTTP(w, r)
+	})
+}
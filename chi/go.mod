@@ -0,0 +1,10 @@
+module github.com/radim/httpx/chi
+
+go 1.24
+
+require (
+	github.com/go-chi/chi/v5 v5.1.0
+	github.com/radim/httpx v0.0.0
+)
+
+replace github.com/radim/httpx => ../
@@ -0,0 +1,180 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Process exit codes returned by ExitCode, following BSD sysexits.h.
+const (
+	ExitOK          = 0
+	ExitDataErr     = 65 // invalid input (4xx)
+	ExitNoInput     = 66 // not found
+	ExitUnavailable = 69 // not implemented or unavailable
+	ExitSoftware    = 70 // internal error
+	ExitTempFail    = 75 // retryable failure
+	ExitNoPerm      = 77 // unauthorized or forbidden
+)
+
+// ErrorClass tells whose fault an error is, for dashboards and alerts that
+// separate failures of this service from bad requests and failing
+// dependencies.
+type ErrorClass string
+
+const (
+	// ClassClient errors are caused by the request: 4xx AppErrors.
+	ClassClient ErrorClass = "client"
+	// ClassInternal errors are failures of this service: 5xx AppErrors,
+	// panics and unclassified errors.
+	ClassInternal ErrorClass = "internal"
+	// ClassUpstream errors come from a dependency: UpstreamErrors,
+	// responses and transport failures of the Client, and 502 AppErrors.
+	ClassUpstream ErrorClass = "upstream"
+	// ClassTimeout errors are expired deadlines and 408 and 504 AppErrors.
+	ClassTimeout ErrorClass = "timeout"
+	// ClassCanceled errors are requests abandoned by the client.
+	ClassCanceled ErrorClass = "canceled"
+)
+
+var errorClassKey = NewContextKey[ErrorClass]("error class")
+
+// Classification describes an error in terms shared by the HTTP layer and
+// background jobs, so both use one taxonomy.
+type Classification struct {
+	// Status is the HTTP status the error maps to, 500 when unclassified.
+	Status int
+	// Code is the AppError code, if any.
+	Code string
+	// Severity is slog.LevelWarn for client errors and slog.LevelError for
+	// everything else.
+	Severity slog.Level
+	// Retryable marks transient failures: timeouts, rate limiting and
+	// unavailable upstreams.
+	Retryable bool
+	// Class tells whose fault the error is.
+	Class ErrorClass
+}
+
+// Classify classifies err the way HandleError does: AppErrors (including the
+// best one of a joined error) keep their status and code, UpstreamErrors
+// map to 502 or 504, anything else is an internal error. Deadline
+// expirations map to 504.
+func Classify(err error) Classification {
+	c := Classification{Status: http.StatusInternalServerError}
+
+	var appErr AppError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		appErr = bodyTooLargeError(maxBytesErr.Limit)
+	case isUpstream(err):
+		upstreamErr, _ := asUpstream(err)
+		appErr = upstreamErr.appError()
+	case errors.As(err, &appErr):
+	default:
+		if joined, ok := classifyJoined(err); ok {
+			appErr = joined
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			appErr = StatusError(http.StatusGatewayTimeout, "deadline exceeded")
+		}
+	}
+	if appErr.StatusCode != 0 {
+		c.Status, c.Code = appErr.StatusCode, appErr.Code
+	}
+
+	c.Severity = slog.LevelError
+	if c.Status < 500 {
+		c.Severity = slog.LevelWarn
+	}
+
+	switch c.Status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		c.Retryable = true
+	}
+	c.Class = classOf(err, c.Status)
+	return c
+}
+
+func classOf(err error, status int) ErrorClass {
+	var respErr *ResponseError
+	var urlErr *url.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout(),
+		status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return ClassTimeout
+	case isUpstream(err), errors.As(err, &respErr), errors.As(err, &urlErr), status == http.StatusBadGateway:
+		return ClassUpstream
+	case status < 500:
+		return ClassClient
+	default:
+		return ClassInternal
+	}
+}
+
+// ErrorClassFromContext returns the class of the error handled for the
+// request, for OnResponse hooks labelling metrics. It reports false when
+// the request succeeded.
+func ErrorClassFromContext(ctx context.Context) (ErrorClass, bool) {
+	return errorClassKey.Value(ctx)
+}
+
+// classified records the class of err for ErrorClassFromContext, when
+// OnResponse hooks observe the request.
+func (a *HandlerAdapter) classified(req *http.Request, err error) *http.Request {
+	if len(a.onResponse) == 0 {
+		return req
+	}
+	return req.WithContext(errorClassKey.WithValue(req.Context(), Classify(err).Class))
+}
+
+// ExitCode maps err to a process exit code for CLIs and jobs reusing the
+// application's errors. nil maps to ExitOK.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	c := Classify(err)
+	switch {
+	case c.Retryable:
+		return ExitTempFail
+	case c.Status == http.StatusUnauthorized || c.Status == http.StatusForbidden:
+		return ExitNoPerm
+	case c.Status == http.StatusNotFound:
+		return ExitNoInput
+	case c.Status < 500:
+		return ExitDataErr
+	case c.Status == http.StatusNotImplemented || c.Status == http.StatusServiceUnavailable:
+		return ExitUnavailable
+	default:
+		return ExitSoftware
+	}
+}
+
+// LogValue implements slog.LogValuer.
+func (c Classification) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.Int("status", c.Status)}
+	if c.Code != "" {
+		attrs = append(attrs, slog.String("code", c.Code))
+	}
+	attrs = append(attrs, slog.Bool("retryable", c.Retryable), slog.String("class", string(c.Class)))
+	return slog.GroupValue(attrs...)
+}
+
+// LogError logs err at the severity of its classification, with the
+// classification attached under "class". A nil logger uses slog.Default.
+func LogError(ctx context.Context, logger *slog.Logger, msg string, err error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := Classify(err)
+	logger.Log(ctx, c.Severity, msg, slog.Any("error", err), slog.Any("class", c))
+}
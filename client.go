@@ -0,0 +1,304 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	// Client is an HTTP client sharing the server's error vocabulary:
+	// non-2xx responses come back as AppErrors with the upstream status and
+	// code, decoded from problem+json or JSONRenderer bodies when present.
+	//
+	//	var user User
+	//	err := client.Get("/users/{id}").Path("id", id).Do(ctx, &user)
+	//	if appErr, ok := err.(httpx.AppError); ok && appErr.StatusCode == 404 { ... }
+	Client struct {
+		http    *http.Client
+		baseURL *url.URL
+		header  http.Header
+		codec   Codec
+		// upstream names the service for UpstreamErrors, see WithUpstream.
+		upstream string
+	}
+
+	ClientOption func(*Client)
+
+	// ClientRequest builds one request. Methods return the receiver for
+	// chaining; Do sends it.
+	ClientRequest struct {
+		client *Client
+		method string
+		path   string
+		params map[string]string
+		query  url.Values
+		header http.Header
+		body   interface{}
+		err    error
+	}
+
+	// ResponseError is the Err of AppErrors returned for non-2xx
+	// responses. It unwraps to a *ValidationError when the body listed
+	// field errors.
+	ResponseError struct {
+		StatusCode int
+		Header     http.Header
+		// Body holds up to MaxErrorBody bytes of the response.
+		Body    []byte
+		Message string
+		fields  []FieldError
+	}
+)
+
+// MaxErrorBody bounds the error response bytes kept in ResponseError.
+const MaxErrorBody = 64 << 10
+
+// NewClient returns a client resolving request paths against baseURL.
+func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: invalid base URL: %w", err)
+	}
+	c := &Client{http: &http.Client{}, baseURL: u, header: http.Header{}, codec: JSONCodec{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// WithHTTPClient sets the underlying http.Client.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithClientHeader adds a header sent with every request.
+func WithClientHeader(name, value string) ClientOption {
+	return func(c *Client) { c.header.Add(name, value) }
+}
+
+// WithClientCodec sets the codec for request and response bodies;
+// JSONCodec by default.
+func WithClientCodec(codec Codec) ClientOption {
+	return func(c *Client) { c.codec = codec }
+}
+
+// WithUpstream wraps the failures of requests sent by the client, except
+// invalid requests, in an UpstreamError for service, with the method and
+// path template as Op. Handlers can then return them unchanged to respond
+// 502 or 504. Upstream AppErrors stay reachable with errors.As.
+func WithUpstream(service string) ClientOption {
+	return func(c *Client) { c.upstream = service }
+}
+
+// HTTPClient returns the underlying http.Client.
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+func (c *Client) Get(path string) *ClientRequest    { return c.NewRequest(http.MethodGet, path) }
+func (c *Client) Post(path string) *ClientRequest   { return c.NewRequest(http.MethodPost, path) }
+func (c *Client) Put(path string) *ClientRequest    { return c.NewRequest(http.MethodPut, path) }
+func (c *Client) Patch(path string) *ClientRequest  { return c.NewRequest(http.MethodPatch, path) }
+func (c *Client) Delete(path string) *ClientRequest { return c.NewRequest(http.MethodDelete, path) }
+
+// NewRequest starts a request. path may contain {name} placeholders
+// filled in by Path.
+func (c *Client) NewRequest(method, path string) *ClientRequest {
+	return &ClientRequest{client: c, method: method, path: path, query: url.Values{}, header: http.Header{}}
+}
+
+// Path substitutes the {name} placeholder, escaping value.
+func (r *ClientRequest) Path(name, value string) *ClientRequest {
+	if r.params == nil {
+		r.params = map[string]string{}
+	}
+	r.params[name] = value
+	return r
+}
+
+func (r *ClientRequest) Query(name, value string) *ClientRequest {
+	r.query.Add(name, value)
+	return r
+}
+
+func (r *ClientRequest) Header(name, value string) *ClientRequest {
+	r.header.Add(name, value)
+	return r
+}
+
+// Body sets a value encoded with the client's codec. io.Readers and
+// []byte are sent as they are.
+func (r *ClientRequest) Body(v interface{}) *ClientRequest {
+	r.body = v
+	return r
+}
+
+// Build returns the *http.Request without sending it.
+func (r *ClientRequest) Build(ctx context.Context) (*http.Request, error) {
+	path := r.path
+	for name, value := range r.params {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: invalid request path: %w", err)
+	}
+	u := r.client.baseURL.ResolveReference(ref)
+	if len(r.query) > 0 {
+		q := u.Query()
+		for k, vs := range r.query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := r.body.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+	case []byte:
+		body = bytes.NewReader(b)
+	default:
+		data, err := r.client.codec.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("httpx: encoding request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = r.client.codec.ContentType()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range r.client.header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	for k, vs := range r.header {
+		req.Header[k] = append(req.Header[k], vs...)
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", r.client.codec.ContentType()+", application/problem+json")
+	}
+	return req, nil
+}
+
+// Do sends the request and decodes a 2xx response into out, unless out is
+// nil or the response has no content. Non-2xx responses are returned as
+// AppErrors wrapping a *ResponseError.
+func (r *ClientRequest) Do(ctx context.Context, out interface{}) error {
+	resp, err := r.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return r.upstreamError(fmt.Errorf("httpx: reading response: %w", err))
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := r.client.codec.Unmarshal(data, out); err != nil {
+		return r.upstreamError(fmt.Errorf("httpx: decoding response: %w", err))
+	}
+	return nil
+}
+
+// Send sends the request and returns the raw response for 2xx statuses.
+// The caller must close its body.
+func (r *ClientRequest) Send(ctx context.Context) (*http.Response, error) {
+	req, err := r.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.http.Do(req)
+	if err != nil {
+		return nil, r.upstreamError(err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, r.upstreamError(ErrorFromResponse(resp))
+}
+
+// upstreamError wraps err for WithUpstream clients.
+func (r *ClientRequest) upstreamError(err error) error {
+	if r.client.upstream == "" {
+		return err
+	}
+	return WrapUpstream(r.client.upstream, r.method+" "+r.path, err)
+}
+
+// ErrorFromResponse converts a non-2xx response into an AppError, reading
+// up to MaxErrorBody bytes of its body. problem+json bodies and the
+// {"error", "code"} bodies of JSONRenderer supply message, code and field
+// errors; other bodies fall back to the status text.
+func ErrorFromResponse(resp *http.Response) AppError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBody))
+	re := &ResponseError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+		Message:    http.StatusText(resp.StatusCode),
+	}
+
+	var code string
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" || mediaType == "application/json" {
+		var body struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+			Error  string `json:"error"`
+			Code   string `json:"code"`
+			// Errors is []FieldError for problem+json but []string in
+			// JSONRenderer 500 bodies.
+			Errors json.RawMessage `json:"errors"`
+			Fields []FieldError    `json:"fields"`
+		}
+		if json.Unmarshal(data, &body) == nil {
+			code = body.Code
+			for _, msg := range []string{body.Detail, body.Error, body.Title} {
+				if msg != "" {
+					re.Message = msg
+					break
+				}
+			}
+			var fields []FieldError
+			if json.Unmarshal(body.Errors, &fields) == nil {
+				re.fields = fields
+			}
+			re.fields = append(re.fields, body.Fields...)
+		}
+	}
+	return AppError{Err: re, StatusCode: resp.StatusCode, Code: code}
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+func (e *ResponseError) Unwrap() error {
+	if len(e.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: e.fields}
+}
@@ -0,0 +1,143 @@
+package httpx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type (
+	// ClientMiddleware wraps a RoundTripper, the client-side counterpart of
+	// Middleware.
+	ClientMiddleware func(http.RoundTripper) http.RoundTripper
+
+	// RoundTripperFunc adapts a function to http.RoundTripper.
+	RoundTripperFunc func(*http.Request) (*http.Response, error)
+)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ChainTransport wraps rt in mws, the first one outermost like Chain. A
+// nil rt means http.DefaultTransport.
+func ChainTransport(rt http.RoundTripper, mws ...ClientMiddleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// WithClientMiddleware wraps the client's transport in mws. It copies the
+// http.Client, so apply it after WithHTTPClient.
+func WithClientMiddleware(mws ...ClientMiddleware) ClientOption {
+	return func(c *Client) {
+		hc := *c.http
+		hc.Transport = ChainTransport(hc.Transport, mws...)
+		c.http = &hc
+	}
+}
+
+// cloneForHeaders returns a copy of req whose headers may be modified, as
+// RoundTrippers must not change the caller's request.
+func cloneForHeaders(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = req.Header.Clone()
+	return r
+}
+
+// SetRequestHeader sets name to value on every outgoing request that does
+// not already carry it.
+func SetRequestHeader(name, value string) ClientMiddleware {
+	return RequestHeaderFunc(name, func(*http.Request) (string, error) { return value, nil })
+}
+
+// RequestHeaderFunc sets name to the value fn derives for each request,
+// e.g. from its context. Empty values are skipped and errors fail the
+// request.
+func RequestHeaderFunc(name string, fn func(*http.Request) (string, error)) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(name) != "" {
+				return next.RoundTrip(req)
+			}
+			v, err := fn(req)
+			if err != nil {
+				return nil, err
+			}
+			if v == "" {
+				return next.RoundTrip(req)
+			}
+			req = cloneForHeaders(req)
+			req.Header.Set(name, v)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// BearerToken authorizes requests with the token returned by token, which
+// may refresh it as needed.
+func BearerToken(token func(ctx context.Context) (string, error)) ClientMiddleware {
+	return RequestHeaderFunc("Authorization", func(req *http.Request) (string, error) {
+		t, err := token(req.Context())
+		if err != nil || t == "" {
+			return "", err
+		}
+		return "Bearer " + t, nil
+	})
+}
+
+// LogRequests logs each outgoing request with its status and duration,
+// at Warn for transport errors and 5xx responses and Debug otherwise.
+func LogRequests(logger *slog.Logger) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Duration("duration", time.Since(start)),
+			}
+			level := slog.LevelDebug
+			switch {
+			case err != nil:
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.Any("error", err))
+			default:
+				if resp.StatusCode >= 500 {
+					level = slog.LevelWarn
+				}
+				attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			}
+			logger.LogAttrs(req.Context(), level, "outgoing request", attrs...)
+			return resp, err
+		})
+	}
+}
+
+// TraceRequests calls start before each outgoing request; the function it
+// returns is called with the outcome. start may return a request with a
+// derived context, e.g. carrying a span, and headers it injects are sent.
+//
+//	httpx.TraceRequests(func(req *http.Request) (*http.Request, func(*http.Response, error)) {
+//		ctx, span := tracer.Start(req.Context(), req.Method)
+//		return req.WithContext(ctx), func(*http.Response, error) { span.End() }
+//	})
+func TraceRequests(start func(req *http.Request) (*http.Request, func(*http.Response, error))) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			traced, finish := start(cloneForHeaders(req))
+			resp, err := next.RoundTrip(traced)
+			if finish != nil {
+				finish(resp, err)
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Clock tells time for the time-dependent parts of the package:
+	// concurrency limiter queueing, circuit breakers, error alarms, the
+	// response cache, idempotency records, session and OAuth state expiry,
+	// JWT and webhook timestamp checks, JWKS and discovery caching, report
+	// sampling and slow request detection. Latencies and network deadlines
+	// keep using the time package. Tests replace it with SetClock, usually
+	// through httpxtest.SetClock, to control time deterministically.
+	Clock interface {
+		Now() time.Time
+		NewTimer(d time.Duration) Timer
+	}
+
+	// Timer is a timer of a Clock, like time.Timer.
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+	}
+
+	systemClock struct{}
+
+	systemTimer struct {
+		t *time.Timer
+	}
+)
+
+// SystemClock is the Clock of the time package, used by default.
+var SystemClock Clock = systemClock{}
+
+var clock atomic.Pointer[Clock]
+
+// SetClock replaces the package clock. A nil clock restores SystemClock.
+// It is meant for tests, which must not run in parallel with others that
+// depend on time while it is replaced.
+func SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	clock.Store(&c)
+}
+
+// CurrentClock returns the package clock, for stores and adapters in other
+// modules whose expiry must agree with the middleware's.
+func CurrentClock() Clock {
+	if c := clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// clockNow is time.Now of the package clock.
+func clockNow() time.Time {
+	return CurrentClock().Now()
+}
+
+// clockSince is time.Since of the package clock.
+func clockSince(t time.Time) time.Duration {
+	return clockNow().Sub(t)
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
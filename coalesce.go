@@ -0,0 +1,131 @@
+package httpx
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"net/http"
+	"sync"
+)
+
+// CoalescedHeader is set to "true" on responses shared from another
+// request's handler execution.
+const CoalescedHeader = "X-Coalesced"
+
+// Coalesce collapses concurrent identical GET requests into one handler
+// execution and sends its buffered response to every waiter. Requests are
+// identical when their CachePolicy.CacheKey matches, extended with the
+// given Vary headers. Requests carrying Authorization or Cookie headers
+// are never coalesced, like ResponseCache bypasses them, so one user's
+// personalized response never reaches another. A response that sets
+// cookies (a new session or CSRF token, say) is not shared either: the
+// waiters then run the handler themselves. Per-request headers such as
+// Date, the request ID and trace context are not copied to waiters.
+// Responses are buffered in full, so do not use it on streaming routes.
+//
+// The shared execution runs with a context that is not canceled when the
+// first client goes away, so the remaining waiters still get a response.
+func Coalesce(vary ...string) Middleware {
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]*coalescedCall)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			policy, ok := CachePolicyFromContext(r.Context())
+			if !ok {
+				policy = Cache()
+			}
+			key := policy.Vary(vary...).CacheKey(r)
+
+			mu.Lock()
+			if c, ok := calls[key]; ok {
+				mu.Unlock()
+				select {
+				case <-c.done:
+				case <-r.Context().Done():
+					return
+				}
+				if !c.ok || len(c.resp.header.Values("Set-Cookie")) > 0 {
+					// The shared execution panicked or issued per-client
+					// cookies; run independently.
+					next.ServeHTTP(w, r)
+					return
+				}
+				c.resp.writeTo(w, true)
+				return
+			}
+			c := &coalescedCall{done: make(chan struct{})}
+			calls[key] = c
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(c.done)
+			}()
+			c.resp = &bufferedResponse{header: make(http.Header)}
+			next.ServeHTTP(c.resp, r.WithContext(context.WithoutCancel(r.Context())))
+			deferTrailers(c.resp.header)
+			c.ok = true
+			c.resp.writeTo(w, false)
+		})
+	}
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *bufferedResponse
+	ok   bool
+}
+
+// bufferedResponse is a ResponseWriter that keeps the whole response in
+// memory for later replay.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 && (status < 100 || status > 199) {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// Flush is a no-op: the response is sent once the handler returns.
+func (b *bufferedResponse) Flush() {}
+
+func (b *bufferedResponse) writeTo(w http.ResponseWriter, shared bool) {
+	header := b.header
+	if shared {
+		header = header.Clone()
+		deletePerRequestHeaders(header)
+	}
+	h := w.Header()
+	for k, v := range header {
+		h[k] = append([]string(nil), v...)
+	}
+	if shared {
+		h.Set(CoalescedHeader, "true")
+	}
+	w.WriteHeader(cmp.Or(b.status, http.StatusOK))
+	w.Write(b.body.Bytes())
+}
@@ -0,0 +1,119 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCoalesceSkipsCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{name: "authorization", header: "Authorization", value: "Bearer token"},
+		{name: "cookie", header: "Cookie", value: "_session=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			h := Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				entered <- struct{}{}
+				<-release
+				w.Header().Set("Set-Cookie", "user="+r.Header.Get(tt.header))
+			}))
+
+			serve := func(value string) <-chan *httptest.ResponseRecorder {
+				done := make(chan *httptest.ResponseRecorder, 1)
+				go func() {
+					req := httptest.NewRequest(http.MethodGet, "/me", nil)
+					req.Header.Set(tt.header, value)
+					w := httptest.NewRecorder()
+					h.ServeHTTP(w, req)
+					done <- w
+				}()
+				return done
+			}
+
+			first := serve(tt.value)
+			<-entered
+			second := serve(tt.value + "-other")
+			select {
+			case <-entered:
+			case <-time.After(5 * time.Second):
+				t.Fatal("second request was coalesced with the first")
+			}
+			close(release)
+
+			for _, w := range []*httptest.ResponseRecorder{<-first, <-second} {
+				if w.Header().Get(CoalescedHeader) != "" {
+					t.Errorf("response has %s", CoalescedHeader)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceKeepsPerClientHeaders(t *testing.T) {
+	t.Run("cookies are not shared", func(t *testing.T) {
+		entered := make(chan struct{}, 2)
+		release := make(chan struct{})
+		calls := 0
+		h := Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			entered <- struct{}{}
+			<-release
+			w.Header().Set("Set-Cookie", fmt.Sprintf("_csrf=token%d", calls))
+		}))
+
+		serve := func() <-chan *httptest.ResponseRecorder {
+			done := make(chan *httptest.ResponseRecorder, 1)
+			go func() {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+				done <- w
+			}()
+			return done
+		}
+
+		first := serve()
+		<-entered
+		second := serve()
+		close(release)
+		a, b := <-first, <-second
+		if a.Header().Get("Set-Cookie") == b.Header().Get("Set-Cookie") {
+			t.Errorf("both responses set %q", a.Header().Get("Set-Cookie"))
+		}
+		if b.Header().Get(CoalescedHeader) != "" {
+			t.Errorf("response setting a cookie was shared")
+		}
+	})
+
+	t.Run("per-request headers", func(t *testing.T) {
+		resp := &bufferedResponse{header: http.Header{}}
+		resp.Header().Set(RequestIDHeader, "leader")
+		resp.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+		resp.Header().Set("Content-Type", "text/plain")
+		resp.Write([]byte("ok"))
+
+		w := httptest.NewRecorder()
+		w.Header().Set(RequestIDHeader, "waiter")
+		resp.writeTo(w, true)
+		tests := []struct{ header, want string }{
+			{RequestIDHeader, "waiter"},
+			{"Date", ""},
+			{"Content-Type", "text/plain"},
+			{CoalescedHeader, "true"},
+		}
+		for _, tt := range tests {
+			if got := w.Header().Get(tt.header); got != tt.want {
+				t.Errorf("shared %s = %q, want %q", tt.header, got, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync/atomic"
+)
+
+type (
+	// Codec encodes and decodes bodies of one media type. JSONCodec is the
+	// default; the protobuf and msgpack sub-modules provide binary codecs.
+	Codec interface {
+		ContentType() string
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	// JSONCodec encodes with Engine, or the engine set with SetJSONEngine
+	// when nil.
+	JSONCodec struct {
+		Engine JSONEngine
+	}
+
+	// JSONEngine is a JSON implementation. encoding/json is the default;
+	// faster drop-in replacements such as go-json or sonic can be installed
+	// with SetJSONEngine.
+	JSONEngine interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	jsonFuncs struct {
+		marshal   func(v interface{}) ([]byte, error)
+		unmarshal func(data []byte, v interface{}) error
+	}
+)
+
+var (
+	_ Codec = JSONCodec{}
+
+	// StdJSON is the encoding/json engine.
+	StdJSON JSONEngine = JSONEngineFuncs(json.Marshal, json.Unmarshal)
+
+	jsonEngine atomic.Pointer[JSONEngine]
+)
+
+// SetJSONEngine replaces the JSON implementation used by JSONCodec (and so
+// by Bind, Respond and the Client), the JSON and problem renderers, and the
+// JSON response helpers such as JSONStream, SSE and IngestNDJSON. Internal
+// formats, like sessions, tokens and the OpenAPI document, keep using
+// encoding/json. A nil engine restores StdJSON.
+//
+//	httpx.SetJSONEngine(sonic.ConfigStd)
+//	httpx.SetJSONEngine(httpx.JSONEngineFuncs(gojson.Marshal, gojson.Unmarshal))
+func SetJSONEngine(e JSONEngine) {
+	if e == nil {
+		e = StdJSON
+	}
+	jsonEngine.Store(&e)
+}
+
+// JSONEngineFuncs builds a JSONEngine from a pair of functions with the
+// signatures of json.Marshal and json.Unmarshal.
+func JSONEngineFuncs(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) JSONEngine {
+	return jsonFuncs{marshal: marshal, unmarshal: unmarshal}
+}
+
+func (f jsonFuncs) Marshal(v interface{}) ([]byte, error) {
+	return f.marshal(v)
+}
+
+func (f jsonFuncs) Unmarshal(data []byte, v interface{}) error {
+	return f.unmarshal(data, v)
+}
+
+// currentJSON returns the engine set with SetJSONEngine.
+func currentJSON() JSONEngine {
+	if e := jsonEngine.Load(); e != nil {
+		return *e
+	}
+	return StdJSON
+}
+
+// writeJSON encodes v with the current engine and writes it followed by a
+// newline, like json.Encoder.
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := currentJSON().Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.engine().Marshal(v)
+}
+
+func (c JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.engine().Unmarshal(data, v)
+}
+
+func (c JSONCodec) engine() JSONEngine {
+	if c.Engine != nil {
+		return c.Engine
+	}
+	return currentJSON()
+}
+
+// Bind decodes the request body into v with the codec matching its
+// Content-Type, JSONCodec when codecs is empty. A missing Content-Type
+// selects the first codec. Unsupported types are 415 and undecodable bodies
+// 400 AppErrors. Bodies read by BufferBody are decoded from its buffer, so
+// Bind works after other readers of the body.
+func Bind(r *http.Request, v interface{}, codecs ...Codec) error {
+	if len(codecs) == 0 {
+		codecs = []Codec{JSONCodec{}}
+	}
+
+	codec := codecs[0]
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return BadRequestError("invalid Content-Type: %v", err)
+		}
+		codec = nil
+		for _, c := range codecs {
+			if c.ContentType() == mediaType {
+				codec = c
+				break
+			}
+		}
+		if codec == nil {
+			return StatusError(http.StatusUnsupportedMediaType, "unsupported Content-Type %s", mediaType).WithCode("unsupported_media_type")
+		}
+	}
+
+	data, ok := BufferedBody(r.Context())
+	if !ok {
+		var err error
+		if data, err = io.ReadAll(r.Body); err != nil {
+			return err
+		}
+	}
+	if err := codec.Unmarshal(data, v); err != nil {
+		return BadRequestError("invalid %s body: %v", codec.ContentType(), err).WithCode("invalid_body")
+	}
+	return nil
+}
+
+// Respond encodes v with the codec negotiated from the Accept header,
+// JSONCodec when codecs is empty, and writes it with status. Unacceptable
+// requests get a 406 AppError. Encoding happens before anything is written.
+// In envelope mode v becomes the data of an Envelope.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}, codecs ...Codec) error {
+	if len(codecs) == 0 {
+		codecs = []Codec{JSONCodec{}}
+	}
+
+	types := make([]string, len(codecs))
+	for i, c := range codecs {
+		types[i] = c.ContentType()
+	}
+	chosen := NegotiateContentType(r, types...)
+	if chosen == "" {
+		return StatusError(http.StatusNotAcceptable, "none of %v is acceptable", types).WithCode("not_acceptable")
+	}
+
+	var codec Codec
+	for _, c := range codecs {
+		if c.ContentType() == chosen {
+			codec = c
+			break
+		}
+	}
+	if env, ok := envelope(r.Context(), v, nil); ok {
+		v = env
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding response: %w", err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", codec.ContentType())
+	h.Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
+// Typed adapts a function from a decoded request to a response value into
+// an HTTPHandlerExt. The request body is decoded with Bind (skipped for
+// GET, HEAD and DELETE without a body) and the result written with Respond
+// as 200, or 204 when Out is empty.
+func Typed[In, Out any](fn func(ctx context.Context, in In) (Out, error), codecs ...Codec) HTTPHandlerExt {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var in In
+		if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+			if err := Bind(r, &in, codecs...); err != nil {
+				return err
+			}
+		} else if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodDelete {
+			if _, ok := any(&in).(*struct{}); !ok {
+				return BadRequestError("request body required").WithCode("invalid_body")
+			}
+		}
+
+		out, err := fn(r.Context(), in)
+		if err != nil {
+			return err
+		}
+		if _, ok := any(out).(struct{}); ok {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return Respond(w, r, http.StatusOK, out, codecs...)
+	}
+}
@@ -0,0 +1,390 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Encoding is a response Content-Encoding the Compress middleware can
+	// negotiate.
+	Encoding struct {
+		// Name is the Accept-Encoding / Content-Encoding token, e.g. "zstd".
+		Name string
+		// NewWriter returns a writer compressing into w. Close must flush
+		// all pending data but not close w.
+		NewWriter func(w io.Writer) (io.WriteCloser, error)
+		// Accept optionally restricts the encoding to matching requests,
+		// e.g. when a shared dictionary must be advertised by the client.
+		Accept func(r *http.Request) bool
+		// Vary lists request headers, besides Accept-Encoding, that the
+		// choice of this encoding depends on.
+		Vary []string
+	}
+
+	// CompressConfig configures the Compress middleware.
+	CompressConfig struct {
+		// Encodings in server preference order, which breaks ties between
+		// the q-values of Accept-Encoding. Defaults to gzip and deflate; the
+		// brotli and zstd sub-modules provide more.
+		Encodings []Encoding
+		// MinSize is the smallest body worth compressing. Smaller responses
+		// are buffered and sent uncompressed. Zero compresses everything.
+		MinSize int
+		// SkipContentTypes lists media type prefixes never compressed.
+		// Defaults to DefaultSkipContentTypes.
+		SkipContentTypes []string
+		// MaxConcurrent bounds the number of responses compressed at the same
+		// time, capping the CPU spent on compression. Responses beyond the
+		// budget are sent uncompressed. Zero means unlimited.
+		MaxConcurrent int
+	}
+
+	compressWriter struct {
+		http.ResponseWriter
+		enc       *Encoding
+		budget    chan struct{}
+		minSize   int
+		skipTypes []string
+
+		status    int
+		buf       []byte
+		committed bool
+		cw        io.WriteCloser
+	}
+)
+
+// DefaultSkipContentTypes lists media type prefixes that are already
+// compressed and not worth compressing again.
+var DefaultSkipContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/zstd", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf", "application/wasm",
+}
+
+// Compress returns a middleware compressing response bodies with the
+// configured encoding the client prefers, by Accept-Encoding q-value.
+func Compress(cfg CompressConfig) Middleware {
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []Encoding{GzipEncoding(gzip.DefaultCompression), DeflateEncoding(flate.DefaultCompression)}
+	}
+	if cfg.SkipContentTypes == nil {
+		cfg.SkipContentTypes = DefaultSkipContentTypes
+	}
+
+	var budget chan struct{}
+	if cfg.MaxConcurrent > 0 {
+		budget = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enc := negotiateEncoding(r, cfg.Encodings)
+			if enc == nil || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				enc:            enc,
+				budget:         budget,
+				minSize:        cfg.MinSize,
+				skipTypes:      cfg.SkipContentTypes,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// GzipEncoding returns the "gzip" encoding at the given compress/gzip level.
+func GzipEncoding(level int) Encoding {
+	pool := &sync.Pool{
+		New: func() interface{} {
+			gw, err := gzip.NewWriterLevel(nil, level)
+			if err != nil {
+				return err
+			}
+			return gw
+		},
+	}
+
+	return Encoding{
+		Name: "gzip",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			gw, ok := pool.Get().(*gzip.Writer)
+			if !ok {
+				return nil, fmt.Errorf("invalid gzip level %d", level)
+			}
+			gw.Reset(w)
+			return &pooledWriter{WriteCloser: gw, flush: gw.Flush, release: func() { pool.Put(gw) }}, nil
+		},
+	}
+}
+
+// DeflateEncoding returns the "deflate" encoding at the given compress/flate
+// level.
+func DeflateEncoding(level int) Encoding {
+	pool := &sync.Pool{
+		New: func() interface{} {
+			fw, err := flate.NewWriter(nil, level)
+			if err != nil {
+				return err
+			}
+			return fw
+		},
+	}
+
+	return Encoding{
+		Name: "deflate",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			fw, ok := pool.Get().(*flate.Writer)
+			if !ok {
+				return nil, fmt.Errorf("invalid deflate level %d", level)
+			}
+			fw.Reset(w)
+			return &pooledWriter{WriteCloser: fw, flush: fw.Flush, release: func() { pool.Put(fw) }}, nil
+		},
+	}
+}
+
+// negotiateEncoding picks the encoding the client gives the highest
+// q-value, preferring earlier encodings on ties. A "*" entry covers the
+// encodings the header does not list.
+func negotiateEncoding(r *http.Request, encodings []Encoding) *Encoding {
+	header := r.Header.Get("Accept-Encoding")
+	if header == "" {
+		return nil
+	}
+	var best *Encoding
+	bestQ := 0.0
+	for i := range encodings {
+		enc := &encodings[i]
+		q, ok := acceptEncodingQ(header, enc.Name)
+		if !ok {
+			q, _ = acceptEncodingQ(header, "*")
+		}
+		if q <= bestQ {
+			continue
+		}
+		if enc.Accept != nil && !enc.Accept(r) {
+			continue
+		}
+		best, bestQ = enc, q
+	}
+	return best
+}
+
+// acceptEncodingQ returns the q-value header gives the coding name, the
+// last one if it is listed more than once. Malformed q-values count as 0,
+// so a coding is never used against the client's intent.
+func acceptEncodingQ(header, name string) (float64, bool) {
+	q, found := 0.0, false
+	for item := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), name) {
+			continue
+		}
+
+		q, found = 1.0, true
+		for param := range strings.SplitSeq(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(k, "q") {
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 1 {
+				f = 0
+			}
+			q = f
+		}
+	}
+	return q, found
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if status >= 100 && status <= 199 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	if !bodyAllowed(status) || w.Header().Get("Content-Encoding") != "" {
+		w.commit(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.committed {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		if err := w.commit(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush forces the compression decision regardless of MinSize, so streaming
+// responses are compressed from the first flush on.
+func (w *compressWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *compressWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.committed {
+		if err := w.commit(true); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.cw.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// commit decides on compression, writes the header and any buffered body.
+func (w *compressWriter) commit(compress bool) error {
+	w.committed = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff before compressing, the server would sniff compressed bytes.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+	for _, v := range w.enc.Vary {
+		h.Add("Vary", v)
+	}
+
+	if compress && bodyAllowed(w.status) && h.Get("Content-Encoding") == "" &&
+		!w.skipType(h.Get("Content-Type")) && w.acquire() {
+		cw, err := w.enc.NewWriter(w.ResponseWriter)
+		if err == nil {
+			h.Set("Content-Encoding", w.enc.Name)
+			h.Del("Content-Length")
+			w.cw = cw
+		} else {
+			w.release()
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.cw != nil {
+		_, err := w.cw.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Close flushes a response still below MinSize uncompressed and finishes the
+// compressed stream otherwise.
+func (w *compressWriter) Close() error {
+	if !w.committed && w.status != 0 {
+		w.commit(false)
+	}
+
+	if w.cw == nil {
+		return nil
+	}
+	err := w.cw.Close()
+	w.cw = nil
+	w.release()
+	return err
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) skipType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	for _, prefix := range w.skipTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) acquire() bool {
+	if w.budget == nil {
+		return true
+	}
+	select {
+	case w.budget <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *compressWriter) release() {
+	if w.budget != nil {
+		<-w.budget
+	}
+}
+
+// pooledWriter returns its compressor to the pool once closed.
+type pooledWriter struct {
+	io.WriteCloser
+	flush   func() error
+	release func()
+}
+
+func (p *pooledWriter) Flush() error {
+	return p.flush()
+}
+
+func (p *pooledWriter) Close() error {
+	err := p.WriteCloser.Close()
+	p.release()
+	return err
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,214 @@
+package httpx
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// EnvVar is the environment variable NewConfig reads the environment from.
+// "dev" and "stage" are accepted as short forms; anything else, including
+// an empty value, means production.
+const EnvVar = "HTTPX_ENV"
+
+const (
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+type (
+	// ConfigOption customizes the AppConfig returned by NewConfig.
+	ConfigOption func(*config)
+
+	// JSONRenderer renders errors as JSON objects of the form
+	// {"error": "...", "code": "..."}; 500 responses carry the ErrorInfo
+	// details when they are provided.
+	JSONRenderer struct{}
+
+	config struct {
+		env         string
+		reporter    ErrorReporter
+		renderer    Renderer
+		rendererSet bool
+		renderers   map[string]Renderer
+		redaction   *RedactionPolicy
+		errorLimits ErrorBodyLimits
+	}
+
+	jsonErrorBody struct {
+		Error  string       `json:"error"`
+		Code   string       `json:"code,omitempty"`
+		Fields []FieldError `json:"fields,omitempty"`
+		// RetryAfter is AppError.RetryAfter in seconds.
+		RetryAfter int `json:"retry_after,omitempty"`
+		*ErrorInfo
+	}
+)
+
+var _ Renderer = JSONRenderer{}
+
+// NewConfig returns a ready-made AppConfig. By default the environment is
+// read from EnvVar, errors are not reported anywhere and the renderer is
+// chosen by environment: DebugRenderer in development, JSONRenderer with
+// error details in staging and the sanitized ProblemRenderer in production.
+func NewConfig(opts ...ConfigOption) AppConfig {
+	c := &config{
+		env:      normalizeEnv(os.Getenv(EnvVar)),
+		reporter: NopReporter(),
+		renderers: map[string]Renderer{
+			EnvDevelopment: DebugRenderer{},
+			EnvStaging:     JSONRenderer{},
+			EnvProduction:  ProblemRenderer{},
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if !c.rendererSet {
+		c.renderer = c.renderers[c.env]
+	}
+	return c
+}
+
+// WithEnvironment overrides the environment read from EnvVar.
+func WithEnvironment(env string) ConfigOption {
+	return func(c *config) {
+		c.env = normalizeEnv(env)
+	}
+}
+
+// WithDevelopment switches to the development environment, or away from it
+// to production.
+func WithDevelopment(dev bool) ConfigOption {
+	return func(c *config) {
+		switch {
+		case dev:
+			c.env = EnvDevelopment
+		case c.env == EnvDevelopment:
+			c.env = EnvProduction
+		}
+	}
+}
+
+// WithEnvRenderer replaces the renderer selected for env. It has no effect
+// when WithRenderer is used.
+func WithEnvRenderer(env string, r Renderer) ConfigOption {
+	return func(c *config) {
+		c.renderers[normalizeEnv(env)] = r
+	}
+}
+
+// WithReporter replaces the reporter. Nil logs errors to stderr.
+func WithReporter(r ErrorReporter) ConfigOption {
+	return func(c *config) {
+		c.reporter = r
+	}
+}
+
+// WithSlogReporter reports errors to logger. A nil logger uses slog.Default.
+func WithSlogReporter(logger *slog.Logger) ConfigOption {
+	return WithReporter(SlogReporter(logger))
+}
+
+// WithRenderer uses r in every environment. Nil falls back to plain-text
+// responses.
+func WithRenderer(r Renderer) ConfigOption {
+	return func(c *config) {
+		c.renderer = r
+		c.rendererSet = true
+	}
+}
+
+// WithRedaction replaces the default RedactionPolicy used in production.
+func WithRedaction(p *RedactionPolicy) ConfigOption {
+	return func(c *config) {
+		c.redaction = p
+	}
+}
+
+func (c *config) IsDevelopment() bool {
+	return c.env == EnvDevelopment
+}
+
+// Environment returns EnvDevelopment, EnvStaging or EnvProduction.
+func (c *config) Environment() string {
+	return c.env
+}
+
+// VerboseErrors exposes error details outside development too, in staging.
+func (c *config) VerboseErrors() bool {
+	return c.env != EnvProduction
+}
+
+func (c *config) ReportError(ctx context.Context, err error) {
+	if c.reporter == nil {
+		log.Printf("httpx: %v", err)
+		return
+	}
+	c.reporter.ReportError(ctx, err)
+}
+
+func (c *config) GetRenderer() Renderer {
+	return c.renderer
+}
+
+func (c *config) Redaction() *RedactionPolicy {
+	return c.redaction
+}
+
+func (c *config) ErrorBodyLimits() ErrorBodyLimits {
+	return c.errorLimits
+}
+
+func normalizeEnv(env string) string {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "development", "dev":
+		return EnvDevelopment
+	case "staging", "stage":
+		return EnvStaging
+	default:
+		return EnvProduction
+	}
+}
+
+func (JSONRenderer) Render500(ctx context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	writeJSONError(ctx, w, http.StatusInternalServerError, jsonErrorBody{
+		Error:     http.StatusText(http.StatusInternalServerError),
+		ErrorInfo: errInfo,
+	})
+}
+
+func (JSONRenderer) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError) {
+	body := jsonErrorBody{
+		Error:      appErr.Error(),
+		Code:       appErr.Code,
+		RetryAfter: retryAfterSeconds(appErr.RetryAfter),
+	}
+	if v, ok := AsValidationError(appErr); ok {
+		body.Fields = v.Fields
+	}
+	writeJSONError(ctx, w, appErr.StatusCode, body)
+}
+
+// writeJSONError writes body, inside an Envelope in envelope mode.
+func writeJSONError(ctx context.Context, w http.ResponseWriter, status int, body jsonErrorBody) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if env, ok := envelope(ctx, nil, &EnvelopeError{
+		Message:    body.Error,
+		Code:       body.Code,
+		Fields:     body.Fields,
+		RetryAfter: body.RetryAfter,
+		Details:    body.ErrorInfo,
+	}); ok {
+		writeJSON(w, env)
+		return
+	}
+	writeJSON(w, body)
+}
@@ -0,0 +1,77 @@
+package httpx
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeConfig lists the media types a route consumes and produces.
+// Entries may be wildcards such as "image/*". Empty lists are not enforced.
+type ContentTypeConfig struct {
+	Consumes []string
+	Produces []string
+}
+
+// ContentTypes rejects requests with a body whose Content-Type is not in
+// cfg.Consumes (415, code "unsupported_media_type") and requests whose
+// Accept header allows none of cfg.Produces (406, code "not_acceptable").
+// Scope it per route with a Group:
+//
+//	api := g.Group("/api", httpx.ContentTypes(adapter, httpx.ContentTypeConfig{
+//		Consumes: []string{"application/json"},
+//	}))
+func ContentTypes(adapter *HandlerAdapter, cfg ContentTypeConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(cfg.Consumes) > 0 && hasBody(r) {
+				mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || !mediaTypeAllowed(mediaType, cfg.Consumes) {
+					w.Header().Set("Accept", strings.Join(cfg.Consumes, ", "))
+					adapter.HandleError(w, r, StatusError(http.StatusUnsupportedMediaType,
+						"Content-Type must be one of %s", strings.Join(cfg.Consumes, ", ")).WithCode("unsupported_media_type"))
+					return
+				}
+			}
+
+			if len(cfg.Produces) > 0 && acceptsNone(r, cfg.Produces) {
+				adapter.HandleError(w, r, StatusError(http.StatusNotAcceptable,
+					"responses are available as %s", strings.Join(cfg.Produces, ", ")).WithCode("not_acceptable"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
+
+func mediaTypeAllowed(mediaType string, allowed []string) bool {
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(a, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsNone reports whether the Accept header excludes every produced
+// type. Wildcard entries in produced are matched by their family.
+func acceptsNone(r *http.Request, produced []string) bool {
+	if r.Header.Get("Accept") == "" {
+		return false
+	}
+	offers := make([]string, len(produced))
+	for i, p := range produced {
+		// A concrete member stands in for a wildcard family.
+		offers[i] = strings.Replace(p, "/*", "/x-any", 1)
+	}
+	return NegotiateContentType(r, offers...) == ""
+}
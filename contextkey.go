@@ -0,0 +1,53 @@
+package httpx
+
+import (
+	"context"
+	"net/netip"
+)
+
+// ContextKey is a typed context key. Keys compare by identity, so keys from
+// different packages never collide even when they share a name; the name
+// only serves debugging.
+//
+//	var tenantKey = httpx.NewContextKey[*Tenant]("tenant")
+//
+//	ctx = tenantKey.WithValue(ctx, t)
+//	t, ok := tenantKey.Value(ctx)
+type ContextKey[T any] struct {
+	name string
+}
+
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+func (k *ContextKey[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value stored under k and whether there was one.
+func (k *ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+func (k *ContextKey[T]) String() string {
+	return "httpx context key " + k.name
+}
+
+// Keys of the request-scoped values set by the package's middleware. Use
+// the accessors (RequestIDFromContext, PrincipalFromContext, ...) to read
+// them.
+var (
+	requestIDKey    = NewContextKey[string]("request ID")
+	principalKey    = NewContextKey[*Principal]("principal")
+	clientIPKey     = NewContextKey[netip.Addr]("client IP")
+	sessionKey      = NewContextKey[*Session]("session")
+	routePatternKey = NewContextKey[string]("route pattern")
+	requestLineKey  = NewContextKey[requestLine]("request line")
+)
+
+// requestLine is the method and path of the request, for reporters.
+type requestLine struct {
+	method, path string
+}
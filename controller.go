@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"net/http"
+	"time"
+)
+
+// Every ResponseWriter wrapper in this package implements Unwrap, Flush,
+// FlushError and Hijack, so http.ResponseController and type assertions keep
+// working behind middleware. The helpers below adjust the connection of the
+// current request from inside a handler.
+
+// ExtendReadDeadline allows the request body to be read for d more from now.
+// A zero d removes the deadline.
+func ExtendReadDeadline(w http.ResponseWriter, d time.Duration) error {
+	return http.NewResponseController(w).SetReadDeadline(deadlineFromNow(d))
+}
+
+// ExtendWriteDeadline allows the response to be written for d more from now,
+// e.g. before a long export. A zero d removes the deadline.
+func ExtendWriteDeadline(w http.ResponseWriter, d time.Duration) error {
+	return http.NewResponseController(w).SetWriteDeadline(deadlineFromNow(d))
+}
+
+// ExtendDeadlines extends both the read and the write deadline.
+func ExtendDeadlines(w http.ResponseWriter, d time.Duration) error {
+	if err := ExtendReadDeadline(w, d); err != nil {
+		return err
+	}
+	return ExtendWriteDeadline(w, d)
+}
+
+// Flush flushes buffered response data to the client, reporting writers that
+// cannot flush with http.ErrNotSupported.
+func Flush(w http.ResponseWriter) error {
+	return http.NewResponseController(w).Flush()
+}
+
+// EnableFullDuplex lets an HTTP/1 handler keep reading the request body after
+// it started writing the response.
+func EnableFullDuplex(w http.ResponseWriter) error {
+	return http.NewResponseController(w).EnableFullDuplex()
+}
+
+func deadlineFromNow(d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+// canFlush reports whether Flush would reach a flushing writer, without
+// committing the response.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch w.(type) {
+		case http.Flusher, interface{ FlushError() error }:
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
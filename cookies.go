@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxCookieSize is the per-cookie limit browsers are required to support.
+const maxCookieSize = 4096
+
+var (
+	// ErrInvalidCookie is returned when a signed or encrypted cookie fails
+	// verification under every key.
+	ErrInvalidCookie = errors.New("httpx: invalid cookie")
+	// ErrNoCookieKeys is returned when a cookie helper is called without keys.
+	ErrNoCookieKeys = errors.New("httpx: no cookie keys")
+)
+
+// SetSignedCookie sets c with its value signed by HMAC-SHA256 under the
+// first key. The value stays readable by the client; use SetEncryptedCookie
+// to hide it. The signature covers the cookie name, so a value cannot be
+// moved to another cookie.
+func SetSignedCookie(w http.ResponseWriter, c *http.Cookie, keys ...[]byte) error {
+	if len(keys) == 0 {
+		return ErrNoCookieKeys
+	}
+	return setCookie(w, c, signCookieValue(c.Name, c.Value, keys[0]))
+}
+
+// GetSignedCookie returns the verified value of the named cookie. Every key
+// is tried, so keys can be rotated by prepending a new one and dropping the
+// oldest once its cookies have expired. It returns http.ErrNoCookie when the
+// cookie is absent and ErrInvalidCookie when it does not verify.
+func GetSignedCookie(r *http.Request, name string, keys ...[]byte) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := verifyCookieValue(name, c.Value, keys); ok {
+		return v, nil
+	}
+	return "", ErrInvalidCookie
+}
+
+// SetEncryptedCookie sets c with its value encrypted and authenticated by
+// AES-GCM under the first key. Keys of any length are accepted and
+// stretched with SHA-256.
+func SetEncryptedCookie(w http.ResponseWriter, c *http.Cookie, keys ...[]byte) error {
+	if len(keys) == 0 {
+		return ErrNoCookieKeys
+	}
+	token, err := encryptCookieValue(c.Name, c.Value, keys[0])
+	if err != nil {
+		return err
+	}
+	return setCookie(w, c, token)
+}
+
+// GetEncryptedCookie is the counterpart of SetEncryptedCookie; keys rotate
+// as for GetSignedCookie.
+func GetEncryptedCookie(r *http.Request, name string, keys ...[]byte) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := decryptCookieValue(name, c.Value, keys); ok {
+		return v, nil
+	}
+	return "", ErrInvalidCookie
+}
+
+func setCookie(w http.ResponseWriter, c *http.Cookie, value string) error {
+	if len(value) > maxCookieSize {
+		return fmt.Errorf("httpx: cookie %q of %d bytes exceeds %d", c.Name, len(value), maxCookieSize)
+	}
+	sc := *c
+	sc.Value = value
+	http.SetCookie(w, &sc)
+	return nil
+}
+
+func cookieMAC(name, payload string, key []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(name))
+	m.Write([]byte{0})
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// signCookieValue encodes value so that it survives as a cookie-octet
+// string and appends the MAC.
+func signCookieValue(name, value string, key []byte) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(name, payload, key))
+}
+
+func verifyCookieValue(name, token string, keys [][]byte) (string, bool) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	for _, k := range keys {
+		if hmac.Equal(mac, cookieMAC(name, payload, k)) {
+			v, err := base64.RawURLEncoding.DecodeString(payload)
+			return string(v), err == nil
+		}
+	}
+	return "", false
+}
+
+func cookieAEAD(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptCookieValue seals value with the cookie name as additional data.
+func encryptCookieValue(name, value string, key []byte) (string, error) {
+	aead, err := cookieAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(name))), nil
+}
+
+func decryptCookieValue(name, token string, keys [][]byte) (string, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", false
+	}
+	for _, k := range keys {
+		aead, err := cookieAEAD(k)
+		if err != nil || len(data) < aead.NonceSize() {
+			return "", false
+		}
+		n := aead.NonceSize()
+		if plain, err := aead.Open(nil, data[:n], data[n:], []byte(name)); err == nil {
+			return string(plain), true
+		}
+	}
+	return "", false
+}
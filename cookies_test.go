@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecureCookies(t *testing.T) {
+	oldKey, newKey := []byte("old-key"), []byte("new-key")
+
+	type codec struct {
+		set func(http.ResponseWriter, *http.Cookie, ...[]byte) error
+		get func(*http.Request, string, ...[]byte) (string, error)
+	}
+	codecs := map[string]codec{
+		"signed":    {SetSignedCookie, GetSignedCookie},
+		"encrypted": {SetEncryptedCookie, GetEncryptedCookie},
+	}
+
+	// issue returns the Set-Cookie value written for name=value.
+	issue := func(t *testing.T, c codec, name, value string, keys ...[]byte) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		if err := c.set(w, &http.Cookie{Name: name, Value: value}, keys...); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+		return w.Result().Cookies()[0].Value
+	}
+	tamper := func(v string) string {
+		b := []byte(v)
+		if b[0] == 'A' {
+			b[0] = 'B'
+		} else {
+			b[0] = 'A'
+		}
+		return string(b)
+	}
+
+	for kind, c := range codecs {
+		t.Run(kind, func(t *testing.T) {
+			value := "user=42; admin"
+			token := issue(t, c, "session", value, oldKey)
+			if kind == "encrypted" && strings.Contains(token, "user=") {
+				t.Errorf("encrypted cookie %q leaks its value", token)
+			}
+
+			moved := issue(t, c, "remember", value, oldKey)
+
+			tests := []struct {
+				name   string
+				cookie string
+				keys   [][]byte
+				want   string
+				err    error
+			}{
+				{name: "valid", cookie: token, keys: [][]byte{oldKey}, want: value},
+				{name: "rotated key", cookie: token, keys: [][]byte{newKey, oldKey}, want: value},
+				{name: "retired key", cookie: token, keys: [][]byte{newKey}, err: ErrInvalidCookie},
+				{name: "tampered", cookie: tamper(token), keys: [][]byte{oldKey}, err: ErrInvalidCookie},
+				{name: "issued for another name", cookie: moved, keys: [][]byte{oldKey}, err: ErrInvalidCookie},
+				{name: "plain value", cookie: "admin", keys: [][]byte{oldKey}, err: ErrInvalidCookie},
+				{name: "missing", keys: [][]byte{oldKey}, err: http.ErrNoCookie},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					r := httptest.NewRequest(http.MethodGet, "/", nil)
+					if tt.cookie != "" {
+						r.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+					}
+					got, err := c.get(r, "session", tt.keys...)
+					if !errors.Is(err, tt.err) || got != tt.want {
+						t.Errorf("got %q, %v; want %q, %v", got, err, tt.want, tt.err)
+					}
+				})
+			}
+
+			w := httptest.NewRecorder()
+			if err := c.set(w, &http.Cookie{Name: "session", Value: value}); !errors.Is(err, ErrNoCookieKeys) {
+				t.Errorf("set without keys: %v, want ErrNoCookieKeys", err)
+			}
+			if err := c.set(w, &http.Cookie{Name: "session", Value: strings.Repeat("x", maxCookieSize)}, oldKey); err == nil {
+				t.Error("set of an oversized cookie succeeded")
+			}
+			if len(w.Result().Cookies()) != 0 {
+				t.Error("failed set wrote a cookie")
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCORSMaxAge is how long browsers may cache preflight answers.
+const DefaultCORSMaxAge = 10 * time.Minute
+
+// corsMethods are the methods preflights derived from routes are checked
+// for, besides the requested one.
+var corsMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost,
+	http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+type (
+	// CORSConfig configures CORS. Zero values select the defaults noted on
+	// each field.
+	CORSConfig struct {
+		// AllowOrigins lists the origins allowed to call, such as
+		// "https://app.example.com"; "*" allows any, but without
+		// credentials. Requests from other origins get no CORS headers,
+		// which browsers treat as a denial.
+		AllowOrigins []string
+		// AllowMethods answers preflights when Routes is nil; GET, HEAD and
+		// POST if empty.
+		AllowMethods []string
+		// AllowHeaders are allowed on every route, on top of the headers of
+		// the route; Content-Type if empty.
+		AllowHeaders []string
+		// ExposeHeaders lists the response headers scripts may read.
+		ExposeHeaders []string
+		// AllowCredentials lets browsers send cookies and Authorization
+		// headers to routes that need credentials (RouteInfo.Credentials),
+		// or to every route when Routes is nil. It only applies to the
+		// origins listed explicitly in AllowOrigins, whose origin is echoed
+		// instead of "*"; origins allowed through "*" never get
+		// credentials, or any website could act on behalf of the user.
+		AllowCredentials bool
+		// MaxAge is how long browsers may cache a preflight answer;
+		// DefaultCORSMaxAge if zero. Negative disables caching.
+		MaxAge time.Duration
+		// Routes lists the routes, normally Group.Routes, preflights are
+		// answered from: with the methods registered for the requested path
+		// and the headers and credentials of the route serving the
+		// requested method, instead of one global policy. It is read once,
+		// on the first cross-origin request, so register routes first.
+		Routes func() []RouteInfo
+		// Reporter receives the errors of reading Routes, such as patterns
+		// ServeMux cannot tell apart, whose routes are then left out;
+		// logged to stderr if nil.
+		Reporter ErrorReporter
+	}
+
+	// corsRoutes matches requests against the routes of CORSConfig.Routes.
+	corsRoutes struct {
+		load     func() []RouteInfo
+		reporter ErrorReporter
+		once     sync.Once
+		mux      *http.ServeMux
+		infos    map[string]RouteInfo
+	}
+)
+
+// CORS answers cross-origin preflight requests and adds CORS headers to
+// cross-origin responses. It must wrap the router, since the routes do not
+// register OPTIONS.
+//
+// With CORSConfig.Routes, a preflight for "DELETE /users/42" allows only
+// the methods registered for /users/{id}, the headers the DELETE route
+// declares, Authorization when it authenticates, and credentials only if it
+// needs them, so public and protected routes no longer share the most
+// permissive policy:
+//
+//	api := httpx.NewGroup(mux, adapter)
+//	...
+//	handler := httpx.CORS(httpx.CORSConfig{
+//		AllowOrigins:     []string{"https://app.example.com"},
+//		AllowCredentials: true,
+//		Routes:           api.Routes,
+//	})(mux)
+func CORS(cfg CORSConfig) Middleware {
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = []string{"Content-Type"}
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = DefaultCORSMaxAge
+	}
+	anyOrigin := slices.Contains(cfg.AllowOrigins, "*")
+	var routes *corsRoutes
+	if cfg.Routes != nil {
+		routes = &corsRoutes{load: cfg.Routes, reporter: cfg.Reporter}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			listed := slices.Contains(cfg.AllowOrigins, origin)
+			allowed := listed || anyOrigin
+
+			reqMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || reqMethod == "" {
+				if allowed {
+					credentials := cfg.AllowCredentials && listed
+					if routes != nil {
+						info, _ := routes.match(r, r.Method)
+						credentials = credentials && info.Credentials
+					}
+					setCORSOrigin(h, origin, !listed, credentials)
+					if len(cfg.ExposeHeaders) > 0 {
+						h.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposeHeaders, ", "))
+					}
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			methods, headers, credentials := cfg.AllowMethods, cfg.AllowHeaders, cfg.AllowCredentials && listed
+			if routes != nil {
+				info, ok := routes.match(r, reqMethod)
+				if !ok {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				methods = routes.methods(r, reqMethod)
+				headers = append(slices.Clone(headers), info.Headers...)
+				credentials = credentials && info.Credentials
+			}
+
+			setCORSOrigin(h, origin, !listed, credentials)
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// setCORSOrigin allows origin, or any origin when wildcard, and credentials
+// only for a listed origin.
+func setCORSOrigin(h http.Header, origin string, wildcard, credentials bool) {
+	if wildcard {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// match returns the route serving method for the path and host of r,
+// using ServeMux precedence.
+func (c *corsRoutes) match(r *http.Request, method string) (RouteInfo, bool) {
+	c.once.Do(func() {
+		if err := c.build(); err != nil {
+			c.report(r, err)
+		}
+	})
+	_, pattern := c.mux.Handler(&http.Request{Method: method, Host: r.Host, URL: r.URL})
+	info, ok := c.infos[pattern]
+	return info, ok
+}
+
+// methods lists the methods registered for the path of r, method first.
+func (c *corsRoutes) methods(r *http.Request, method string) []string {
+	methods := []string{method}
+	for _, m := range corsMethods {
+		if m == method {
+			continue
+		}
+		if _, ok := c.match(r, m); ok {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// build registers the routes on a ServeMux, leaving out and returning the
+// patterns it rejects.
+func (c *corsRoutes) build() error {
+	c.mux = http.NewServeMux()
+	c.infos = map[string]RouteInfo{}
+	var errs []error
+	for _, info := range c.load() {
+		if _, dup := c.infos[info.Pattern]; dup {
+			continue
+		}
+		if err := handlePattern(c.mux, info.Pattern); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.infos[info.Pattern] = info
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("httpx: CORS routes: %w", err)
+	}
+	return nil
+}
+
+func (c *corsRoutes) report(r *http.Request, err error) {
+	if c.reporter != nil {
+		c.reporter.ReportError(r.Context(), err)
+		return
+	}
+	log.Print(err)
+}
+
+// handlePattern registers pattern on mux, returning the panic of invalid or
+// conflicting patterns as an error.
+func handlePattern(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+	mux.Handle(pattern, http.NotFoundHandler())
+	return nil
+}
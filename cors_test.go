@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		origin      string
+		allow       string
+		credentials bool
+	}{
+		{
+			name:   "listed",
+			cfg:    CORSConfig{AllowOrigins: []string{"https://app.example.com"}},
+			origin: "https://app.example.com",
+			allow:  "https://app.example.com",
+		},
+		{
+			name:   "unlisted",
+			cfg:    CORSConfig{AllowOrigins: []string{"https://app.example.com"}},
+			origin: "https://evil.example.com",
+		},
+		{
+			name:   "wildcard",
+			cfg:    CORSConfig{AllowOrigins: []string{"*"}},
+			origin: "https://any.example.com",
+			allow:  "*",
+		},
+		{
+			name:        "listed with credentials",
+			cfg:         CORSConfig{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+			origin:      "https://app.example.com",
+			allow:       "https://app.example.com",
+			credentials: true,
+		},
+		{
+			name:   "wildcard never gets credentials",
+			cfg:    CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			origin: "https://evil.example.com",
+			allow:  "*",
+		},
+		{
+			name:        "listed next to wildcard gets credentials",
+			cfg:         CORSConfig{AllowOrigins: []string{"*", "https://app.example.com"}, AllowCredentials: true},
+			origin:      "https://app.example.com",
+			allow:       "https://app.example.com",
+			credentials: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := CORS(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			for _, preflight := range []bool{false, true} {
+				req := httptest.NewRequest(http.MethodGet, "/items", nil)
+				if preflight {
+					req.Method = http.MethodOptions
+					req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				}
+				req.Header.Set("Origin", tt.origin)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+					t.Errorf("preflight %v: Allow-Origin = %q, want %q", preflight, got, tt.allow)
+				}
+				if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+					t.Errorf("preflight %v: Allow-Credentials = %v, want %v", preflight, got, tt.credentials)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSPreflightRoutes(t *testing.T) {
+	adapter := NewDefaultHandlerAdapter(NewConfig())
+	mux := http.NewServeMux()
+	api := NewGroup(mux, adapter)
+	ok := func(w http.ResponseWriter, r *http.Request) error { return nil }
+
+	api.HandleExt("GET /public", ok)
+	csrf := api.Group("", CSRFMiddleware(adapter, CSRFConfig{HeaderName: "X-XSRF"}))
+	csrf.HandleExt("POST /forms", ok)
+	keys := api.Group("", When(Method(http.MethodDelete), AuthMiddleware(adapter, &APIKeyAuthenticator{Header: "X-Key"})))
+	keys.HandleExt("GET /users/{id}", ok)
+	keys.HandleExt("DELETE /users/{id}", ok)
+	if got := api.Routes()[1].Middleware; len(got) != 1 || got[0] != "httpx.CSRFMiddleware" {
+		t.Fatalf("Middleware = %q, want [httpx.CSRFMiddleware]", got)
+	}
+
+	h := CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowCredentials: true,
+		Routes:           api.Routes,
+	})(mux)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		methods     string
+		headers     string
+		credentials bool
+	}{
+		{name: "public", method: http.MethodGet, path: "/public", methods: "GET, HEAD", headers: "Content-Type"},
+		{name: "custom CSRF header", method: http.MethodPost, path: "/forms", methods: "POST", headers: "Content-Type, X-XSRF", credentials: true},
+		{name: "API key through When", method: http.MethodDelete, path: "/users/42", methods: "DELETE, GET, HEAD", headers: "Content-Type, X-Key", credentials: true},
+		{name: "unknown route", method: http.MethodPut, path: "/public"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != tt.methods {
+				t.Errorf("Allow-Methods = %q, want %q", got, tt.methods)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != tt.headers {
+				t.Errorf("Allow-Headers = %q, want %q", got, tt.headers)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+				t.Errorf("Allow-Credentials = %v, want %v", got, tt.credentials)
+			}
+		})
+	}
+}
+
+func TestCORSConflictingRoutes(t *testing.T) {
+	var reported error
+	h := CORS(CORSConfig{
+		AllowOrigins: []string{"https://app.example.com"},
+		Routes: func() []RouteInfo {
+			return []RouteInfo{{Pattern: "GET /a/{x}"}, {Pattern: "GET /{y}/b"}, {Pattern: "GET /c"}}
+		},
+		Reporter: ReporterFunc(func(_ context.Context, err error) { reported = err }),
+	})(http.NotFoundHandler())
+
+	for _, path := range []string{"/a/b", "/c"} {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if reported == nil || !strings.Contains(reported.Error(), "conflicts") {
+		t.Errorf("reported %v, want the pattern conflict", reported)
+	}
+}
@@ -0,0 +1,146 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+const csrfTokenLen = 32
+
+type (
+	// CSRFConfig configures CSRFMiddleware. Zero values select the defaults
+	// noted on each field.
+	CSRFConfig struct {
+		// CookieName defaults to "_csrf".
+		CookieName string
+		// HeaderName defaults to "X-CSRF-Token".
+		HeaderName string
+		// FormField defaults to "csrf_token".
+		FormField string
+		// Keys, when set, sign the token cookie (see SetSignedCookie) so
+		// that a cookie planted by a sibling subdomain is rejected.
+		Keys [][]byte
+
+		Path     string
+		Domain   string
+		MaxAge   int
+		Secure   bool
+		SameSite http.SameSite
+	}
+
+	csrfTokenKey struct{}
+)
+
+// GenerateCSRFToken returns a new random URL-safe token.
+func GenerateCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating CSRF token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CSRFToken returns the token for the current request, for embedding into
+// forms or meta tags. It is empty when CSRFMiddleware is not installed.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// CSRFMiddleware implements the double-submit cookie pattern. Every request
+// gets a token cookie (reused while valid); state-changing requests must echo
+// the cookie value in the header or form field, otherwise they are rejected
+// with a 403 AppError through the adapter.
+func CSRFMiddleware(adapter *HandlerAdapter, cfg CSRFConfig) Middleware {
+	if cfg.CookieName == "" {
+		cfg.CookieName = "_csrf"
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = "X-CSRF-Token"
+	}
+	if cfg.FormField == "" {
+		cfg.FormField = CSRFFormField
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	info := RouteInfo{Headers: []string{cfg.HeaderName}, Credentials: true}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if v, err := csrfCookie(r, cfg); err == nil && validCSRFToken(v) {
+				token = v
+			}
+			cookieToken := token
+
+			if token == "" {
+				var err error
+				if token, err = GenerateCSRFToken(); err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+				c := &http.Cookie{
+					Name:     cfg.CookieName,
+					Value:    token,
+					Path:     cfg.Path,
+					Domain:   cfg.Domain,
+					MaxAge:   cfg.MaxAge,
+					Secure:   cfg.Secure,
+					HttpOnly: true,
+					SameSite: cfg.SameSite,
+				}
+				if len(cfg.Keys) == 0 {
+					http.SetCookie(w, c)
+				} else if err := SetSignedCookie(w, c, cfg.Keys...); err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+			}
+			w.Header().Add("Vary", "Cookie")
+
+			if !isSafeMethod(r.Method) {
+				submitted := r.Header.Get(cfg.HeaderName)
+				if submitted == "" {
+					submitted = r.PostFormValue(cfg.FormField)
+				}
+				if cookieToken == "" || submitted == "" || !secureCompare(submitted, cookieToken) {
+					adapter.HandleError(w, r, ForbiddenError("invalid CSRF token").WithCode("csrf_invalid"))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token)))
+		})
+	})
+}
+
+func csrfCookie(r *http.Request, cfg CSRFConfig) (string, error) {
+	if len(cfg.Keys) > 0 {
+		return GetSignedCookie(r, cfg.CookieName, cfg.Keys...)
+	}
+	c, err := r.Cookie(cfg.CookieName)
+	if err != nil {
+		return "", err
+	}
+	return c.Value, nil
+}
+
+func validCSRFToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == csrfTokenLen
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
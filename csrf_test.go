@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	adapter := NewDefaultHandlerAdapter(NewConfig())
+	keys := [][]byte{[]byte("0123456789abcdef0123456789abcdef")}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r.Context())))
+	})
+	plain := CSRFMiddleware(adapter, CSRFConfig{})(ok)
+	signed := CSRFMiddleware(adapter, CSRFConfig{Keys: keys})(ok)
+
+	// issue fetches a token cookie with a GET.
+	issue := func(h http.Handler) (*http.Cookie, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusOK || len(cookies) != 1 {
+			t.Fatalf("GET: %d with cookies %v", w.Code, cookies)
+		}
+		return cookies[0], w.Body.String()
+	}
+	plainCookie, plainToken := issue(plain)
+	signedCookie, signedToken := issue(signed)
+	if plainCookie.Value != plainToken || !plainCookie.HttpOnly || plainCookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie %v for token %q", plainCookie, plainToken)
+	}
+	otherToken, _ := GenerateCSRFToken()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		method  string
+		cookie  *http.Cookie
+		header  string
+		form    string
+		status  int
+	}{
+		{name: "safe method without token", handler: plain, method: http.MethodGet, status: http.StatusOK},
+		{name: "header matches cookie", handler: plain, method: http.MethodPost, cookie: plainCookie, header: plainToken, status: http.StatusOK},
+		{name: "form field matches cookie", handler: plain, method: http.MethodPost, cookie: plainCookie, form: plainToken, status: http.StatusOK},
+		{name: "missing cookie", handler: plain, method: http.MethodPost, header: plainToken, status: http.StatusForbidden},
+		{name: "missing token", handler: plain, method: http.MethodDelete, cookie: plainCookie, status: http.StatusForbidden},
+		{name: "mismatched token", handler: plain, method: http.MethodPost, cookie: plainCookie, header: otherToken, status: http.StatusForbidden},
+		{name: "malformed cookie", handler: plain, method: http.MethodPost, cookie: &http.Cookie{Name: "_csrf", Value: "short"}, header: "short", status: http.StatusForbidden},
+		{name: "signed cookie", handler: signed, method: http.MethodPost, cookie: signedCookie, header: signedToken, status: http.StatusOK},
+		{name: "planted unsigned cookie", handler: signed, method: http.MethodPost, cookie: &http.Cookie{Name: "_csrf", Value: otherToken}, header: otherToken, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.form != "" {
+				req = httptest.NewRequest(tt.method, "/form", strings.NewReader(url.Values{CSRFFormField: {tt.form}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, "/form", nil)
+			}
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if w.Header().Get("Vary") != "Cookie" {
+				t.Errorf("Vary = %q, want Cookie", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
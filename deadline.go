@@ -0,0 +1,91 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestTimeoutHeader is the default header for client-supplied timeouts.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// RequestTimeoutConfig configures RequestTimeout.
+type RequestTimeoutConfig struct {
+	// Header carries the client's timeout; RequestTimeoutHeader if empty.
+	// Values are Go durations ("1.5s"), plain seconds ("2") or gRPC
+	// timeouts ("500m"), so "grpc-timeout" works as well. The gRPC reading
+	// wins where they overlap: "5m" is five milliseconds.
+	Header string
+	// Default applies when the header is absent or invalid; none if zero.
+	Default time.Duration
+	// Max caps client-supplied timeouts; uncapped if zero.
+	Max time.Duration
+}
+
+// RequestTimeout bounds the request context by the timeout the client
+// asked for, capped by cfg.Max. When the deadline passes before a response
+// is committed, the client gets a 504 AppError with code
+// "deadline_exceeded"; handlers returning the context error get the same.
+func RequestTimeout(adapter *HandlerAdapter, cfg RequestTimeoutConfig) Middleware {
+	header := orDefault(cfg.Header, RequestTimeoutHeader)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := parseTimeout(r.Header.Get(header))
+			if !ok {
+				d = cfg.Default
+			}
+			if cfg.Max > 0 && (d <= 0 || d > cfg.Max) {
+				d = cfg.Max
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			cw := &commitWriter{ResponseWriter: w}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(cw, r)
+
+			if !cw.committed && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				adapter.HandleError(cw, r, deadlineExceededError(ctx.Err()))
+			}
+		})
+	}
+}
+
+func deadlineExceededError(err error) AppError {
+	return WrapStatus(err, http.StatusGatewayTimeout, "request deadline exceeded").WithCode("deadline_exceeded")
+}
+
+// parseTimeout accepts Go durations, decimal seconds and gRPC timeouts
+// (up to 8 digits followed by one of H, M, S, m, u, n).
+func parseTimeout(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if n := len(v); n >= 2 && n <= 9 {
+		units := map[byte]time.Duration{
+			'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+			'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+		}
+		if unit, ok := units[v[n-1]]; ok {
+			if digits, err := strconv.ParseUint(v[:n-1], 10, 64); err == nil {
+				return time.Duration(digits) * unit, digits > 0
+			}
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, d > 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 && secs < 1e9 {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	return 0, false
+}
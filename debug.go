@@ -0,0 +1,183 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DebugPath is the conventional mount point for Debugger.Handler.
+const DebugPath = "/debug/httpx"
+
+type (
+	// DebugConfig selects what a Debugger exposes. Every field is optional.
+	DebugConfig struct {
+		// Adapter renders the 403 for disallowed clients, and its settings
+		// are included in the output.
+		Adapter *HandlerAdapter
+		// Group lists its routes and their middleware.
+		Group *Group
+		// Limiters and Breakers are reported by name. Breakers that were
+		// not built by NewBreakerTransport are skipped.
+		Limiters map[string]*ConcurrencyLimiter
+		Breakers map[string]http.RoundTripper
+		// Allow lists the networks that may read the endpoint; loopback
+		// only if empty.
+		Allow []netip.Prefix
+		// RecentErrors is how many error summaries are kept; 50 if zero.
+		RecentErrors int
+	}
+
+	// Debugger collects live state of the httpx stack and serves it as
+	// JSON. It is opt-in: install Middleware to count in-flight requests,
+	// wrap the adapter's Reporter with Reporter to keep recent errors, and
+	// mount Handler, usually at DebugPath. In development, RecordTraffic
+	// adds recent requests and responses to the output.
+	Debugger struct {
+		cfg      DebugConfig
+		inFlight atomic.Int64
+		served   atomic.Int64
+
+		mu      sync.Mutex
+		errors  []DebugError
+		next    int
+		traffic *trafficRing
+	}
+
+	// DebugError summarizes a reported error.
+	DebugError struct {
+		Time      time.Time   `json:"time"`
+		Route     string      `json:"route,omitempty"`
+		Status    int         `json:"status,omitempty"`
+		Class     ErrorClass  `json:"class"`
+		RequestID string      `json:"request_id,omitempty"`
+		Tags      []ReportTag `json:"tags,omitempty"`
+		Error     string      `json:"error"`
+	}
+)
+
+func NewDebugger(cfg DebugConfig) *Debugger {
+	if len(cfg.Allow) == 0 {
+		cfg.Allow = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	}
+	if cfg.RecentErrors <= 0 {
+		cfg.RecentErrors = 50
+	}
+	if cfg.Adapter == nil {
+		cfg.Adapter = &HandlerAdapter{}
+	}
+	return &Debugger{cfg: cfg}
+}
+
+// Middleware counts in-flight and served requests.
+func (d *Debugger) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d.inFlight.Add(1)
+			defer func() {
+				d.inFlight.Add(-1)
+				d.served.Add(1)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Reporter records a summary of every error before passing it to next,
+// which may be nil.
+func (d *Debugger) Reporter(next ErrorReporter) ErrorReporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		e := DebugError{
+			Time:      time.Now(),
+			Route:     routePattern(ctx),
+			Class:     Classify(err).Class,
+			RequestID: RequestIDFromContext(ctx),
+			Tags:      ReportTags(ctx),
+			Error:     err.Error(),
+		}
+		var se Error
+		if errors.As(err, &se) {
+			e.Status = se.GetStatusCode()
+		}
+
+		d.mu.Lock()
+		if len(d.errors) < d.cfg.RecentErrors {
+			d.errors = append(d.errors, e)
+		} else {
+			d.errors[d.next] = e
+		}
+		d.next = (d.next + 1) % d.cfg.RecentErrors
+		d.mu.Unlock()
+
+		if next != nil {
+			next.ReportError(ctx, err)
+		}
+	})
+}
+
+// recentErrors returns the kept summaries, newest first.
+func (d *Debugger) recentErrors() []DebugError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DebugError, 0, len(d.errors))
+	for i := range len(d.errors) {
+		out = append(out, d.errors[(d.next-1-i+len(d.errors))%len(d.errors)])
+	}
+	return out
+}
+
+// Handler serves the collected state as JSON to allowed clients, see
+// DebugConfig.Allow and IPAccess.
+func (d *Debugger) Handler() http.Handler {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := d.cfg.Adapter
+		state := map[string]interface{}{
+			"in_flight": d.inFlight.Load(),
+			"served":    d.served.Load(),
+			"adapter": map[string]interface{}{
+				"recover_panics":       a.RecoverPanics,
+				"detect_server_errors": a.DetectServerErrors,
+				"max_body_bytes":       a.MaxBodyBytes,
+				"redaction":            a.Redaction != nil,
+			},
+			"recent_errors": d.recentErrors(),
+		}
+		if d.cfg.Group != nil {
+			state["routes"] = d.cfg.Group.Routes()
+		}
+		d.mu.Lock()
+		traffic := d.traffic
+		d.mu.Unlock()
+		if traffic != nil {
+			state["traffic"] = traffic.recent()
+		}
+		if len(d.cfg.Limiters) > 0 {
+			limiters := make(map[string]LimiterStats, len(d.cfg.Limiters))
+			for name, l := range d.cfg.Limiters {
+				limiters[name] = l.Stats()
+			}
+			state["limiters"] = limiters
+		}
+		if len(d.cfg.Breakers) > 0 {
+			breakers := make(map[string]map[string]CircuitState, len(d.cfg.Breakers))
+			for name, rt := range d.cfg.Breakers {
+				if s := CircuitStates(rt); s != nil {
+					breakers[name] = s
+				}
+			}
+			state["breakers"] = breakers
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(state)
+	})
+	return IPAccess(d.cfg.Adapter, IPAccessConfig{Allow: d.cfg.Allow})(h)
+}
@@ -0,0 +1,171 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxDecompressedSize limits decompressed request bodies when
+// DecompressConfig.MaxSize is zero.
+const DefaultMaxDecompressedSize = 10 << 20
+
+type (
+	// Decoding is a request Content-Encoding the Decompress middleware can
+	// undo.
+	Decoding struct {
+		// Name is the Content-Encoding token, e.g. "gzip".
+		Name string
+		// NewReader returns a reader decompressing r. Close must not close
+		// r.
+		NewReader func(r io.Reader) (io.ReadCloser, error)
+	}
+
+	// DecompressConfig configures the Decompress middleware.
+	DecompressConfig struct {
+		// Decodings accepted. Defaults to gzip and deflate.
+		Decodings []Decoding
+		// MaxSize limits the decompressed body, guarding against
+		// decompression bombs. Defaults to DefaultMaxDecompressedSize.
+		MaxSize int64
+	}
+
+	decodedBody struct {
+		io.Reader
+		closers []io.Closer
+	}
+)
+
+// Decompress transparently decompresses request bodies sent with a
+// Content-Encoding, so binding helpers read plain content. Bodies growing
+// beyond cfg.MaxSize fail on read with *http.MaxBytesError, which the
+// adapter renders as a 413 AppError when the handler returns it.
+//
+// Unsupported encodings are rejected with a 415 AppError (code
+// "unsupported_content_encoding") listing the supported ones in
+// Accept-Encoding, and malformed compressed data with a 400 (code
+// "invalid_content_encoding").
+func Decompress(adapter *HandlerAdapter, cfg DecompressConfig) Middleware {
+	if len(cfg.Decodings) == 0 {
+		cfg.Decodings = []Decoding{GzipDecoding(), DeflateDecoding()}
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultMaxDecompressedSize
+	}
+	names := make([]string, len(cfg.Decodings))
+	for i, d := range cfg.Decodings {
+		names[i] = d.Name
+	}
+	accept := strings.Join(names, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			codings := contentCodings(r.Header)
+			if len(codings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Codings are listed in the order they were applied.
+			body := &decodedBody{Reader: r.Body, closers: []io.Closer{r.Body}}
+			for i := len(codings) - 1; i >= 0; i-- {
+				d := findDecoding(cfg.Decodings, codings[i])
+				if d == nil {
+					body.Close()
+					w.Header().Set("Accept-Encoding", accept)
+					adapter.HandleError(w, r, StatusError(http.StatusUnsupportedMediaType,
+						"unsupported Content-Encoding %s", codings[i]).WithCode("unsupported_content_encoding"))
+					return
+				}
+				rc, err := d.NewReader(body.Reader)
+				if err != nil {
+					body.Close()
+					adapter.HandleError(w, r, BadRequestError("invalid %s request body", d.Name).WithCode("invalid_content_encoding"))
+					return
+				}
+				body.Reader = rc
+				body.closers = append(body.closers, rc)
+			}
+
+			r2 := r.WithContext(r.Context())
+			r2.Header = r.Header.Clone()
+			r2.Header.Del("Content-Encoding")
+			r2.Header.Del("Content-Length")
+			r2.ContentLength = -1
+			r2.Body = http.MaxBytesReader(w, body, cfg.MaxSize)
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// GzipDecoding returns the "gzip" decoding. Concatenated gzip members are
+// read as one stream.
+func GzipDecoding() Decoding {
+	return Decoding{
+		Name: "gzip",
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+}
+
+// DeflateDecoding returns the "deflate" decoding. It accepts the zlib
+// format the specification requires as well as the raw deflate data many
+// clients send.
+func DeflateDecoding() Decoding {
+	return Decoding{
+		Name: "deflate",
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			br := bufio.NewReader(r)
+			if h, err := br.Peek(2); err == nil && isZlibHeader(h) {
+				return zlib.NewReader(br)
+			}
+			return flate.NewReader(br), nil
+		},
+	}
+}
+
+// isZlibHeader reports whether h starts with a zlib header: the deflate
+// method and a check value making the first two bytes a multiple of 31.
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// contentCodings returns the Content-Encoding tokens of h in order, without
+// "identity".
+func contentCodings(h http.Header) []string {
+	var codings []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if c != "" && c != "identity" {
+				codings = append(codings, c)
+			}
+		}
+	}
+	return codings
+}
+
+func findDecoding(decodings []Decoding, name string) *Decoding {
+	for i := range decodings {
+		if strings.EqualFold(decodings[i].Name, name) {
+			return &decodings[i]
+		}
+	}
+	return nil
+}
+
+// Close closes the decoders, innermost last.
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
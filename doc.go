@@ -0,0 +1,22 @@
+// Package httpx adapts error-returning handlers (HTTPHandlerExt) to
+// net/http and provides the middleware and helpers around them.
+//
+// The core package depends on the standard library only. Subsystems that
+// need third-party code live in nested modules with their own go.mod, so
+// importing httpx for error handling does not pull in their dependencies:
+//
+//	github.com/radim/httpx/autocert  ACME/Let's Encrypt certificates for Server
+//	github.com/radim/httpx/brotli    brotli response compression
+//	github.com/radim/httpx/chi       handler registration on chi routers
+//	github.com/radim/httpx/echo      Echo middleware and handler adapters
+//	github.com/radim/httpx/gin       Gin middleware adapter
+//	github.com/radim/httpx/gorilla   handler registration on gorilla/mux
+//	github.com/radim/httpx/grpc      gRPC status code mapping
+//	github.com/radim/httpx/msgpack   MessagePack Codec
+//	github.com/radim/httpx/protobuf  Protocol Buffers Codec
+//	github.com/radim/httpx/redis     Redis-backed stores for shared state
+//	github.com/radim/httpx/zstd      zstd and shared-dictionary compression
+//
+// Packages without third-party dependencies, such as healthcheck and
+// httpxtest, are part of the core module.
+package httpx
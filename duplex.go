@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+)
+
+// CommittedError wraps an error that occurred after the response status
+// was sent. HandleError reports it through the adapter's Reporter instead
+// of rendering an error response into the half-written body.
+type CommittedError struct {
+	Err error
+}
+
+func (e *CommittedError) Error() string {
+	return "response already committed: " + e.Err.Error()
+}
+
+func (e *CommittedError) Unwrap() error {
+	return e.Err
+}
+
+// FullDuplex runs fn with the request body and the response writer usable
+// concurrently, for endpoints that stream a transformation of the request
+// body back to the client. HTTP/1 connections need EnableFullDuplex for this;
+// when the writer does not support it an error is returned before fn runs.
+//
+// Errors returned by fn before anything was written are returned unchanged so
+// the adapter renders them as usual. Once the status was sent they are wrapped
+// in *CommittedError and only reported.
+func FullDuplex(w http.ResponseWriter, r *http.Request, fn func(body io.Reader, w http.ResponseWriter) error) error {
+	if err := EnableFullDuplex(w); err != nil {
+		return err
+	}
+
+	cw := &commitWriter{ResponseWriter: w}
+	if err := fn(r.Body, cw); err != nil {
+		if cw.committed {
+			return &CommittedError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
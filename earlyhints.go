@@ -0,0 +1,38 @@
+package httpx
+
+import (
+	"net/http"
+)
+
+// Preload returns a Link header value preloading href as the given
+// destination ("style", "script", "font", ...). Fonts are marked
+// crossorigin, as browsers require.
+func Preload(href, as string) string {
+	v := "<" + href + ">; rel=preload; as=" + as
+	if as == "font" {
+		v += "; crossorigin"
+	}
+	return v
+}
+
+// EarlyHints sends a 103 Early Hints response carrying links as Link
+// headers, letting browsers fetch critical resources while the handler
+// is still working. The links stay set for the final response, whose
+// status (and any error rendering) is unaffected, and every httpx writer
+// passes informational responses through. Clients older than HTTP/1.1
+// cannot receive 1xx responses, so for them this is a no-op.
+//
+//	httpx.EarlyHints(w, r, httpx.Preload("/app.css", "style"), httpx.Preload("/app.js", "script"))
+func EarlyHints(w http.ResponseWriter, r *http.Request, links ...string) {
+	if len(links) == 0 {
+		return
+	}
+	h := w.Header()
+	for _, l := range links {
+		h.Add("Link", l)
+	}
+	if !r.ProtoAtLeast(1, 1) {
+		return
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+}
@@ -0,0 +1,62 @@
+// Package echo adapts Echo middleware and handlers to httpx, easing the
+// migration of existing Echo applications onto the adapter one piece at a
+// time.
+package echo
+
+import (
+	"fmt"
+	"net/http"
+
+	echov4 "github.com/labstack/echo/v4"
+
+	"github.com/radim/httpx"
+)
+
+// Middleware converts Echo middleware into httpx middleware for use with
+// httpx.Chain. Errors returned by the middleware are handled by adapter;
+// *echo.HTTPError keeps its status and message.
+func Middleware(adapter *httpx.HandlerAdapter, mws ...echov4.MiddlewareFunc) httpx.Middleware {
+	e := echov4.New()
+
+	return func(next http.Handler) http.Handler {
+		h := func(c echov4.Context) error {
+			next.ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := e.NewContext(r, w)
+			if err := h(c); err != nil {
+				adapter.HandleError(w, r, convertError(c, err))
+			}
+		})
+	}
+}
+
+// Handler converts an Echo handler into an http.Handler wrapped by adapter.
+// The handler runs outside Echo's router, so c.Param is empty; read path
+// parameters with c.Request().PathValue instead.
+func Handler(adapter *httpx.HandlerAdapter, h echov4.HandlerFunc) http.Handler {
+	e := echov4.New()
+
+	return adapter.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		c := e.NewContext(r, w)
+		if err := h(c); err != nil {
+			return convertError(c, err)
+		}
+		return nil
+	})
+}
+
+func convertError(c echov4.Context, err error) error {
+	if c.Response().Committed {
+		return &httpx.CommittedError{Err: err}
+	}
+	if he, ok := err.(*echov4.HTTPError); ok {
+		return httpx.WrapStatus(he, he.Code, fmt.Sprint(he.Message))
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+)
+
+type (
+	// Envelope is the body of every response in envelope mode, see
+	// EnvelopeResponses. Exactly one of Data and Error is set.
+	Envelope struct {
+		Data  interface{}            `json:"data"`
+		Error *EnvelopeError         `json:"error"`
+		Meta  map[string]interface{} `json:"meta,omitempty"`
+	}
+
+	// EnvelopeError is the error of an enveloped response.
+	EnvelopeError struct {
+		Message string       `json:"message"`
+		Code    string       `json:"code,omitempty"`
+		Fields  []FieldError `json:"fields,omitempty"`
+		// RetryAfter is AppError.RetryAfter in seconds.
+		RetryAfter int `json:"retry_after,omitempty"`
+		// Details carries the internal error details renderers show in
+		// development.
+		Details *ErrorInfo `json:"details,omitempty"`
+	}
+
+	// Pagination is the "pagination" meta of a page of results. Fields
+	// that do not apply to the pagination style in use are left empty.
+	Pagination struct {
+		Page       int    `json:"page,omitempty"`
+		PerPage    int    `json:"per_page,omitempty"`
+		Total      int64  `json:"total,omitempty"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+	}
+
+	envelopeMeta struct {
+		mu   sync.Mutex
+		meta map[string]interface{}
+	}
+)
+
+var envelopeKey = NewContextKey[*envelopeMeta]("envelope")
+
+// EnvelopeResponses switches the requests it serves to envelope mode:
+// Respond, and with it Typed, HandleTyped and the other response helpers,
+// writes {"data": ..., "error": null, "meta": {...}}, and JSONRenderer
+// writes errors as {"data": null, "error": {...}, "meta": {...}}.
+// ProblemRenderer keeps writing RFC 9457 bodies.
+//
+// Meta collects what the request added with SetMeta and SetPagination, and
+// the links applied with LinkSet.Apply.
+func EnvelopeResponses() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(envelopeKey.WithValue(r.Context(), &envelopeMeta{})))
+		})
+	}
+}
+
+// Enveloped reports whether the request of ctx is in envelope mode.
+func Enveloped(ctx context.Context) bool {
+	_, ok := envelopeKey.Value(ctx)
+	return ok
+}
+
+// SetMeta adds key to the meta of the enveloped response. It does nothing
+// outside envelope mode.
+func SetMeta(ctx context.Context, key string, value interface{}) {
+	m, ok := envelopeKey.Value(ctx)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.meta == nil {
+		m.meta = map[string]interface{}{}
+	}
+	m.meta[key] = value
+}
+
+// SetPagination records p as the "pagination" meta of the enveloped
+// response.
+func SetPagination(ctx context.Context, p Pagination) {
+	SetMeta(ctx, "pagination", p)
+}
+
+// envelope wraps data or err in an Envelope carrying the meta of ctx, and
+// reports whether ctx is in envelope mode.
+func envelope(ctx context.Context, data interface{}, err *EnvelopeError) (Envelope, bool) {
+	m, ok := envelopeKey.Value(ctx)
+	if !ok {
+		return Envelope{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Envelope{Data: data, Error: err, Meta: maps.Clone(m.meta)}, true
+}
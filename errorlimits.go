@@ -0,0 +1,135 @@
+package httpx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type (
+	// ErrorBodyLimits bounds what rendered error bodies carry, so an error
+	// whose message embeds a large payload, a deep stack trace or
+	// thousands of field errors cannot turn into a multi-megabyte
+	// response. InternalErrorsHandler and AppErrorsHandler apply them
+	// before rendering; reporters still receive the full errors. Truncated
+	// values end with an indicator of what was cut. Zero fields select the
+	// defaults noted on each; negative ones disable the limit.
+	ErrorBodyLimits struct {
+		// MaxMessage bounds messages and causes, in bytes; 4 KiB if zero.
+		MaxMessage int
+		// MaxStack bounds development stack traces, in bytes; 64 KiB if
+		// zero.
+		MaxStack int
+		// MaxFields bounds the field errors of a ValidationError; 100 if
+		// zero.
+		MaxFields int
+		// MaxErrors bounds the constituents of joined errors; 50 if zero.
+		MaxErrors int
+	}
+
+	// truncatedError shortens the message of err, and its field errors,
+	// while keeping err in the chain for reporters.
+	truncatedError struct {
+		msg        string
+		validation *ValidationError
+		err        error
+	}
+)
+
+// WithErrorBodyLimits replaces the default ErrorBodyLimits.
+func WithErrorBodyLimits(l ErrorBodyLimits) ConfigOption {
+	return func(c *config) {
+		c.errorLimits = l
+	}
+}
+
+// errorLimitsFor returns the limits config asks for, with defaults.
+func errorLimitsFor(config AppConfig) ErrorBodyLimits {
+	var l ErrorBodyLimits
+	if c, ok := config.(interface{ ErrorBodyLimits() ErrorBodyLimits }); ok {
+		l = c.ErrorBodyLimits()
+	}
+	if l.MaxMessage == 0 {
+		l.MaxMessage = 4 << 10
+	}
+	if l.MaxStack == 0 {
+		l.MaxStack = 64 << 10
+	}
+	if l.MaxFields == 0 {
+		l.MaxFields = 100
+	}
+	if l.MaxErrors == 0 {
+		l.MaxErrors = 50
+	}
+	return l
+}
+
+// info truncates the fields of errInfo in place.
+func (l ErrorBodyLimits) info(errInfo *ErrorInfo) {
+	errInfo.Message = truncateString(errInfo.Message, l.MaxMessage)
+	errInfo.Cause = truncateString(errInfo.Cause, l.MaxMessage)
+	errInfo.Stack = truncateString(errInfo.Stack, l.MaxStack)
+	if l.MaxErrors >= 0 && len(errInfo.Errors) > l.MaxErrors {
+		more := len(errInfo.Errors) - l.MaxErrors
+		errInfo.Errors = append(errInfo.Errors[:l.MaxErrors:l.MaxErrors], fmt.Sprintf("... %d more errors", more))
+	}
+	for i, msg := range errInfo.Errors {
+		errInfo.Errors[i] = truncateString(msg, l.MaxMessage)
+	}
+}
+
+// appError returns e with its message and field errors truncated.
+func (l ErrorBodyLimits) appError(e AppError) AppError {
+	if e.Err == nil {
+		return e
+	}
+	msg := e.Error()
+	short := truncateString(msg, l.MaxMessage)
+
+	var validation *ValidationError
+	if v, ok := AsValidationError(e); ok && l.MaxFields >= 0 && len(v.Fields) > l.MaxFields {
+		more := len(v.Fields) - l.MaxFields
+		fields := append(v.Fields[:l.MaxFields:l.MaxFields], FieldError{
+			Message: fmt.Sprintf("... %d more field errors", more),
+			Code:    "truncated",
+		})
+		validation = &ValidationError{Fields: fields}
+	}
+
+	if short == msg && validation == nil {
+		return e
+	}
+	e.Err = &truncatedError{msg: short, validation: validation, err: e.Err}
+	return e
+}
+
+func (e *truncatedError) Error() string {
+	return e.msg
+}
+
+func (e *truncatedError) Unwrap() error {
+	return e.err
+}
+
+// As hands out the truncated field errors instead of those deeper in the
+// chain.
+func (e *truncatedError) As(target interface{}) bool {
+	t, ok := target.(**ValidationError)
+	if ok && e.validation != nil {
+		*t = e.validation
+		return true
+	}
+	return false
+}
+
+// truncateString cuts s to at most max bytes plus an indicator, on a rune
+// boundary.
+func truncateString(s string, max int) string {
+	if max < 0 || len(s) <= max {
+		return s
+	}
+	n := max
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + fmt.Sprintf("... (%d bytes truncated)", len(s)-n)
+}
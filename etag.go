@@ -0,0 +1,218 @@
+package httpx
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAutoETagMaxSize bounds the response size AutoETag buffers.
+const DefaultAutoETagMaxSize = 1 << 20
+
+type etagWriter struct {
+	http.ResponseWriter
+	maxSize     int
+	status      int
+	buf         []byte
+	passthrough bool
+}
+
+// StrongETag returns a strong entity tag for data.
+func StrongETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag for data, for representations that are
+// semantically but not byte-for-byte equivalent.
+func WeakETag(data []byte) string {
+	return "W/" + StrongETag(data)
+}
+
+// CheckNotModified sets the ETag and Last-Modified validators (when non-zero)
+// and evaluates If-None-Match and If-Modified-Since. It returns
+// ErrNotModified when the client's copy is current, so handlers can simply
+// return its result:
+//
+//	if err := httpx.CheckNotModified(w, r, etag, updatedAt); err != nil {
+//		return err
+//	}
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) error {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if NotModified(r, etag, modTime) {
+		return ErrNotModified
+	}
+	return nil
+}
+
+// NotModified reports whether a GET or HEAD request's preconditions show the
+// client already holds the current representation.
+func NotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagListMatches(inm, etag, false)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// etagListMatches compares etag against a comma separated If-Match or
+// If-None-Match list, using strong or weak comparison.
+func etagListMatches(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong {
+			if !strings.HasPrefix(candidate, "W/") && candidate == etag {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// AutoETag buffers successful GET/HEAD responses of up to maxSize bytes,
+// tags them with a strong ETag and answers matching If-None-Match requests
+// with 304. Larger or streamed responses pass through untouched. A maxSize of
+// zero uses DefaultAutoETagMaxSize.
+func AutoETag(maxSize int) Middleware {
+	if maxSize <= 0 {
+		maxSize = DefaultAutoETagMaxSize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, maxSize: maxSize}
+			next.ServeHTTP(ew, r)
+			if ew.passthrough {
+				return
+			}
+
+			if ew.status == 0 {
+				ew.status = http.StatusOK
+			}
+
+			h := w.Header()
+			deferTrailers(h)
+			if h.Get("ETag") == "" {
+				h.Set("ETag", StrongETag(ew.buf))
+			}
+			if NotModified(r, h.Get("ETag"), time.Time{}) {
+				writeNotModified(w)
+				return
+			}
+
+			w.WriteHeader(ew.status)
+			w.Write(ew.buf)
+		})
+	}
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if status >= 100 && status <= 199 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	if status != http.StatusOK {
+		w.startPassthrough()
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) > w.maxSize {
+		w.startPassthrough()
+	}
+	return len(b), nil
+}
+
+// Flush gives up on tagging: a flushed response is a stream.
+func (w *etagWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *etagWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.startPassthrough()
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) startPassthrough() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+type (
+	FileServerOption func(*fileServerConfig)
+
+	fileServerConfig struct {
+		prefix string
+		spa    string
+	}
+)
+
+// WithStripPrefix removes prefix from request paths before looking files up.
+func WithStripPrefix(prefix string) FileServerOption {
+	return func(c *fileServerConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WithSPAFallback serves index (e.g. "index.html") for paths without a file
+// extension that do not exist, so client-side routes of single-page
+// applications survive reloads.
+func WithSPAFallback(index string) FileServerOption {
+	return func(c *fileServerConfig) {
+		c.spa = index
+	}
+}
+
+// FileServer serves files from fsys. Missing files and dot files are 404
+// and unreadable ones 403, both rendered through the adapter; directories
+// serve their index.html but are never listed. Names carrying a content
+// hash ("app.9f3a1c2e.js", "chunk-5f2b8e1a.css") get immutable caching,
+// everything else must be revalidated.
+func FileServer(fsys fs.FS, opts ...FileServerOption) HTTPHandlerExt {
+	var cfg fileServerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			return StatusError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+		urlPath, ok := strings.CutPrefix(r.URL.Path, cfg.prefix)
+		if !ok {
+			return NotFoundError("%s not found", r.URL.Path)
+		}
+		name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+		if name == "" {
+			name = "."
+		}
+
+		err := serveFS(w, r, fsys, name)
+		if errors.Is(err, fs.ErrNotExist) && cfg.spa != "" && path.Ext(name) == "" {
+			w.Header().Set("Cache-Control", "no-cache")
+			err = serveFS(w, r, fsys, cfg.spa)
+		}
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return NotFoundError("%s not found", r.URL.Path)
+		case errors.Is(err, fs.ErrPermission):
+			return ForbiddenError("%s is not accessible", r.URL.Path)
+		}
+		return err
+	}
+}
+
+func serveFS(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) error {
+	for _, elem := range strings.Split(name, "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." {
+			return fs.ErrNotExist
+		}
+	}
+
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		name = path.Join(name, "index.html")
+		if fi, err = fs.Stat(fsys, name); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return fs.ErrNotExist
+		}
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if w.Header().Get("Cache-Control") == "" {
+		if isHashedName(path.Base(name)) {
+			w.Header().Set("Cache-Control", immutableCacheControl)
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+	// embed.FS reports a zero ModTime; ServeContent then omits Last-Modified.
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), content)
+	return nil
+}
+
+// isHashedName reports whether one of the dot or dash separated parts of a
+// file name looks like a content hash: at least 8 hex digits.
+func isHashedName(name string) bool {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	for _, p := range parts[:max(len(parts)-1, 0)] {
+		if len(p) >= 8 && strings.Trim(p, "0123456789abcdefABCDEF") == "" {
+			return true
+		}
+	}
+	return false
+}
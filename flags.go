@@ -0,0 +1,107 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+type (
+	// FlagProvider evaluates feature flags, typically backed by a flag
+	// service SDK or a config file.
+	FlagProvider interface {
+		Evaluate(ctx context.Context, name string, subject FlagSubject) (bool, error)
+	}
+
+	FlagProviderFunc func(ctx context.Context, name string, subject FlagSubject) (bool, error)
+
+	// FlagSubject identifies who a flag is evaluated for. Fields are empty
+	// when unknown.
+	FlagSubject struct {
+		// Principal is the Principal.Subject of the request.
+		Principal string
+		// Tenant is the Tenant.ID of the request.
+		Tenant string
+	}
+
+	flagSet struct {
+		provider FlagProvider
+		report   func(error)
+
+		mu     sync.Mutex
+		values map[string]bool
+	}
+)
+
+var flagsKey = NewContextKey[*flagSet]("feature flags")
+
+func (f FlagProviderFunc) Evaluate(ctx context.Context, name string, subject FlagSubject) (bool, error) {
+	return f(ctx, name, subject)
+}
+
+// FeatureFlags makes provider available to Flag for the request. Each flag
+// is evaluated once per request, on first use, for the principal and
+// tenant known at that point, so a handler and the renderer of its error
+// see the same value. Evaluated flags are added to the ReportContext as
+// "flag.<name>" tags.
+//
+// Evaluation errors are reported through the adapter and the flag is off.
+func FeatureFlags(adapter *HandlerAdapter, provider FlagProvider) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fs := &flagSet{provider: provider, values: map[string]bool{}}
+			fs.report = func(err error) { adapter.report(r, err) }
+			next.ServeHTTP(w, r.WithContext(flagsKey.WithValue(r.Context(), fs)))
+		})
+	}
+}
+
+// Flag reports whether the named feature is on for the request of ctx. It
+// is off outside FeatureFlags.
+func Flag(ctx context.Context, name string) bool {
+	fs, ok := flagsKey.Value(ctx)
+	if !ok {
+		return false
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if v, ok := fs.values[name]; ok {
+		return v
+	}
+
+	var subject FlagSubject
+	if p, ok := PrincipalFromContext(ctx); ok {
+		subject.Principal = p.Subject
+	}
+	if t, ok := TenantFromContext(ctx); ok {
+		subject.Tenant = t.ID
+	}
+	v, err := fs.provider.Evaluate(ctx, name, subject)
+	if err != nil {
+		fs.report(fmt.Errorf("evaluating feature flag %s: %w", name, err))
+		v = false
+	}
+	fs.values[name] = v
+	if _, ok := reportContextKey.Value(ctx); ok {
+		SetReportTag(ctx, "flag."+name, strconv.FormatBool(v))
+	}
+	return v
+}
+
+// EvaluatedFlags returns the flags evaluated so far for the request of ctx.
+func EvaluatedFlags(ctx context.Context) map[string]bool {
+	fs, ok := flagsKey.Value(ctx)
+	if !ok {
+		return nil
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	out := make(map[string]bool, len(fs.values))
+	for k, v := range fs.values {
+		out[k] = v
+	}
+	return out
+}
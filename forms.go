@@ -0,0 +1,113 @@
+package httpx
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// CSRFFormField and MethodOverrideField are the hidden input names
+	// rendered by Form, matching the CSRFMiddleware default.
+	CSRFFormField       = "csrf_token"
+	MethodOverrideField = "_method"
+)
+
+// formOpenTemplate renders the form tag, so html/template sanitizes the
+// action like any URL attribute and unsafe schemes such as javascript:
+// never reach the page.
+var formOpenTemplate = template.Must(template.New("form").Parse(`<form method="{{ .Method }}" action="{{ .Action }}">`))
+
+// Form carries what a template needs to re-render a submitted form: the
+// submitted values, field errors from a ValidationError and the CSRF token.
+type Form struct {
+	Values      url.Values
+	FieldErrors map[string][]string
+	CSRFToken   string
+}
+
+// NewForm prepares a Form for rendering. err is the error returned by the
+// binder or validator, if any; its field errors are attached to the form.
+// r.ParseForm must have been called for values to be repopulated.
+func NewForm(r *http.Request, err error) *Form {
+	f := &Form{
+		Values:      r.PostForm,
+		FieldErrors: map[string][]string{},
+		CSRFToken:   CSRFToken(r.Context()),
+	}
+	if f.Values == nil {
+		f.Values = url.Values{}
+	}
+	if v, ok := AsValidationError(err); ok {
+		f.FieldErrors = v.ByField()
+	}
+	return f
+}
+
+func (f *Form) Value(name string) string {
+	return f.Values.Get(name)
+}
+
+func (f *Form) Errors(name string) []string {
+	return f.FieldErrors[name]
+}
+
+func (f *Form) HasError(name string) bool {
+	return len(f.FieldErrors[name]) > 0
+}
+
+// CSRFField renders the hidden CSRF token input.
+func (f *Form) CSRFField() template.HTML {
+	if f.CSRFToken == "" {
+		return ""
+	}
+	return hiddenInput(CSRFFormField, f.CSRFToken)
+}
+
+// Open renders the opening form tag for method and action. Methods other
+// than GET and POST are submitted as POST with a method override field. The
+// CSRF field is included for every non-GET form.
+func (f *Form) Open(method, action string) template.HTML {
+	method = strings.ToUpper(method)
+
+	var b strings.Builder
+	formMethod := "post"
+	if method == http.MethodGet {
+		formMethod = "get"
+	}
+	formOpenTemplate.Execute(&b, struct{ Method, Action string }{formMethod, action})
+
+	if method != http.MethodGet {
+		b.WriteString(string(f.CSRFField()))
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		b.WriteString(string(hiddenInput(MethodOverrideField, method)))
+	}
+	return template.HTML(b.String())
+}
+
+// FormFuncs returns template functions wrapping Form, for templates that
+// prefer funcs over method calls:
+//
+//	{{ formOpen .Form "PUT" "/items/1" }}
+//	  <input name="title" value="{{ fieldValue .Form "title" }}">
+//	  {{ range fieldErrors .Form "title" }}<p class="error">{{ . }}</p>{{ end }}
+//	</form>
+func FormFuncs() template.FuncMap {
+	return template.FuncMap{
+		"formOpen":    (*Form).Open,
+		"csrfField":   (*Form).CSRFField,
+		"fieldValue":  (*Form).Value,
+		"fieldErrors": (*Form).Errors,
+		"hasError":    (*Form).HasError,
+		"methodField": func(method string) template.HTML {
+			return hiddenInput(MethodOverrideField, strings.ToUpper(method))
+		},
+	}
+}
+
+func hiddenInput(name, value string) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(value) + `">`)
+}
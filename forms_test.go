@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormOpen(t *testing.T) {
+	tests := []struct {
+		method, action string
+		want           string
+	}{
+		{"GET", "/search?q=a&b=c", `<form method="get" action="/search?q=a&amp;b=c">`},
+		{"PUT", "/items/1", `<form method="post" action="/items/1"><input type="hidden" name="csrf_token" value="tok"><input type="hidden" name="_method" value="PUT">`},
+		{"POST", `/x"><script>`, `action="/x%22%3e%3cscript%3e"`},
+		{"POST", "javascript:alert(1)", `action="#ZgotmplZ"`},
+	}
+
+	f := &Form{CSRFToken: "tok"}
+	for _, tt := range tests {
+		if got := string(f.Open(tt.method, tt.action)); !strings.Contains(got, tt.want) {
+			t.Errorf("Open(%q, %q) = %s, want it to contain %s", tt.method, tt.action, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package gin adapts Gin middleware to httpx, easing the migration of
+// existing Gin applications onto the adapter one piece at a time.
+package gin
+
+import (
+	"context"
+	"net/http"
+
+	gingonic "github.com/gin-gonic/gin"
+
+	"github.com/radim/httpx"
+)
+
+type nextKey struct{}
+
+// Middleware converts Gin middleware into httpx middleware for use with
+// httpx.Chain. The handlers run on a private engine without routes; calling
+// c.Next continues with the rest of the httpx chain, aborting stops it.
+//
+// Gin writes aborted responses itself (AbortWithStatus, AbortWithError), so
+// they bypass the adapter's renderers.
+func Middleware(mws ...gingonic.HandlerFunc) httpx.Middleware {
+	engine := gingonic.New()
+	engine.RedirectTrailingSlash = false
+	engine.RedirectFixedPath = false
+	engine.Use(mws...)
+	engine.NoRoute(func(c *gingonic.Context) {
+		// Gin presets 404 for unrouted requests; restore the default.
+		c.Status(http.StatusOK)
+		next := c.Request.Context().Value(nextKey{}).(http.Handler)
+		next.ServeHTTP(c.Writer, c.Request)
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			engine.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), nextKey{}, next)))
+		})
+	}
+}
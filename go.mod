@@ -1,5 +1,3 @@
 module github.com/radim/httpx
 
-go 1.15
-
-require github.com/pkg/errors v0.9.1 // indirect
+go 1.24
@@ -0,0 +1,10 @@
+module github.com/radim/httpx/gorilla
+
+go 1.24
+
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/radim/httpx v0.0.0
+)
+
+replace github.com/radim/httpx => ../
@@ -0,0 +1,43 @@
+// Package gorilla registers httpx handlers on gorilla/mux routers.
+package gorilla
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/radim/httpx"
+)
+
+// Handle registers h for path on r through adapter and returns the route
+// for further matchers (Methods, Host...). Handlers see the path template
+// in Request.Pattern, prefixed with the method when the route restricts it
+// to one, and the route variables through Request.PathValue.
+func Handle(r *mux.Router, adapter *httpx.HandlerAdapter, path string, h httpx.HTTPHandlerExt, opts ...httpx.HandleOption) *mux.Route {
+	return r.Handle(path, withRoute(adapter.Handle(h, opts...)))
+}
+
+// Wire renders unmatched routes and methods through adapter instead of
+// gorilla/mux's plain-text defaults.
+func Wire(r *mux.Router, adapter *httpx.HandlerAdapter) {
+	r.NotFoundHandler = adapter.NotFoundHandler()
+	r.MethodNotAllowedHandler = adapter.MethodNotAllowedHandler()
+}
+
+func withRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				if methods, err := route.GetMethods(); err == nil && len(methods) == 1 {
+					tmpl = strings.ToUpper(methods[0]) + " " + tmpl
+				}
+				r.Pattern = tmpl
+			}
+		}
+		for name, value := range mux.Vars(r) {
+			r.SetPathValue(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
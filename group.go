@@ -0,0 +1,227 @@
+package httpx
+
+import (
+	"cmp"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Group registers routes on a Router under a shared path prefix, middleware
+// chain and HandlerAdapter. Groups nest: a subgroup inherits the prefix,
+// middleware and adapter of its parent at creation time.
+//
+// Group itself is a Router, so helpers taking a Router (healthcheck.Mount,
+// Security.Handle) register through it.
+type Group struct {
+	router     Router
+	prefix     string
+	adapter    *HandlerAdapter
+	middleware []Middleware
+	headers    []string
+	api        *openAPIRegistry
+	routes     *routeRegistry
+}
+
+// RouteInfo describes a route registered through a Group.
+type RouteInfo struct {
+	Pattern string `json:"pattern"`
+	// Middleware names the group middleware wrapping the route, outermost
+	// first, by the function that built it (e.g. "httpx.CSRFMiddleware").
+	Middleware []string `json:"middleware,omitempty"`
+	// Headers lists the request headers the route reads, as declared with
+	// Group.AllowHeaders or by its middleware through DescribeRoute (the
+	// credential headers of AuthMiddleware, the token header of
+	// CSRFMiddleware...). CORS allows them in preflights.
+	Headers []string `json:"headers,omitempty"`
+	// Credentials is set when the route's middleware authenticates the
+	// request or uses sessions, so cross-origin callers must send
+	// credentials.
+	Credentials bool `json:"credentials,omitempty"`
+}
+
+type (
+	// describedHandler is the handler of a DescribeRoute middleware.
+	describedHandler struct {
+		http.Handler
+		name string
+		info RouteInfo
+	}
+
+	// routeDescriber is implemented by handlers contributing to the
+	// RouteInfo of the routes they wrap. describeRoute merges the
+	// contribution into info and returns the middleware name to record, or
+	// "" to keep the name of the function that built it.
+	routeDescriber interface {
+		describeRoute(info *RouteInfo) string
+	}
+)
+
+type routeRegistry struct {
+	mu     sync.Mutex
+	routes []RouteInfo
+}
+
+var _ Router = (*Group)(nil)
+
+func NewGroup(router Router, adapter *HandlerAdapter, mws ...Middleware) *Group {
+	return &Group{router: router, adapter: adapter, middleware: mws, api: &openAPIRegistry{}, routes: &routeRegistry{}}
+}
+
+// Group returns a subgroup mounted at prefix, running mws after the
+// parent's middleware.
+func (g *Group) Group(prefix string, mws ...Middleware) *Group {
+	return &Group{
+		router:     g.router,
+		prefix:     g.prefix + strings.TrimSuffix(prefix, "/"),
+		adapter:    g.adapter,
+		middleware: append(append([]Middleware(nil), g.middleware...), mws...),
+		headers:    slices.Clone(g.headers),
+		api:        g.api,
+		routes:     g.routes,
+	}
+}
+
+// Use appends middleware for routes registered afterwards. Existing
+// subgroups are not affected.
+func (g *Group) Use(mws ...Middleware) {
+	g.middleware = append(g.middleware, mws...)
+}
+
+// AllowHeaders declares request headers read by routes registered
+// afterwards, such as an API key header or a custom CSRF header, so CORS
+// allows them in preflights. Existing subgroups are not affected.
+func (g *Group) AllowHeaders(headers ...string) {
+	g.headers = append(g.headers, headers...)
+}
+
+// WithAdapter returns a copy of g using adapter for its HTTPHandlerExt routes.
+func (g *Group) WithAdapter(adapter *HandlerAdapter) *Group {
+	c := *g
+	c.middleware = append([]Middleware(nil), g.middleware...)
+	c.headers = slices.Clone(g.headers)
+	c.adapter = adapter
+	return &c
+}
+
+func (g *Group) Adapter() *HandlerAdapter {
+	return g.adapter
+}
+
+// Handle registers a plain handler. pattern uses the ServeMux syntax, with
+// the group prefix inserted before its path.
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	pattern = g.pattern(pattern)
+	info := RouteInfo{Pattern: pattern, Headers: slices.Clone(g.headers)}
+	info.Middleware = make([]string, len(g.middleware))
+	h := handler
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		h = g.middleware[i](h)
+		info.Middleware[i] = middlewareName(g.middleware[i])
+		if d, ok := h.(routeDescriber); ok {
+			if name := d.describeRoute(&info); name != "" {
+				info.Middleware[i] = name
+			}
+		}
+	}
+	if len(info.Middleware) == 0 {
+		info.Middleware = nil
+	}
+	info.Headers = compactHeaders(info.Headers)
+
+	g.router.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Record the pattern before the group middleware runs.
+		h.ServeHTTP(w, r.WithContext(WithRoutePattern(r.Context(), cmp.Or(r.Pattern, pattern))))
+	}))
+
+	g.routes.mu.Lock()
+	g.routes.routes = append(g.routes.routes, info)
+	g.routes.mu.Unlock()
+}
+
+// HandleExt registers an error-returning handler through the group's
+// adapter.
+func (g *Group) HandleExt(pattern string, h HTTPHandlerExt, opts ...HandleOption) {
+	g.Handle(pattern, g.adapter.Handle(h, opts...))
+}
+
+// Routes lists the routes registered through g, its parent and its
+// subgroups, in registration order.
+func (g *Group) Routes() []RouteInfo {
+	g.routes.mu.Lock()
+	defer g.routes.mu.Unlock()
+	return append([]RouteInfo(nil), g.routes.routes...)
+}
+
+func (g *Group) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.router.ServeHTTP(w, r)
+}
+
+func (g *Group) pattern(pattern string) string {
+	if g.prefix == "" {
+		return pattern
+	}
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		return g.prefix + pattern
+	}
+	return method + " " + g.prefix + strings.TrimLeft(path, " \t")
+}
+
+// middlewareName returns the package-qualified name of the function that
+// built mw, with closure suffixes removed.
+func middlewareName(mw Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return "?"
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	for {
+		i := strings.LastIndexByte(name, '.')
+		suffix := strings.TrimPrefix(name[i+1:], "func")
+		if i < 0 || suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+			return name
+		}
+		name = name[:i]
+	}
+}
+
+// DescribeRoute returns mw declaring info for the routes it wraps: the
+// request headers they read and whether they need credentials. Groups merge
+// it into the RouteInfo of their routes, where CORS finds it, also when mw
+// is applied through When or Unless. Only Headers and Credentials of info
+// are used.
+//
+//	tenant := httpx.DescribeRoute(httpx.RouteInfo{Headers: []string{"X-Tenant"}}, tenantMiddleware)
+func DescribeRoute(info RouteInfo, mw Middleware) Middleware {
+	name := middlewareName(mw)
+	return func(next http.Handler) http.Handler {
+		return &describedHandler{Handler: mw(next), name: name, info: info}
+	}
+}
+
+func (h *describedHandler) describeRoute(info *RouteInfo) string {
+	if d, ok := h.Handler.(routeDescriber); ok {
+		d.describeRoute(info)
+	}
+	info.Headers = append(info.Headers, h.info.Headers...)
+	info.Credentials = info.Credentials || h.info.Credentials
+	return h.name
+}
+
+// compactHeaders removes empty and repeated header names, keeping the first
+// spelling of each.
+func compactHeaders(headers []string) []string {
+	var out []string
+	for _, h := range headers {
+		if h != "" && !slices.ContainsFunc(out, func(o string) bool { return strings.EqualFold(o, h) }) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,16 @@
+module github.com/radim/httpx/grpc
+
+go 1.24
+
+require (
+	github.com/radim/httpx v0.0.0
+	google.golang.org/grpc v1.71.0
+)
+
+require (
+	golang.org/x/sys v0.29.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+	google.golang.org/protobuf v1.36.4 // indirect
+)
+
+replace github.com/radim/httpx => ../
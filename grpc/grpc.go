@@ -0,0 +1,165 @@
+// Package grpc maps between gRPC status codes and httpx AppErrors, so
+// services fronting gRPC backends report upstream failures with the same
+// statuses and codes as their own errors.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/radim/httpx"
+)
+
+// Mapper translates an upstream status into an AppError. It returns false
+// to defer to later mappers and the default table.
+type Mapper func(st *status.Status) (httpx.AppError, bool)
+
+var (
+	mappersMu sync.RWMutex
+	mappers   []Mapper
+)
+
+// RegisterMapper adds m to the mappers consulted by FromGRPCError, in
+// registration order, before the default code table. Register mappers
+// during initialization, e.g. to turn a backend's error details into
+// validation errors.
+func RegisterMapper(m Mapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+	mappers = append(mappers, m)
+}
+
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499, // client closed request
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatus returns the HTTP status for a gRPC code.
+func HTTPStatus(c codes.Code) int {
+	if s, ok := httpStatuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the gRPC code for an HTTP status.
+func Code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	switch {
+	case httpStatus >= 200 && httpStatus < 300:
+		return codes.OK
+	case httpStatus >= 400 && httpStatus < 500:
+		return codes.FailedPrecondition
+	case httpStatus >= 500:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// FromGRPCError converts an error returned by a gRPC client into an
+// AppError, using the registered mappers and then the default code table.
+// The AppError code is the snake_case code name, e.g. "not_found", and the
+// original error stays in the chain. Errors that carry no gRPC status map
+// to 500.
+func FromGRPCError(err error) httpx.AppError {
+	st, ok := status.FromError(err)
+	if !ok {
+		return httpx.WrapStatus(err, http.StatusInternalServerError, err.Error())
+	}
+
+	mappersMu.RLock()
+	ms := mappers
+	mappersMu.RUnlock()
+	for _, m := range ms {
+		if appErr, ok := m(st); ok {
+			return appErr
+		}
+	}
+
+	return httpx.WrapStatus(err, HTTPStatus(st.Code()), st.Message()).WithCode(snakeCase(st.Code().String()))
+}
+
+// ToGRPCStatus converts a handler error into a gRPC status: gRPC errors
+// are returned as they are, AppErrors map by HTTP status and keep their
+// message, context errors map to Canceled and DeadlineExceeded, and
+// anything else becomes Internal without exposing its message.
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	var appErr httpx.AppError
+	switch {
+	case errors.As(err, &appErr):
+		return status.New(Code(appErr.StatusCode), appErr.Error())
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	}
+	return status.New(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
+
+func snakeCase(s string) string {
+	out := make([]rune, 0, len(s)+4)
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,44 @@
+package httpx
+
+import "net/http"
+
+// HeaderPolicy is the response header hygiene a HandlerAdapter applies to
+// every response, right before the status is written, so handlers do not
+// each repeat it. Handlers keep control: headers they set win over Set and
+// CacheControl.
+type HeaderPolicy struct {
+	// Set adds headers the handler did not set.
+	Set map[string]string
+	// CacheControl is the Cache-Control of responses that set none, such
+	// as "no-store" for APIs whose responses must not be cached by
+	// browsers or shared caches.
+	CacheControl string
+	// Remove deletes headers even when set, such as the Server and
+	// X-Powered-By headers proxied responses carry.
+	Remove []string
+}
+
+// APIHeaderPolicy returns the policy suiting JSON APIs: responses are not
+// cached unless a handler says otherwise, content sniffing is disabled and
+// server identification is removed.
+func APIHeaderPolicy() *HeaderPolicy {
+	return &HeaderPolicy{
+		Set:          map[string]string{"X-Content-Type-Options": "nosniff"},
+		CacheControl: "no-store",
+		Remove:       []string{"Server", "X-Powered-By", "X-AspNet-Version"},
+	}
+}
+
+func (p *HeaderPolicy) apply(h http.Header) {
+	for name, value := range p.Set {
+		if h.Get(name) == "" {
+			h.Set(name, value)
+		}
+	}
+	if p.CacheControl != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", p.CacheControl)
+	}
+	for _, name := range p.Remove {
+		h.Del(name)
+	}
+}
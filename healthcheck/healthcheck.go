@@ -0,0 +1,191 @@
+// Package healthcheck provides liveness and readiness endpoints backed by a
+// registry of named probes.
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radim/httpx"
+)
+
+// DefaultTimeout applies to checks registered without their own timeout.
+const DefaultTimeout = 5 * time.Second
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type (
+	// Check probes a dependency. It must honor ctx cancellation.
+	Check func(ctx context.Context) error
+
+	// Registry holds the liveness and readiness checks of an application.
+	Registry struct {
+		mu        sync.RWMutex
+		liveness  []namedCheck
+		readiness []namedCheck
+	}
+
+	// Report is the JSON body of the health endpoints. When a check failed it
+	// is also the error carried by the 503 AppError.
+	Report struct {
+		Status string            `json:"status"`
+		Checks map[string]Result `json:"checks,omitempty"`
+	}
+
+	Result struct {
+		Status   string        `json:"status"`
+		Error    string        `json:"error,omitempty"`
+		Duration time.Duration `json:"duration_ns"`
+	}
+
+	namedCheck struct {
+		name    string
+		check   Check
+		timeout time.Duration
+	}
+)
+
+func New() *Registry {
+	return &Registry{}
+}
+
+// AddLiveness registers a check that tells whether the process is healthy at
+// all. A zero timeout uses DefaultTimeout.
+func (r *Registry) AddLiveness(name string, check Check, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness = append(r.liveness, namedCheck{name: name, check: check, timeout: timeout})
+}
+
+// AddReadiness registers a check that tells whether the process can serve
+// traffic. A zero timeout uses DefaultTimeout.
+func (r *Registry) AddReadiness(name string, check Check, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness = append(r.readiness, namedCheck{name: name, check: check, timeout: timeout})
+}
+
+// Liveness runs the liveness checks concurrently.
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.liveness...)
+	r.mu.RUnlock()
+	return run(ctx, checks)
+}
+
+// Readiness runs the readiness checks concurrently.
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.readiness...)
+	r.mu.RUnlock()
+	return run(ctx, checks)
+}
+
+// LivenessHandler serves the liveness report.
+func (r *Registry) LivenessHandler() httpx.HTTPHandlerExt {
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler serves the readiness report.
+func (r *Registry) ReadinessHandler() httpx.HTTPHandlerExt {
+	return reportHandler(r.Readiness)
+}
+
+// Mount registers /healthz and /readyz on router through adapter.
+func (r *Registry) Mount(router httpx.Router, adapter *httpx.HandlerAdapter) {
+	router.Handle("GET /healthz", adapter.Handle(r.LivenessHandler()))
+	router.Handle("GET /readyz", adapter.Handle(r.ReadinessHandler()))
+}
+
+func (rep *Report) Error() string {
+	var failed []string
+	for name, res := range rep.Checks {
+		if res.Status != StatusUp {
+			failed = append(failed, name+": "+res.Error)
+		}
+	}
+	sort.Strings(failed)
+	return "unhealthy: " + strings.Join(failed, "; ")
+}
+
+func reportHandler(run func(context.Context) *Report) httpx.HTTPHandlerExt {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		report := run(req.Context())
+		if report.Status != StatusUp {
+			return httpx.AppError{Err: report, StatusCode: http.StatusServiceUnavailable, Code: "unhealthy"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		return json.NewEncoder(w).Encode(report)
+	}
+}
+
+func run(ctx context.Context, checks []namedCheck) *Report {
+	report := &Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			res := c.run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = res
+			if res.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (c namedCheck) run(ctx context.Context) Result {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errc <- &httpx.PanicError{Value: rec}
+			}
+		}()
+		errc <- c.check(ctx)
+	}()
+
+	// Do not wait for checks ignoring their context past the timeout.
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusUp, Duration: time.Since(start)}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
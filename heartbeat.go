@@ -0,0 +1,192 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeatInterval is how often Heartbeat writes while waiting.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+type (
+	// HeartbeatConfig configures Heartbeat. Zero values select the defaults
+	// noted on each field.
+	HeartbeatConfig struct {
+		// Interval between heartbeats; DefaultHeartbeatInterval if zero.
+		// Keep it below the idle timeout of the proxies in front.
+		Interval time.Duration
+		// Beat is written on every heartbeat; a single space if empty,
+		// which JSON and most text formats ignore. Use ":\n" for
+		// text/event-stream.
+		Beat []byte
+		// Status is sent with the first heartbeat; 200 if zero.
+		Status int
+	}
+
+	heartbeat struct {
+		w    http.ResponseWriter
+		cfg  HeartbeatConfig
+		fail context.CancelFunc
+
+		mu      sync.Mutex
+		stopped bool
+		beaten  bool
+	}
+
+	// heartbeatWriter gives fn its own header map, so fn can set headers
+	// while a heartbeat writes; they are applied when fn writes first,
+	// unless a heartbeat already sent the headers.
+	heartbeatWriter struct {
+		http.ResponseWriter
+		hb     *heartbeat
+		header http.Header
+		wrote  bool
+	}
+)
+
+// Heartbeat runs fn, a handler that takes long to compute its response,
+// while writing cfg.Beat every interval so proxies and load balancers do
+// not drop the idle connection. Heartbeats stop as soon as fn writes, and
+// when the client disconnects or a heartbeat cannot be written, which also
+// cancels fn's context.
+//
+// The first heartbeat commits the status and headers, so set them, Content-Type
+// included, before calling Heartbeat; fn's status is then ignored. An error
+// fn returns after a heartbeat is wrapped in *CommittedError, so the adapter
+// reports it instead of rendering it into the body.
+//
+//	w.Header().Set("Content-Type", "application/json")
+//	return httpx.Heartbeat(w, r, httpx.HeartbeatConfig{}, func(ctx context.Context, w http.ResponseWriter) error {
+//		report, err := reports.Build(ctx, id)
+//		if err != nil {
+//			return err
+//		}
+//		return httpx.Respond(w, r, http.StatusOK, report)
+//	})
+func Heartbeat(w http.ResponseWriter, r *http.Request, cfg HeartbeatConfig, fn func(ctx context.Context, w http.ResponseWriter) error) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHeartbeatInterval
+	}
+	if len(cfg.Beat) == 0 {
+		cfg.Beat = []byte(" ")
+	}
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusOK
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	hb := &heartbeat{w: w, cfg: cfg, fail: cancel}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		hb.run(ctx, done)
+	}()
+
+	err := fn(ctx, &heartbeatWriter{ResponseWriter: w, hb: hb, header: w.Header().Clone()})
+	hb.stop()
+	close(done)
+	wg.Wait()
+
+	if err != nil && hb.beaten {
+		return &CommittedError{Err: err}
+	}
+	return err
+}
+
+func (hb *heartbeat) run(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(hb.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !hb.beat() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// beat writes one heartbeat and reports whether to keep going.
+func (hb *heartbeat) beat() bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if hb.stopped {
+		return false
+	}
+	if !hb.beaten {
+		hb.beaten = true
+		hb.w.WriteHeader(hb.cfg.Status)
+	}
+	if _, err := hb.w.Write(hb.cfg.Beat); err != nil {
+		hb.fail()
+		return false
+	}
+	if err := Flush(hb.w); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		hb.fail()
+		return false
+	}
+	return true
+}
+
+// stop ends the heartbeats, waiting for one in progress, and reports
+// whether any were sent.
+func (hb *heartbeat) stop() bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.stopped = true
+	return hb.beaten
+}
+
+func (w *heartbeatWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader is dropped once a heartbeat sent the status.
+func (w *heartbeatWriter) WriteHeader(status int) {
+	if !w.start() {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *heartbeatWriter) Write(b []byte) (int, error) {
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *heartbeatWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *heartbeatWriter) FlushError() error {
+	w.start()
+	return Flush(w.ResponseWriter)
+}
+
+// start stops the heartbeats when fn writes first, applying fn's headers
+// unless a heartbeat sent them, and reports whether one did.
+func (w *heartbeatWriter) start() bool {
+	beaten := w.hb.stop()
+	if !w.wrote && !beaten {
+		h := w.ResponseWriter.Header()
+		clear(h)
+		maps.Copy(h, w.header)
+	}
+	w.wrote = true
+	return beaten
+}
+
+func (w *heartbeatWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
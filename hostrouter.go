@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HostRouter dispatches requests to a handler per host, so one server can
+// run separate stacks (each a Group with its own adapter and middleware)
+// for, say, the apex marketing site and the tenant subdomains.
+//
+// Hosts are matched case-insensitively and without the port. A pattern may
+// start with "*." to match exactly one further label: "*.example.com"
+// matches "acme.example.com" but neither "example.com" nor
+// "a.b.example.com". Exact hosts win over wildcards. The label matched by
+// "*" is available through SubdomainFromContext.
+//
+// The host is taken from ExternalURL, so install RealIPMiddleware first
+// when proxies forward the original host.
+type HostRouter struct {
+	// NotFound serves requests for unknown hosts; http.NotFound if nil.
+	NotFound http.Handler
+
+	exact     map[string]http.Handler
+	wildcards map[string]http.Handler
+}
+
+var subdomainKey = NewContextKey[string]("subdomain")
+
+func NewHostRouter() *HostRouter {
+	return &HostRouter{exact: map[string]http.Handler{}, wildcards: map[string]http.Handler{}}
+}
+
+// Handle registers handler for the host pattern. It panics if the pattern
+// is already registered, like http.ServeMux.
+func (hr *HostRouter) Handle(pattern string, handler http.Handler) {
+	pattern = normalizeHost(pattern)
+	m, key := hr.exact, pattern
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		m, key = hr.wildcards, suffix
+	}
+	if _, dup := m[key]; dup {
+		panic("httpx: host " + pattern + " registered twice")
+	}
+	m[key] = handler
+}
+
+func (hr *HostRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := normalizeHost(ExternalURL(r).Host)
+	if h, ok := hr.exact[host]; ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	if label, parent, ok := strings.Cut(host, "."); ok && label != "" {
+		if h, ok := hr.wildcards[parent]; ok {
+			h.ServeHTTP(w, r.WithContext(subdomainKey.WithValue(r.Context(), label)))
+			return
+		}
+	}
+	if hr.NotFound != nil {
+		hr.NotFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// SubdomainFromContext returns the label matched by a HostRouter wildcard.
+func SubdomainFromContext(ctx context.Context) (string, bool) {
+	return subdomainKey.Value(ctx)
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
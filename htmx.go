@@ -0,0 +1,209 @@
+package httpx
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type (
+	// OOBFragment is an out-of-band fragment swapped into the element with
+	// the given ID, next to the main partial.
+	OOBFragment struct {
+		ID      string
+		Swap    string // hx-swap-oob value, defaults to "true"
+		Content template.HTML
+	}
+
+	// HTMXError is the template data for error partials.
+	HTMXError struct {
+		Status  int
+		Message string
+		Code    string
+		// Reference identifies the reported internal error, see
+		// ErrorReference.
+		Reference string
+	}
+
+	// HTMXErrorRenderer renders errors of partial (htmx / Turbo Frame)
+	// requests with a partial template instead of a full error page.
+	// Wrap the adapter funcs with it:
+	//
+	//	r := &httpx.HTMXErrorRenderer{Config: cfg, Templates: tmpl, Template: "error"}
+	//	adapter.ClientErrs = r.Wrap(adapter.ClientErrs)
+	//	adapter.InternalErrs = r.Wrap(adapter.InternalErrs)
+	HTMXErrorRenderer struct {
+		// Config, when set, receives internal errors of partial requests,
+		// which bypass the wrapped InternalErrs func, and selects the
+		// RedactionPolicy of AppError messages.
+		Config    AppConfig
+		Templates *template.Template
+		Template  string
+		// Retarget and Reswap, when set, are sent as HX-Retarget and
+		// HX-Reswap so the error lands somewhere visible. htmx does not swap
+		// error responses by default.
+		Retarget string
+		Reswap   string
+	}
+)
+
+// IsHTMX reports whether r was issued by htmx.
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// IsHTMXBoosted reports whether r comes from an hx-boost'ed link or form,
+// which expects a full page.
+func IsHTMXBoosted(r *http.Request) bool {
+	return r.Header.Get("HX-Boosted") == "true"
+}
+
+// HTMXTarget returns the ID of the element targeted by an htmx request.
+func HTMXTarget(r *http.Request) string {
+	return r.Header.Get("HX-Target")
+}
+
+// TurboFrame returns the ID of the Turbo Frame that issued r, if any.
+func TurboFrame(r *http.Request) (string, bool) {
+	id := r.Header.Get("Turbo-Frame")
+	return id, id != ""
+}
+
+// AcceptsTurboStream reports whether the client accepts Turbo Stream
+// responses.
+func AcceptsTurboStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/vnd.turbo-stream.html")
+}
+
+// IsPartialRequest reports whether r expects an HTML fragment rather than a
+// full page.
+func IsPartialRequest(r *http.Request) bool {
+	if IsHTMX(r) {
+		return !IsHTMXBoosted(r)
+	}
+	_, ok := TurboFrame(r)
+	return ok
+}
+
+// HXRedirect makes htmx perform a full client-side redirect.
+func HXRedirect(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Redirect", url)
+}
+
+// HXLocation makes htmx navigate to url without a full page reload.
+func HXLocation(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Location", url)
+}
+
+func HXRefresh(w http.ResponseWriter) {
+	w.Header().Set("HX-Refresh", "true")
+}
+
+func HXPushURL(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Push-Url", url)
+}
+
+func HXRetarget(w http.ResponseWriter, selector string) {
+	w.Header().Set("HX-Retarget", selector)
+}
+
+func HXReswap(w http.ResponseWriter, swap string) {
+	w.Header().Set("HX-Reswap", swap)
+}
+
+// HXTrigger triggers the named client-side events after the swap.
+func HXTrigger(w http.ResponseWriter, events ...string) {
+	w.Header().Set("HX-Trigger", strings.Join(events, ", "))
+}
+
+// HXTriggerDetail triggers client-side events carrying detail payloads.
+func HXTriggerDetail(w http.ResponseWriter, events map[string]interface{}) error {
+	b, err := currentJSON().Marshal(events)
+	if err != nil {
+		return fmt.Errorf("encoding HX-Trigger: %w", err)
+	}
+	w.Header().Set("HX-Trigger", string(b))
+	return nil
+}
+
+// RenderPartial executes the named template with data, followed by any
+// out-of-band fragments.
+func RenderPartial(w http.ResponseWriter, status int, tmpl *template.Template, name string, data interface{}, oob ...OOBFragment) error {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		return fmt.Errorf("rendering partial %s: %w", name, err)
+	}
+	for _, f := range oob {
+		if _, err := w.Write([]byte(f.HTML())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTML renders the fragment as an element with hx-swap-oob.
+func (f OOBFragment) HTML() template.HTML {
+	swap := f.Swap
+	if swap == "" {
+		swap = "true"
+	}
+	return template.HTML(`<div id="` + template.HTMLEscapeString(f.ID) + `" hx-swap-oob="` +
+		template.HTMLEscapeString(swap) + `">` + string(f.Content) + `</div>`)
+}
+
+// TurboStream renders a <turbo-stream> element for the given action and
+// target.
+func TurboStream(action, target string, content template.HTML) template.HTML {
+	return template.HTML(`<turbo-stream action="` + template.HTMLEscapeString(action) + `" target="` +
+		template.HTMLEscapeString(target) + `"><template>` + string(content) + `</template></turbo-stream>`)
+}
+
+// Wrap returns an AdapterFunc rendering the error partial for partial
+// requests and delegating to next for everything else.
+func (h *HTMXErrorRenderer) Wrap(next AdapterFunc) AdapterFunc {
+	return func(w http.ResponseWriter, req *http.Request, err error) {
+		if !IsPartialRequest(req) {
+			next(w, req, err)
+			return
+		}
+
+		var data HTMXError
+		if e, ok := err.(AppError); ok {
+			if h.Config != nil {
+				if policy := redactionFor(h.Config); policy != nil {
+					e = policy.redact(e)
+				}
+			}
+			data = HTMXError{Status: e.StatusCode, Message: e.Error(), Code: e.Code}
+		} else {
+			// Like InternalErrorsHandler: a generic message and a reference
+			// shared by the response and the report.
+			ref := newErrorReference()
+			w.Header().Set(ErrorReferenceHeader, ref)
+			data = HTMXError{
+				Status:    http.StatusInternalServerError,
+				Message:   http.StatusText(http.StatusInternalServerError),
+				Reference: ref,
+			}
+			if h.Config != nil && !ReportingDisabled(req.Context()) {
+				h.Config.ReportError(withErrorReference(req.Context(), ref), err)
+			}
+		}
+
+		if h.Retarget != "" {
+			HXRetarget(w, h.Retarget)
+		}
+		if h.Reswap != "" {
+			HXReswap(w, h.Reswap)
+		}
+
+		if rerr := RenderPartial(w, data.Status, h.Templates, h.Template, data); rerr != nil && h.Config != nil {
+			h.Config.ReportError(req.Context(), rerr)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTMXErrorRendererRedacts(t *testing.T) {
+	var reported string
+	config := NewConfig(WithEnvironment(EnvProduction), WithReporter(ReporterFunc(func(ctx context.Context, err error) {
+		reported = ErrorReference(ctx)
+	})))
+	tmpl := template.Must(template.New("error").Parse(`{{ .Status }} {{ .Message }} ref={{ .Reference }}`))
+	r := &HTMXErrorRenderer{Config: config, Templates: tmpl, Template: "error"}
+	render := r.Wrap(func(w http.ResponseWriter, req *http.Request, err error) {
+		t.Fatal("partial request delegated")
+	})
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+		ref    bool
+	}{
+		{name: "internal", err: errors.New("pq: relation users_secret"), status: 500, body: "500 Internal Server Error ref=", ref: true},
+		{name: "internal AppError", err: StatusError(http.StatusBadGateway, "dial tcp 10.0.0.7:5432"), status: 502, body: "502 Bad Gateway ref="},
+		{name: "client AppError", err: BadRequestError("title is required"), status: 400, body: "400 title is required ref="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reported = ""
+			req := httptest.NewRequest(http.MethodPost, "/items", nil)
+			req.Header.Set("HX-Request", "true")
+			w := httptest.NewRecorder()
+			render(w, req, tt.err)
+
+			body := w.Body.String()
+			if w.Code != tt.status || !strings.HasPrefix(body, tt.body) {
+				t.Errorf("got %d %q, want %d %q", w.Code, body, tt.status, tt.body)
+			}
+			ref := w.Header().Get(ErrorReferenceHeader)
+			if tt.ref && (ref == "" || !strings.HasSuffix(body, "ref="+ref) || reported != ref) {
+				t.Errorf("reference header %q, body %q, reported %q, want the same reference", ref, body, reported)
+			}
+		})
+	}
+}
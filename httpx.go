@@ -2,21 +2,79 @@ package httpx
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-
-	"github.com/pkg/errors"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"time"
 )
 
 type (
 	HTTPHandlerExt func(http.ResponseWriter, *http.Request) error
 	AdapterFunc    func(http.ResponseWriter, *http.Request, error)
+	Middleware     func(http.Handler) http.Handler
 
 	HandlerAdapter struct {
 		InternalErrs AdapterFunc
 		ClientErrs   AdapterFunc
 
 		UnauthorizedErr AdapterFunc
+
+		// MaxBodyBytes limits request bodies of handlers wrapped by Handle.
+		// Zero means no limit.
+		MaxBodyBytes int64
+
+		// RecoverPanics makes Handle recover panics in the handler and treat
+		// them like returned errors. RecoverMiddleware is still needed for
+		// panics in middleware outside Handle.
+		RecoverPanics bool
+
+		// PanicPassthrough lists panic values that recovery re-raises
+		// instead of handling, for code that panics deliberately to unwind.
+		// Errors match with errors.Is. http.ErrAbortHandler always passes.
+		PanicPassthrough []interface{}
+
+		// DetectServerErrors makes Handle treat a 5xx status written by a
+		// handler that returned nil as a failure: OnError hooks run and a
+		// *StatusWrittenError is reported.
+		DetectServerErrors bool
+
+		// PanicOnMisuse makes Handle and Wrap panic when a handler misuses
+		// the response writer, see Misuse, so the bug surfaces with a stack
+		// trace. Writes after the handler returned are reported instead.
+		// NewDefaultHandlerAdapter sets it in development.
+		PanicOnMisuse bool
+
+		// Redaction hides internal error messages from clients. Nil shows
+		// AppError messages as they are.
+		Redaction *RedactionPolicy
+
+		// Reporter receives errors that can no longer be rendered because the
+		// response was already committed. Nil logs them to stderr.
+		Reporter ErrorReporter
+
+		// HeaderPolicy adjusts the headers of every response served by
+		// Handle, Wrap and RecoverMiddleware, errors included. Nil leaves
+		// them alone.
+		HeaderPolicy *HeaderPolicy
+
+		noReport   bool
+		onError    []func(*http.Request, error)
+		onResponse []func(*http.Request, int, time.Duration)
+		onMisuse   []func(*http.Request, *MisuseError)
+	}
+
+	// HandleOption overrides the adapter's error handling for one route.
+	HandleOption func(*HandlerAdapter)
+
+	noReportKey struct{}
+
+	// ErrorReporter is implemented by AppConfig.
+	ErrorReporter interface {
+		ReportError(ctx context.Context, err error)
 	}
 
 	Error interface {
@@ -27,6 +85,12 @@ type (
 	AppError struct {
 		Err        error
 		StatusCode int
+		// Code is an optional machine-readable error code for clients.
+		Code string
+		// RetryAfter tells clients when to try again. HandleError sends it
+		// as the Retry-After header and renderers as a retry_after body
+		// field, both in whole seconds. Zero omits both.
+		RetryAfter time.Duration
 	}
 
 	Renderer interface {
@@ -34,6 +98,16 @@ type (
 		RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError)
 	}
 
+	// RequestRenderer is implemented by renderers that need the request,
+	// e.g. for content negotiation, locale lookup or the path on error
+	// pages. The adapter prefers these methods over the Renderer ones; the
+	// request carries the context the Renderer methods would receive.
+	RequestRenderer interface {
+		Renderer
+		Render500Request(w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo)
+		RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr AppError)
+	}
+
 	AppConfig interface {
 		IsDevelopment() bool
 		ReportError(ctx context.Context, err error)
@@ -44,9 +118,17 @@ type (
 		Message string `json:"message,omitempty"`
 		Cause   string `json:"cause,omitempty"`
 		Stack   string `json:"stack,omitempty"`
+		// Errors lists the constituents of a joined error.
+		Errors []string `json:"errors,omitempty"`
+		// Reference identifies the reported error, see ErrorReference.
+		Reference string `json:"reference,omitempty"`
 	}
 )
 
+// ErrNotModified is a sentinel result rather than a failure: handlers return
+// it to short-circuit with 304 Not Modified.
+var ErrNotModified = errors.New("not modified")
+
 func BadRequestError(content string, params ...interface{}) AppError {
 	return StatusError(http.StatusBadRequest, content, params...)
 }
@@ -55,6 +137,46 @@ func UnauthorizedError(content string, params ...interface{}) AppError {
 	return StatusError(http.StatusUnauthorized, content, params...)
 }
 
+func ForbiddenError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusForbidden, content, params...)
+}
+
+func NotFoundError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusNotFound, content, params...)
+}
+
+func ConflictError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusConflict, content, params...)
+}
+
+func UnprocessableEntityError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusUnprocessableEntity, content, params...)
+}
+
+func TooManyRequestsError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusTooManyRequests, content, params...)
+}
+
+func NotImplementedError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusNotImplemented, content, params...)
+}
+
+func ServiceUnavailableError(content string, params ...interface{}) AppError {
+	return StatusError(http.StatusServiceUnavailable, content, params...)
+}
+
+// TooManyRequestsWithRetry returns a 429 AppError asking the client to
+// retry after the given delay.
+func TooManyRequestsWithRetry(after time.Duration, content string, params ...interface{}) AppError {
+	return TooManyRequestsError(content, params...).WithRetryAfter(after)
+}
+
+// ServiceUnavailableWithRetry returns a 503 AppError asking the client to
+// retry after the given delay.
+func ServiceUnavailableWithRetry(after time.Duration, content string, params ...interface{}) AppError {
+	return ServiceUnavailableError(content, params...).WithRetryAfter(after)
+}
+
 func StatusError(statusCode int, content string, params ...interface{}) AppError {
 	return AppError{
 		Err:        fmt.Errorf(content, params...),
@@ -62,6 +184,19 @@ func StatusError(statusCode int, content string, params ...interface{}) AppError
 	}
 }
 
+// WrapStatus gives err a client-facing status and message while keeping it
+// in the chain, so errors.Is and errors.As still match the original error.
+// An empty msg uses the status text.
+func WrapStatus(err error, status int, msg string) AppError {
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return AppError{
+		Err:        &wrappedError{msg: msg, err: err},
+		StatusCode: status,
+	}
+}
+
 func (e AppError) Error() string {
 	return e.Err.Error()
 }
@@ -70,6 +205,42 @@ func (e AppError) GetStatusCode() int {
 	return e.StatusCode
 }
 
+func (e AppError) Unwrap() error {
+	return e.Err
+}
+
+func (e AppError) WithCode(code string) AppError {
+	e.Code = code
+	return e
+}
+
+func (e AppError) WithRetryAfter(d time.Duration) AppError {
+	e.RetryAfter = d
+	return e
+}
+
+// retryAfterSeconds rounds d up to whole seconds, as sent in Retry-After.
+func retryAfterSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// wrappedError shows msg to clients and hides err behind Unwrap.
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
 func defaultAppError(w http.ResponseWriter, req *http.Request, err error) {
 	if e, ok := err.(AppError); ok {
 		http.Error(w, err.Error(), e.GetStatusCode())
@@ -83,17 +254,49 @@ func defaultInternalError(w http.ResponseWriter, req *http.Request, err error) {
 	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 }
 
+// InternalErrorsHandler reports err and renders a 500 response through
+// config. A nil config, or one without a Renderer, degrades to logging on
+// stderr and a plain-text response, as does a Renderer that panics or
+// writes nothing.
 func InternalErrorsHandler(config AppConfig) func(http.ResponseWriter, *http.Request, error) {
 	return func(w http.ResponseWriter, req *http.Request, err error) {
 		var errInfo *ErrorInfo
 
-		w.WriteHeader(http.StatusInternalServerError)
+		// Renderers may set headers; the status is forced to 500 on their
+		// first write, or after rendering if they wrote nothing.
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusInternalServerError}
+		defer sw.commit()
+
+		report := !ReportingDisabled(req.Context())
 
-		config.ReportError(req.Context(), err)
+		// Every internal error gets a reference shared by the response and
+		// the report.
+		ref := newErrorReference()
+		ctx := withErrorReference(req.Context(), ref)
+		w.Header().Set(ErrorReferenceHeader, ref)
+
+		if config == nil {
+			if report {
+				log.Printf("httpx: %s %s: [%s] %v", req.Method, req.URL.Path, ref, err)
+			}
+			defaultInternalError(sw, req, err)
+			return
+		}
 
-		if config.IsDevelopment() {
+		if report {
+			config.ReportError(ctx, err)
+		}
+
+		policy := redactionFor(config)
+		if policy != nil {
+			errInfo = &ErrorInfo{
+				Message:   policy.message(http.StatusInternalServerError),
+				Reference: ref,
+			}
+		} else {
 			errInfo = &ErrorInfo{
-				Message: fmt.Sprintf("%s", err),
+				Message:   fmt.Sprintf("%s", err),
+				Reference: ref,
 			}
 
 			// Unwrap the error to get the root cause, if any
@@ -102,76 +305,510 @@ func InternalErrorsHandler(config AppConfig) func(http.ResponseWriter, *http.Req
 			}
 
 			// Check if the error has a stack trace
-			type stackTracer interface {
-				StackTrace() errors.StackTrace
-			}
+			errInfo.Stack = errorStack(err)
 
-			if stackErr, ok := err.(stackTracer); ok {
-				st := stackErr.StackTrace()
-				errInfo.Stack = fmt.Sprintf("%+v", st)
+			for _, e := range joinedErrors(err) {
+				errInfo.Errors = append(errInfo.Errors, e.Error())
 			}
 		}
 
+		errorLimitsFor(config).info(errInfo)
+
+		renderer := config.GetRenderer()
+		if renderer == nil {
+			defaultInternalError(sw, req, err)
+			return
+		}
+
 		// Use the Renderer to render the 500 error response
-		config.GetRenderer().Render500(context.Background(), w, errInfo)
+		renderWithFallback(sw, func(err error) {
+			if report {
+				config.ReportError(ctx, err)
+			}
+		}, func(w http.ResponseWriter) {
+			Render500(renderer, w, req.WithContext(ctx), errInfo)
+		}, func(w http.ResponseWriter) {
+			defaultInternalError(w, req, err)
+		})
+	}
+}
+
+// AppErrorsHandler renders AppErrors through the config's Renderer, falling
+// back to plain text without one or when it fails.
+func AppErrorsHandler(config AppConfig) AdapterFunc {
+	return func(w http.ResponseWriter, req *http.Request, err error) {
+		appErr, ok := err.(AppError)
+		if !ok || config == nil {
+			defaultAppError(w, req, err)
+			return
+		}
+		appErr = errorLimitsFor(config).appError(appErr)
+		renderer := config.GetRenderer()
+		if renderer == nil {
+			defaultAppError(w, req, appErr)
+			return
+		}
+		renderWithFallback(w, func(err error) {
+			if !ReportingDisabled(req.Context()) {
+				config.ReportError(req.Context(), err)
+			}
+		}, func(w http.ResponseWriter) {
+			RenderAppError(renderer, w, req, appErr)
+		}, func(w http.ResponseWriter) {
+			defaultAppError(w, req, appErr)
+		})
 	}
 }
 
+// renderWithFallback runs render as a last-resort safety net: when the
+// renderer panics or writes nothing, the failure is reported and fallback
+// writes a plain-text response, so clients always get a coherent error. A
+// renderer that panics after committing the response can only be reported.
+func renderWithFallback(w http.ResponseWriter, report func(error), render, fallback func(http.ResponseWriter)) {
+	cw := &commitWriter{ResponseWriter: w}
+	defer func() {
+		rec := recover()
+		switch {
+		case rec == http.ErrAbortHandler:
+			panic(rec)
+		case rec != nil:
+			report(fmt.Errorf("renderer failed: %w", &PanicError{Value: rec, Stack: debug.Stack()}))
+		case !cw.committed:
+			report(errors.New("renderer wrote no response"))
+		default:
+			return
+		}
+		if !cw.committed {
+			fallback(w)
+		}
+	}()
+	render(cw)
+}
+
+// Render500 renders errInfo through renderer, passing r to a
+// RequestRenderer. Wrapping renderers use it to forward.
+func Render500(renderer Renderer, w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo) {
+	if rr, ok := renderer.(RequestRenderer); ok {
+		rr.Render500Request(w, r, errInfo)
+		return
+	}
+	renderer.Render500(r.Context(), w, errInfo)
+}
+
+// RenderAppError renders appErr through renderer, passing r to a
+// RequestRenderer. Wrapping renderers use it to forward.
+func RenderAppError(renderer Renderer, w http.ResponseWriter, r *http.Request, appErr AppError) {
+	if rr, ok := renderer.(RequestRenderer); ok {
+		rr.RenderAppErrorRequest(w, r, appErr)
+		return
+	}
+	renderer.RenderAppError(r.Context(), w, appErr)
+}
+
 func NewDefaultHandlerAdapter(config AppConfig) *HandlerAdapter {
-	return &HandlerAdapter{
+	a := &HandlerAdapter{
 		InternalErrs:    InternalErrorsHandler(config),
-		ClientErrs:      defaultAppError,
+		ClientErrs:      AppErrorsHandler(config),
 		UnauthorizedErr: nil,
 	}
+	if config != nil {
+		a.Reporter = config
+		a.Redaction = redactionFor(config)
+		a.PanicOnMisuse = config.IsDevelopment()
+	}
+	return a
 }
 
+// RecoverMiddleware recovers panics raised by next, including middleware,
+// and handles them like HandlerAdapter.RecoverPanics does. Panics with
+// http.ErrAbortHandler or a value in adapter.PanicPassthrough are
+// re-raised without being reported.
 func RecoverMiddleware(adapter *HandlerAdapter, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw, ok := w.(*commitWriter)
+		if !ok {
+			cw = adapter.newCommitWriter(w)
+			defer releaseCommitWriter(cw)
+		}
 		defer func() {
 			if rec := recover(); rec != nil {
-				var err error
-				switch x := rec.(type) {
-				case string:
-					err = fmt.Errorf(x)
-				case error:
-					err = x
-				default:
-					err = fmt.Errorf("unknown panic")
-				}
-				adapter.InternalErrs(w, r, err)
+				adapter.handlePanic(cw, r, cw, rec)
 			}
 		}()
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(cw, r)
 	})
 }
 
-func (a *HandlerAdapter) Handle(h HTTPHandlerExt) http.HandlerFunc {
+// Handle adapts h to http.HandlerFunc. opts override the adapter's error
+// handling for this route only.
+func (a *HandlerAdapter) Handle(h HTTPHandlerExt, opts ...HandleOption) http.HandlerFunc {
+	if len(opts) > 0 {
+		route := *a
+		for _, opt := range opts {
+			opt(&route)
+		}
+		a = &route
+	}
+
+	// The success path allocates nothing beyond pooled writers: request
+	// details for reporters and the error class for OnResponse hooks are
+	// only added to the context once an error is handled.
 	return func(w http.ResponseWriter, req *http.Request) {
-		if err := h(w, req); err != nil {
-			switch e := err.(type) {
-			case AppError:
-				if e.StatusCode == http.StatusUnauthorized && a.UnauthorizedErr != nil {
-					a.UnauthorizedErr(w, req, e)
-					return
-				}
+		cw, _ := w.(*commitWriter)
+		if len(a.onResponse) > 0 || cw == nil && (a.RecoverPanics || a.DetectServerErrors || a.HeaderPolicy != nil) {
+			cw = a.newCommitWriter(w)
+			defer releaseCommitWriter(cw)
+			w = cw
+		} else if cw != nil && a.HeaderPolicy != nil {
+			// The route's policy overrides the one of outer middleware.
+			cw.policy = a.HeaderPolicy
+		}
+		if len(a.onResponse) > 0 {
+			start := time.Now()
+			defer func() { a.observe(req, cw, start) }()
+		}
 
-				if a.ClientErrs != nil {
-					a.ClientErrs(w, req, err)
-					return
+		mw := a.misuseWriter(w, req)
+		if mw != nil {
+			defer mw.finish()
+			w = mw
+		}
+		if a.RecoverPanics {
+			defer func() {
+				if rec := recover(); rec != nil {
+					req = a.classified(req, a.handlePanic(w, req, cw, rec))
 				}
+			}()
+		}
+
+		if a.MaxBodyBytes > 0 {
+			if !limitBody(a, w, req, a.MaxBodyBytes) {
+				return
+			}
+		}
+
+		if err := h(w, req); err != nil {
+			if mw != nil {
+				err = mw.returned(err)
+			}
+			req = a.classified(req, err)
+			a.HandleError(w, req, err)
+		} else if a.DetectServerErrors && cw.status >= 500 {
+			req = a.classified(req, &StatusWrittenError{Status: cw.status})
+			a.serverErrorWritten(req, cw.status)
+		}
+	}
+}
+
+func WithClientErrs(fn AdapterFunc) HandleOption {
+	return func(a *HandlerAdapter) {
+		a.ClientErrs = fn
+	}
+}
+
+func WithInternalErrs(fn AdapterFunc) HandleOption {
+	return func(a *HandlerAdapter) {
+		a.InternalErrs = fn
+	}
+}
+
+func WithUnauthorizedErr(fn AdapterFunc) HandleOption {
+	return func(a *HandlerAdapter) {
+		a.UnauthorizedErr = fn
+	}
+}
+
+func WithHandleMaxBodyBytes(n int64) HandleOption {
+	return func(a *HandlerAdapter) {
+		a.MaxBodyBytes = n
+	}
+}
+
+// WithDetectServerErrors enables HandlerAdapter.DetectServerErrors for
+// this route.
+func WithDetectServerErrors() HandleOption {
+	return func(a *HandlerAdapter) {
+		a.DetectServerErrors = true
+	}
+}
 
-				// Use default AppError handler if no ClientErrs adapter is provided
-				defaultAppError(w, req, err)
+// WithRecover recovers panics in this route's handler, see
+// HandlerAdapter.RecoverPanics.
+func WithRecover() HandleOption {
+	return func(a *HandlerAdapter) {
+		a.RecoverPanics = true
+	}
+}
 
-			default:
-				if a.InternalErrs != nil {
-					a.InternalErrs(w, req, err)
-					return
+// WithHeaderPolicy applies p to this route's responses instead of the
+// adapter's HeaderPolicy, also overriding RecoverMiddleware's. An empty
+// policy leaves the headers alone.
+func WithHeaderPolicy(p *HeaderPolicy) HandleOption {
+	return func(a *HandlerAdapter) {
+		a.HeaderPolicy = p
+	}
+}
+
+// WithNoReport renders errors as usual but skips error reporting, for routes
+// whose failures are expected or noisy (probes, best-effort endpoints).
+func WithNoReport() HandleOption {
+	return func(a *HandlerAdapter) {
+		a.noReport = true
+	}
+}
+
+// ReportingDisabled reports whether the route serving ctx opted out of error
+// reporting with WithNoReport. Custom AdapterFuncs should honor it.
+func ReportingDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noReportKey{}).(bool)
+	return disabled
+}
+
+// Wrap runs a plain http.Handler inside the adapter's pipeline, for legacy
+// handlers not yet converted to HTTPHandlerExt. Bodies are limited, panics
+// are recovered and handled like returned errors, and 5xx responses the
+// handler writes itself are treated as with DetectServerErrors.
+func (a *HandlerAdapter) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cw := a.newCommitWriter(w)
+		defer releaseCommitWriter(cw)
+		if len(a.onResponse) > 0 {
+			start := time.Now()
+			defer func() { a.observe(req, cw, start) }()
+		}
+		// Errors are rendered through cw, so HeaderPolicy applies to them.
+		if a.MaxBodyBytes > 0 {
+			if !limitBody(a, cw, req, a.MaxBodyBytes) {
+				return
+			}
+		}
+		var hw http.ResponseWriter = cw
+		if mw := a.misuseWriter(cw, req); mw != nil {
+			defer mw.finish()
+			hw = mw
+		}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				if cw.status >= 500 {
+					req = a.classified(req, &StatusWrittenError{Status: cw.status})
+					a.serverErrorWritten(req, cw.status)
 				}
+				return
+			}
+			req = a.classified(req, a.handlePanic(cw, req, cw, rec))
+		}()
+
+		h.ServeHTTP(hw, req)
+	})
+}
+
+// StatusWrittenError records that a handler wrote a 5xx response itself
+// instead of returning an error.
+type StatusWrittenError struct {
+	Status int
+}
+
+func (e *StatusWrittenError) Error() string {
+	return fmt.Sprintf("handler responded %d %s", e.Status, http.StatusText(e.Status))
+}
+
+func (a *HandlerAdapter) serverErrorWritten(req *http.Request, status int) {
+	err := &StatusWrittenError{Status: status}
+	for _, hook := range a.onError {
+		hook(req, err)
+	}
+	a.report(req, err)
+}
+
+// handlePanic treats a recovered panic like a returned error, or only
+// reports it once the response is committed, and returns the error.
+// http.ErrAbortHandler is re-panicked so net/http aborts the response as
+// intended.
+func (a *HandlerAdapter) handlePanic(w http.ResponseWriter, req *http.Request, cw *commitWriter, rec interface{}) error {
+	if a.passthrough(rec) {
+		panic(rec)
+	}
 
-				// Use default internal error handler if no InternalErrs adapter is provided
-				defaultInternalError(w, req, err)
+	err := &PanicError{Value: rec, Stack: debug.Stack()}
+	if cw.committed {
+		a.report(req, err)
+		return err
+	}
+	a.HandleError(w, req, err)
+	return err
+}
+
+func (a *HandlerAdapter) passthrough(rec interface{}) bool {
+	err, isErr := rec.(error)
+	if isErr && errors.Is(err, http.ErrAbortHandler) {
+		return true
+	}
+	for _, v := range a.PanicPassthrough {
+		if target, ok := v.(error); ok && isErr {
+			if errors.Is(err, target) {
+				return true
 			}
+		} else if reflect.TypeOf(v) == reflect.TypeOf(rec) && reflect.TypeOf(v).Comparable() && v == rec {
+			return true
 		}
 	}
+	return false
+}
+
+// OnError registers a hook observing every error passed to HandleError,
+// before it is rendered; Classify(err).Class tells whose fault it is. Hooks
+// must be registered before serving.
+func (a *HandlerAdapter) OnError(hook func(r *http.Request, err error)) {
+	a.onError = append(a.onError, hook)
+}
+
+// OnResponse registers a hook observing the status and duration of every
+// request served by Handle or Wrap. ErrorClassFromContext returns the class
+// of the error of failed requests. Hooks must be registered before serving.
+func (a *HandlerAdapter) OnResponse(hook func(r *http.Request, status int, duration time.Duration)) {
+	a.onResponse = append(a.onResponse, hook)
+}
+
+func (a *HandlerAdapter) observe(req *http.Request, cw *commitWriter, start time.Time) {
+	status := cw.status
+	if !cw.committed {
+		// net/http sends 200 for handlers that write nothing.
+		status = http.StatusOK
+	}
+	d := time.Since(start)
+	for _, hook := range a.onResponse {
+		hook(req, status, d)
+	}
+}
+
+// report sends err to the Reporter, or to stderr without one.
+func (a *HandlerAdapter) report(req *http.Request, err error) {
+	if a.noReport || ReportingDisabled(req.Context()) {
+		return
+	}
+	req = withRequestInfo(req)
+	if a.Reporter != nil {
+		a.Reporter.ReportError(req.Context(), err)
+		return
+	}
+	log.Printf("httpx: %s %s: %v", req.Method, req.URL.Path, err)
+}
+
+// HandleError dispatches err to the matching adapter func. It is used by Handle
+// and by middleware that rejects requests before reaching the handler.
+func (a *HandlerAdapter) HandleError(w http.ResponseWriter, req *http.Request, err error) {
+	req = withRequestInfo(req)
+	if a.noReport && !ReportingDisabled(req.Context()) {
+		req = req.WithContext(context.WithValue(req.Context(), noReportKey{}, true))
+	}
+
+	for _, hook := range a.onError {
+		hook(req, err)
+	}
+
+	if errors.Is(err, ErrNotModified) {
+		writeNotModified(w)
+		return
+	}
+
+	var committed *CommittedError
+	if errors.As(err, &committed) {
+		a.report(req, committed.Err)
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = bodyTooLargeError(maxBytesErr.Limit)
+	}
+
+	// Dependency failures are reported, as the 502 or 504 they are
+	// rendered as would not be.
+	if upstreamErr, ok := asUpstream(err); ok {
+		a.report(req, err)
+		err = upstreamErr.appError()
+	}
+
+	if appErr, ok := classifyJoined(err); ok {
+		err = appErr
+	}
+
+	// The request's own deadline expired, see RequestTimeout.
+	if _, ok := err.(AppError); !ok && errors.Is(err, context.DeadlineExceeded) &&
+		errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+		err = deadlineExceededError(err)
+	}
+
+	if appErr, ok := err.(AppError); ok && a.Redaction != nil {
+		err = a.Redaction.redact(appErr)
+	}
+
+	if appErr, ok := err.(AppError); ok && appErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(appErr.RetryAfter)))
+	}
+
+	switch e := err.(type) {
+	case AppError:
+		if e.StatusCode == http.StatusUnauthorized && a.UnauthorizedErr != nil {
+			a.UnauthorizedErr(w, req, e)
+			return
+		}
+
+		if a.ClientErrs != nil {
+			a.ClientErrs(w, req, err)
+			return
+		}
+
+		// Use default AppError handler if no ClientErrs adapter is provided
+		defaultAppError(w, req, err)
+
+	default:
+		if a.InternalErrs != nil {
+			a.InternalErrs(w, req, err)
+			return
+		}
+
+		// Use default internal error handler if no InternalErrs adapter is provided
+		defaultInternalError(w, req, err)
+	}
+}
+
+// WithRoutePattern records the matched route pattern.
+func WithRoutePattern(ctx context.Context, pattern string) context.Context {
+	return routePatternKey.WithValue(ctx, pattern)
+}
+
+// RoutePatternFromContext returns the pattern of the route serving the
+// request, such as "GET /users/{id}", for labelling metrics, logs and traces
+// without the cardinality of raw paths. It is recorded by Group routes and,
+// for reporters, by the adapter when it handles or reports an error (which
+// covers the chi and gorilla integrations). Handlers served by Handle on a
+// ServeMux read Request.Pattern.
+//
+// Middleware wrapping the whole router runs before routing; it can read
+// Request.Pattern after calling the next handler instead.
+func RoutePatternFromContext(ctx context.Context) (string, bool) {
+	return routePatternKey.Value(ctx)
+}
+
+// withRequestInfo records the request line for reporters, unless already
+// recorded, and req.Pattern, as set by the innermost router.
+func withRequestInfo(req *http.Request) *http.Request {
+	ctx := req.Context()
+	if _, ok := requestLineKey.Value(ctx); !ok {
+		ctx = requestLineKey.WithValue(ctx, requestLine{method: req.Method, path: req.URL.Path})
+	}
+	if p, _ := RoutePatternFromContext(ctx); req.Pattern != "" && req.Pattern != p {
+		ctx = WithRoutePattern(ctx, req.Pattern)
+	}
+	if ctx == req.Context() {
+		return req
+	}
+	return req.WithContext(ctx)
+}
+
+func routePattern(ctx context.Context) string {
+	p, _ := RoutePatternFromContext(ctx)
+	return p
 }
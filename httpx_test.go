@@ -0,0 +1,85 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// benchmarkHandler serves a GET request with h and fails when a request
+// allocates more than maxAllocs times, so regressions on the success path
+// show up when benchmarks are run.
+func benchmarkHandler(b *testing.B, h http.Handler, maxAllocs float64) {
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.Header.Set("User-Agent", "bench")
+	w := httptest.NewRecorder()
+
+	if n := testing.AllocsPerRun(100, func() { h.ServeHTTP(w, req) }); n > maxAllocs {
+		b.Fatalf("%v allocations per request, want at most %v", n, maxAllocs)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, req)
+	}
+}
+
+func noContent(w http.ResponseWriter, _ *http.Request) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func BenchmarkHandle(b *testing.B) {
+	benchmarkHandler(b, NewDefaultHandlerAdapter(NewConfig()).Handle(noContent), 0)
+}
+
+func BenchmarkHandleObserved(b *testing.B) {
+	a := NewDefaultHandlerAdapter(NewConfig())
+	a.RecoverPanics, a.DetectServerErrors = true, true
+	a.OnResponse(func(*http.Request, int, time.Duration) {})
+	benchmarkHandler(b, a.Handle(noContent), 0)
+}
+
+func BenchmarkWrap(b *testing.B) {
+	a := NewDefaultHandlerAdapter(NewConfig())
+	benchmarkHandler(b, a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})), 0)
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	a := NewDefaultHandlerAdapter(NewConfig())
+	h := Chain(
+		AccessLog(io.Discard, AccessLogConfig{Duration: true}),
+		NormalizePath(NormalizePathConfig{}),
+		SlowRequests(a, SlowRequestConfig{}),
+		Compress(CompressConfig{}),
+		BodyLimit(a, 1<<20),
+	)(a.Handle(noContent))
+	benchmarkHandler(b, h, 0)
+}
+
+func TestWrapPanicAppliesHeaderPolicy(t *testing.T) {
+	a := NewDefaultHandlerAdapter(NewConfig())
+	a.HeaderPolicy = APIHeaderPolicy()
+	h := a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "legacy")
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	header := w.Result().Header
+	if got := header.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	if got := header.Get("Server"); got != "" {
+		t.Errorf("Server = %q, want it removed", got)
+	}
+}
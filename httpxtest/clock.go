@@ -0,0 +1,111 @@
+package httpxtest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radim/httpx"
+)
+
+type (
+	// Clock is an httpx.Clock that only moves when told to, so session
+	// expiry, cache freshness, breaker cool-downs and the like can be tested
+	// without sleeping. Timers fire when Advance or Set reach their
+	// deadline.
+	//
+	//	clock := httpxtest.SetClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	//	...
+	//	clock.Advance(31 * time.Minute) // the session has expired
+	Clock struct {
+		mu     sync.Mutex
+		now    time.Time
+		timers []*clockTimer
+	}
+
+	clockTimer struct {
+		clock    *Clock
+		c        chan time.Time
+		deadline time.Time
+		stopped  bool
+	}
+)
+
+// NewClock returns a Clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// SetClock installs a new Clock stopped at start as the httpx package
+// clock, and restores the system clock when the test finishes. Tests using
+// it must not run in parallel.
+func SetClock(t testing.TB, start time.Time) *Clock {
+	c := NewClock(start)
+	httpx.SetClock(c)
+	t.Cleanup(func() { httpx.SetClock(nil) })
+	return c
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer firing once the clock reached d from now. A
+// non-positive d fires immediately.
+func (c *Clock) NewTimer(d time.Duration) httpx.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &clockTimer{clock: c, c: make(chan time.Time, 1), deadline: c.now.Add(d)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing the timers due. Moving it backwards
+// fires nothing.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(t)
+}
+
+func (c *Clock) set(t time.Time) {
+	c.now = t
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.stopped {
+			continue
+		}
+		if t.Before(timer.deadline) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- t
+	}
+	c.timers = pending
+}
+
+func (t *clockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop prevents the timer from firing, reporting whether it was pending.
+func (t *clockTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := !t.stopped && len(t.c) == 0 && t.clock.now.Before(t.deadline)
+	t.stopped = true
+	return pending
+}
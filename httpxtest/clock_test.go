@@ -0,0 +1,63 @@
+package httpxtest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radim/httpx"
+)
+
+func TestClockDrivesSampling(t *testing.T) {
+	clock := SetClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var reported []string
+	r := httpx.NewSamplingReporter(httpx.ReporterFunc(func(_ context.Context, err error) {
+		reported = append(reported, err.Error())
+	}), httpx.SamplingConfig{Window: time.Minute})
+
+	err := errors.New("database unavailable")
+	for i := 0; i < 3; i++ {
+		r.ReportError(context.Background(), err)
+	}
+	clock.Advance(59 * time.Second)
+	r.ReportError(context.Background(), err)
+	if len(reported) != 1 {
+		t.Fatalf("reported %q within the window, want one report", reported)
+	}
+
+	clock.Advance(2 * time.Second)
+	r.ReportError(context.Background(), err)
+	if len(reported) != 2 || !strings.Contains(reported[1], "3 similar errors suppressed") {
+		t.Errorf("reported %q after the window, want the suppressed count", reported)
+	}
+}
+
+func TestClockTimers(t *testing.T) {
+	clock := NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fired := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-fired.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+	clock.Advance(time.Millisecond)
+	select {
+	case <-fired.C():
+	default:
+		t.Fatal("timer did not fire at its deadline")
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+}
@@ -0,0 +1,102 @@
+package httpxtest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/radim/httpx"
+)
+
+var updateGolden = flag.Bool("httpxtest.update", false, "rewrite httpxtest golden files")
+
+// GoldenCase is an error rendered by AssertGoldenErrors: an AppError, or
+// a 500 with Info when AppError is nil.
+type GoldenCase struct {
+	Name     string
+	AppError *httpx.AppError
+	Info     *httpx.ErrorInfo
+}
+
+// RepresentativeErrors covers the shapes of a typical error vocabulary.
+var RepresentativeErrors = []GoldenCase{
+	{Name: "bad_request", AppError: ptr(httpx.BadRequestError("invalid page size").WithCode("invalid_parameters"))},
+	{Name: "unauthorized", AppError: ptr(httpx.UnauthorizedError("authentication required"))},
+	{Name: "forbidden", AppError: ptr(httpx.ForbiddenError("access denied").WithCode("insufficient_scope"))},
+	{Name: "not_found", AppError: ptr(httpx.NotFoundError("user not found").WithCode("user_not_found"))},
+	{Name: "validation_failed", AppError: ptr(httpx.ValidationFailed(
+		httpx.FieldError{Field: "email", Message: "is required", Code: "required"},
+		httpx.FieldError{Field: "age", Message: "must be at least 18", Code: "minimum"},
+	))},
+	{Name: "too_many_requests", AppError: ptr(httpx.TooManyRequestsError("rate limit exceeded").WithCode("rate_limited"))},
+	{Name: "internal", Info: &httpx.ErrorInfo{Message: "Internal Server Error", Reference: "ABCDEFGH"}},
+}
+
+func ptr(e httpx.AppError) *httpx.AppError {
+	return &e
+}
+
+// AssertGoldenErrors renders each case (RepresentativeErrors if none are
+// given) through renderer and compares the status, headers and body
+// with dir/<name>.golden. Run the tests with -httpxtest.update to write
+// the files after an intended format change.
+func AssertGoldenErrors(t *testing.T, renderer httpx.Renderer, dir string, cases ...GoldenCase) {
+	t.Helper()
+	if len(cases) == 0 {
+		cases = RepresentativeErrors
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := renderGolden(renderer, c)
+			path := filepath.Join(dir, c.Name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -httpxtest.update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s changed:\n--- got\n%s\n--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func renderGolden(renderer httpx.Renderer, c GoldenCase) []byte {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if c.AppError != nil {
+		httpx.RenderAppError(renderer, rec, req, *c.AppError)
+	} else {
+		httpx.Render500(renderer, rec, req, c.Info)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP %d %s\n", rec.Code, http.StatusText(rec.Code))
+	names := make([]string, 0, len(rec.Header()))
+	for name := range rec.Header() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range rec.Header()[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+	b.WriteString("\n")
+	b.Write(rec.Body.Bytes())
+	return b.Bytes()
+}
@@ -0,0 +1,213 @@
+package httpxtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/radim/httpx"
+)
+
+type (
+	// Result is the outcome of Do.
+	Result struct {
+		Status int
+		Header http.Header
+		Body   []byte
+		// Err is the error the handler returned, before the adapter handled it.
+		Err error
+	}
+
+	// Recorder is an AppConfig whose reporter and renderer record what they
+	// receive. Rendering is delegated to Renderer, httpx.JSONRenderer by
+	// default.
+	//
+	//	rec := &httpxtest.Recorder{}
+	//	adapter := httpx.NewDefaultHandlerAdapter(rec)
+	Recorder struct {
+		Renderer    httpx.Renderer
+		Development bool
+
+		mu       sync.Mutex
+		reported []error
+		rendered []Rendered
+	}
+
+	// Rendered is one response produced through the Recorder's renderer.
+	Rendered struct {
+		Status   int
+		AppError *httpx.AppError
+		Info     *httpx.ErrorInfo
+	}
+)
+
+var (
+	_ httpx.AppConfig       = (*Recorder)(nil)
+	_ httpx.RequestRenderer = (*Recorder)(nil)
+)
+
+// Do serves req with h through adapter and records the response. A nil
+// adapter uses httpx.NewDefaultHandlerAdapter(nil).
+func Do(adapter *httpx.HandlerAdapter, h httpx.HTTPHandlerExt, req *http.Request) *Result {
+	if adapter == nil {
+		adapter = httpx.NewDefaultHandlerAdapter(nil)
+	}
+	res := &Result{}
+	rec := httptest.NewRecorder()
+	adapter.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		res.Err = h(w, r)
+		return res.Err
+	}).ServeHTTP(rec, req)
+
+	res.Status = rec.Code
+	res.Header = rec.Header()
+	res.Body = rec.Body.Bytes()
+	return res
+}
+
+func (r *Result) AssertStatus(t testing.TB, status int) *Result {
+	t.Helper()
+	if r.Status != status {
+		t.Errorf("status = %d, want %d; body: %s", r.Status, status, r.Body)
+	}
+	return r
+}
+
+func (r *Result) AssertHeader(t testing.TB, name, value string) *Result {
+	t.Helper()
+	if got := r.Header.Get(name); got != value {
+		t.Errorf("header %s = %q, want %q", name, got, value)
+	}
+	return r
+}
+
+// AssertJSONBody compares the body with want as JSON values, ignoring
+// formatting and key order. want is a JSON string, []byte or a value to
+// marshal.
+func (r *Result) AssertJSONBody(t testing.TB, want interface{}) *Result {
+	t.Helper()
+	var wantJSON []byte
+	switch w := want.(type) {
+	case string:
+		wantJSON = []byte(w)
+	case []byte:
+		wantJSON = w
+	default:
+		var err error
+		if wantJSON, err = json.Marshal(w); err != nil {
+			t.Fatalf("encoding expected body: %v", err)
+		}
+	}
+
+	var got, exp interface{}
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		t.Errorf("body is not JSON: %v; body: %s", err, r.Body)
+		return r
+	}
+	if err := json.Unmarshal(wantJSON, &exp); err != nil {
+		t.Fatalf("expected body is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("body = %s, want %s", r.Body, wantJSON)
+	}
+	return r
+}
+
+// AssertProblemCode checks the "code" member of a JSON or problem+json
+// error body.
+func (r *Result) AssertProblemCode(t testing.TB, code string) *Result {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		t.Errorf("body is not JSON: %v; body: %s", err, r.Body)
+		return r
+	}
+	if body.Code != code {
+		t.Errorf("error code = %q, want %q; body: %s", body.Code, code, r.Body)
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into v, failing the test on error.
+func (r *Result) DecodeJSON(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decoding body: %v; body: %s", err, r.Body)
+	}
+}
+
+func (rec *Recorder) IsDevelopment() bool {
+	return rec.Development
+}
+
+func (rec *Recorder) ReportError(_ context.Context, err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.reported = append(rec.reported, err)
+}
+
+func (rec *Recorder) GetRenderer() httpx.Renderer {
+	return rec
+}
+
+func (rec *Recorder) Render500(ctx context.Context, w http.ResponseWriter, errInfo *httpx.ErrorInfo) {
+	rec.mu.Lock()
+	rec.rendered = append(rec.rendered, Rendered{Status: http.StatusInternalServerError, Info: errInfo})
+	rec.mu.Unlock()
+	rec.renderer().Render500(ctx, w, errInfo)
+}
+
+func (rec *Recorder) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr httpx.AppError) {
+	rec.mu.Lock()
+	rec.rendered = append(rec.rendered, Rendered{Status: appErr.StatusCode, AppError: &appErr})
+	rec.mu.Unlock()
+	rec.renderer().RenderAppError(ctx, w, appErr)
+}
+
+func (rec *Recorder) Render500Request(w http.ResponseWriter, r *http.Request, errInfo *httpx.ErrorInfo) {
+	rec.mu.Lock()
+	rec.rendered = append(rec.rendered, Rendered{Status: http.StatusInternalServerError, Info: errInfo})
+	rec.mu.Unlock()
+	httpx.Render500(rec.renderer(), w, r, errInfo)
+}
+
+func (rec *Recorder) RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr httpx.AppError) {
+	rec.mu.Lock()
+	rec.rendered = append(rec.rendered, Rendered{Status: appErr.StatusCode, AppError: &appErr})
+	rec.mu.Unlock()
+	httpx.RenderAppError(rec.renderer(), w, r, appErr)
+}
+
+// Reported returns the errors reported so far.
+func (rec *Recorder) Reported() []error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]error(nil), rec.reported...)
+}
+
+// Rendered returns the error responses rendered so far.
+func (rec *Recorder) Rendered() []Rendered {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]Rendered(nil), rec.rendered...)
+}
+
+// Reset forgets recorded errors and responses.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.reported, rec.rendered = nil, nil
+}
+
+func (rec *Recorder) renderer() httpx.Renderer {
+	if rec.Renderer != nil {
+		return rec.Renderer
+	}
+	return httpx.JSONRenderer{}
+}
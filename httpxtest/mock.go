@@ -0,0 +1,221 @@
+// Package httpxtest provides test helpers for httpx handlers and clients.
+package httpxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type (
+	// MockTransport is an http.RoundTripper answering requests from
+	// scripted routes. Requests matching no route fail the test, and routes
+	// declared with Times are checked when the test finishes.
+	//
+	//	mt := httpxtest.NewMockTransport(t)
+	//	mt.On("GET", "/users/1").RespondJSON(200, User{ID: 1})
+	//	mt.On("POST", "/users").Times(1).RespondError(io.ErrUnexpectedEOF)
+	//	client, _ := httpx.NewClient("http://api.test", httpx.WithHTTPClient(mt.Client()))
+	MockTransport struct {
+		t testing.TB
+
+		mu     sync.Mutex
+		routes []*MockRoute
+		calls  []*http.Request
+	}
+
+	// MockRoute matches requests and scripts the response to them.
+	MockRoute struct {
+		method   string
+		url      string
+		matchers []func(r *http.Request, body []byte) bool
+
+		status int
+		header http.Header
+		body   []byte
+		err    error
+
+		times int // expected calls, -1 for any
+		calls int
+	}
+)
+
+func NewMockTransport(t testing.TB) *MockTransport {
+	m := &MockTransport{t: t}
+	t.Cleanup(m.AssertExpectations)
+	return m
+}
+
+// Client returns an http.Client using the transport.
+func (m *MockTransport) Client() *http.Client {
+	return &http.Client{Transport: m}
+}
+
+// On declares a route. method "" matches any method. url is either a full
+// URL or a path; a path matches any host, and without a query it matches
+// any query. Routes are tried in declaration order, skipping those whose
+// Times are used up, so repeated On calls script a sequence.
+func (m *MockTransport) On(method, url string) *MockRoute {
+	r := &MockRoute{method: method, url: url, status: http.StatusOK, header: http.Header{}, times: -1}
+	m.mu.Lock()
+	m.routes = append(m.routes, r)
+	m.mu.Unlock()
+	return r
+}
+
+// Match adds a predicate on the request and its body.
+func (r *MockRoute) Match(fn func(req *http.Request, body []byte) bool) *MockRoute {
+	r.matchers = append(r.matchers, fn)
+	return r
+}
+
+// MatchBody requires the request body to contain substr.
+func (r *MockRoute) MatchBody(substr string) *MockRoute {
+	return r.Match(func(_ *http.Request, body []byte) bool { return bytes.Contains(body, []byte(substr)) })
+}
+
+// MatchHeader requires a request header value.
+func (r *MockRoute) MatchHeader(name, value string) *MockRoute {
+	return r.Match(func(req *http.Request, _ []byte) bool { return req.Header.Get(name) == value })
+}
+
+// Times expects exactly n calls and stops matching after them.
+func (r *MockRoute) Times(n int) *MockRoute {
+	r.times = n
+	return r
+}
+
+func (r *MockRoute) Header(name, value string) *MockRoute {
+	r.header.Add(name, value)
+	return r
+}
+
+func (r *MockRoute) Respond(status int, body string) *MockRoute {
+	r.status, r.body = status, []byte(body)
+	return r
+}
+
+func (r *MockRoute) RespondJSON(status int, v interface{}) *MockRoute {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("httpxtest: encoding mock response: %v", err))
+	}
+	r.header.Set("Content-Type", "application/json")
+	r.status, r.body = status, data
+	return r
+}
+
+// RespondError fails matching requests with err, like a network failure.
+func (r *MockRoute) RespondError(err error) *MockRoute {
+	r.err = err
+	return r
+}
+
+// Calls returns how often the route matched.
+func (r *MockRoute) Calls() int {
+	return r.calls
+}
+
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, req)
+	route := m.match(req, body)
+	if route != nil {
+		route.calls++
+	}
+	m.mu.Unlock()
+
+	if route == nil {
+		m.t.Errorf("httpxtest: unexpected request %s %s", req.Method, req.URL)
+		return nil, fmt.Errorf("httpxtest: no mock route for %s %s", req.Method, req.URL)
+	}
+	if route.err != nil {
+		return nil, route.err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", route.status, http.StatusText(route.status)),
+		StatusCode:    route.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        route.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(route.body)),
+		ContentLength: int64(len(route.body)),
+		Request:       req,
+	}, nil
+}
+
+func (m *MockTransport) match(req *http.Request, body []byte) *MockRoute {
+	for _, r := range m.routes {
+		if r.times >= 0 && r.calls >= r.times {
+			continue
+		}
+		if r.method != "" && !strings.EqualFold(r.method, req.Method) {
+			continue
+		}
+		if !r.matchURL(req) {
+			continue
+		}
+		matched := true
+		for _, fn := range r.matchers {
+			if !fn(req, body) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *MockRoute) matchURL(req *http.Request) bool {
+	if !strings.HasPrefix(r.url, "/") {
+		return r.url == req.URL.String()
+	}
+	path, query, hasQuery := strings.Cut(r.url, "?")
+	if path != req.URL.Path {
+		return false
+	}
+	return !hasQuery || query == req.URL.RawQuery
+}
+
+// Requests returns every request received, in order.
+func (m *MockTransport) Requests() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request(nil), m.calls...)
+}
+
+// AssertExpectations fails the test for routes declared with Times that
+// did not get exactly that many calls. NewMockTransport registers it with
+// t.Cleanup.
+func (m *MockTransport) AssertExpectations() {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.routes {
+		if r.times >= 0 && r.calls != r.times {
+			m.t.Errorf("httpxtest: %s %s: expected %d calls, got %d", orAny(r.method), r.url, r.times, r.calls)
+		}
+	}
+}
+
+func orAny(method string) string {
+	if method == "" {
+		return "*"
+	}
+	return method
+}
@@ -0,0 +1,215 @@
+package httpx
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Message is a localizable error text. Key selects the entry in a
+	// Catalog and Args fill its fmt verbs. Keys are conveniently the
+	// English format string itself, gettext style: untranslated messages
+	// then read naturally, since Error formats Key with Args.
+	Message struct {
+		Key  string
+		Args []interface{}
+	}
+
+	// Catalog looks up the format string for key in the language tag.
+	Catalog interface {
+		Lookup(tag, key string) (string, bool)
+	}
+
+	// MapCatalog is a Catalog of tag → key → format. Tags are matched
+	// case-insensitively.
+	MapCatalog map[string]map[string]string
+
+	// Translator resolves Messages for the languages a client accepts.
+	Translator struct {
+		Catalog Catalog
+		// Fallback lists tags tried after the client's, in order.
+		Fallback []string
+	}
+
+	// LocalizingRenderer translates AppError messages, and the messages of
+	// validation field errors, into the request's Accept-Language before
+	// passing them to Renderer. Untranslated messages are left as they are.
+	// The detail of ValidationFailed errors is looked up under the key
+	// "validation failed".
+	LocalizingRenderer struct {
+		Renderer   Renderer
+		Translator *Translator
+	}
+
+	localizedError struct {
+		msg  string
+		errs []error
+	}
+)
+
+var _ RequestRenderer = (*LocalizingRenderer)(nil)
+
+// LocalizedError returns an AppError whose message is the Message for key
+// and args.
+func LocalizedError(status int, key string, args ...interface{}) AppError {
+	return AppError{Err: &Message{Key: key, Args: args}, StatusCode: status}
+}
+
+func (m *Message) Error() string {
+	if len(m.Args) == 0 {
+		return m.Key
+	}
+	return fmt.Sprintf(m.Key, m.Args...)
+}
+
+func (c MapCatalog) Lookup(tag, key string) (string, bool) {
+	msgs, ok := c[tag]
+	if !ok {
+		for t, m := range c {
+			if strings.EqualFold(t, tag) {
+				msgs, ok = m, true
+				break
+			}
+		}
+	}
+	format, ok := msgs[key]
+	return format, ok
+}
+
+// AcceptLanguages returns the language tags of r's Accept-Language header,
+// most preferred first. Wildcards and tags with q=0 are dropped.
+func AcceptLanguages(r *http.Request) []string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, header := range r.Header.Values("Accept-Language") {
+		for _, item := range strings.Split(header, ",") {
+			tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+			tag = strings.TrimSpace(tag)
+			if tag == "" || tag == "*" {
+				continue
+			}
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+			if q > 0 {
+				prefs = append(prefs, pref{tag, q})
+			}
+		}
+	}
+	slices.SortStableFunc(prefs, func(a, b pref) int { return cmp.Compare(b.q, a.q) })
+
+	tags := make([]string, len(prefs))
+	for i, p := range prefs {
+		tags[i] = p.tag
+	}
+	return tags
+}
+
+// Translate formats m in the first language of the chain that has it: each
+// of langs followed by its base language ("de-AT", then "de"), then the
+// Fallback tags. It returns the tag used, or "" when no catalog entry
+// exists.
+func (t *Translator) Translate(langs []string, m *Message) (string, string) {
+	format, tag, ok := t.lookup(langs, m.Key)
+	if !ok {
+		return m.Error(), ""
+	}
+	if len(m.Args) == 0 {
+		return format, tag
+	}
+	return fmt.Sprintf(format, m.Args...), tag
+}
+
+func (t *Translator) lookup(langs []string, key string) (format, tag string, ok bool) {
+	for _, l := range t.chain(langs) {
+		if format, ok := t.Catalog.Lookup(l, key); ok {
+			return format, l, true
+		}
+	}
+	return "", "", false
+}
+
+func (t *Translator) chain(langs []string) []string {
+	chain := make([]string, 0, 2*len(langs)+len(t.Fallback))
+	for _, l := range langs {
+		chain = append(chain, l)
+		if base, _, ok := strings.Cut(l, "-"); ok {
+			chain = append(chain, base)
+		}
+	}
+	return append(chain, t.Fallback...)
+}
+
+// Render500 and RenderAppError have no request to read Accept-Language
+// from and translate into the Fallback languages only.
+func (l *LocalizingRenderer) Render500(ctx context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	l.Renderer.Render500(ctx, w, errInfo)
+}
+
+func (l *LocalizingRenderer) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError) {
+	l.Renderer.RenderAppError(ctx, w, l.localize(w, nil, appErr))
+}
+
+func (l *LocalizingRenderer) Render500Request(w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo) {
+	Render500(l.Renderer, w, r, errInfo)
+}
+
+func (l *LocalizingRenderer) RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr AppError) {
+	RenderAppError(l.Renderer, w, r, l.localize(w, AcceptLanguages(r), appErr))
+}
+
+func (l *LocalizingRenderer) localize(w http.ResponseWriter, langs []string, appErr AppError) AppError {
+	w.Header().Add("Vary", "Accept-Language")
+
+	// Only a Message at the top is the client-facing text; one deeper in
+	// the chain was hidden by WrapStatus or redaction.
+	msg, tag := appErr.Error(), ""
+	if m, ok := appErr.Err.(*Message); ok {
+		msg, tag = l.Translator.Translate(langs, m)
+	}
+	le := &localizedError{msg: msg}
+	if v, ok := AsValidationError(appErr.Err); ok {
+		fields := make([]FieldError, len(v.Fields))
+		for i, f := range v.Fields {
+			if format, t, ok := l.Translator.lookup(langs, f.Message); ok {
+				f.Message, tag = format, cmp.Or(tag, t)
+			}
+			fields[i] = f
+		}
+		le.errs = append(le.errs, &ValidationError{Fields: fields})
+		if appErr.Err == error(v) {
+			// The detail repeats the field messages; translate it as a
+			// whole under the "validation failed" key instead.
+			if format, t, ok := l.Translator.lookup(langs, "validation failed"); ok {
+				msg, tag = format, cmp.Or(tag, t)
+				le.msg = msg
+			}
+		}
+	}
+	le.errs = append(le.errs, appErr.Err)
+	if tag != "" {
+		w.Header().Set("Content-Language", tag)
+	}
+
+	appErr.Err = le
+	return appErr
+}
+
+func (e *localizedError) Error() string {
+	return e.msg
+}
+
+func (e *localizedError) Unwrap() []error {
+	return e.errs
+}
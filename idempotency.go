@@ -0,0 +1,247 @@
+package httpx
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLockTTL is how long a key stays claimed by a
+	// request in progress.
+	DefaultIdempotencyLockTTL = time.Minute
+)
+
+// ErrIdempotencyKeyExists is returned by IdempotencyStore.Lock when the key
+// is already taken; the existing record is returned alongside it.
+var ErrIdempotencyKeyExists = errors.New("httpx: idempotency key exists")
+
+// IdempotencyRecord is the stored outcome of a request. Done is false
+// while the original request is still being processed.
+type IdempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyStore persists idempotency records. Implementations must make
+// Lock atomic so only one request can claim a key.
+type IdempotencyStore interface {
+	// Lock claims key with an in-progress record. If the key is taken it
+	// returns the existing record and ErrIdempotencyKeyExists.
+	Lock(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, error)
+	// Save replaces the record for a claimed key with the final response.
+	Save(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error
+	// Release drops a claim whose request did not produce a storable
+	// response, so the client may retry.
+	Release(ctx context.Context, key string) error
+}
+
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	// TTL is how long keys are remembered; DefaultIdempotencyTTL if zero.
+	TTL time.Duration
+	// LockTTL is how long a key stays claimed while its request is in
+	// progress, bounding how long a crashed process holds it;
+	// DefaultIdempotencyLockTTL if zero. Requests taking longer may be
+	// processed twice.
+	LockTTL time.Duration
+	// Required rejects requests to covered methods without a key.
+	Required bool
+	// Methods covered by the middleware; POST and PATCH if empty.
+	Methods []string
+	// MaxBody bounds both the fingerprinted request body and the stored
+	// response body; 1 MiB if zero.
+	MaxBody int
+	// Scope namespaces keys, e.g. per authenticated principal, so clients
+	// cannot replay each other's responses. Defaults to none.
+	Scope func(r *http.Request) string
+}
+
+// Idempotency implements the Idempotency-Key pattern: the first request
+// with a key is processed and its response stored; repeats with the same
+// payload get the stored response with an Idempotent-Replayed header.
+// A repeat with a different payload is a 422 AppError (code
+// "idempotency_key_reused"), one arriving while the original is still
+// running a 409 (code "idempotency_key_in_progress"). Server errors, panics
+// and responses too large to store release the key instead. Headers
+// describing the original request, such as Date and X-Request-ID, are not
+// stored.
+func Idempotency(adapter *HandlerAdapter, cfg IdempotencyConfig) Middleware {
+	ttl := cmp.Or(cfg.TTL, DefaultIdempotencyTTL)
+	lockTTL := min(cmp.Or(cfg.LockTTL, DefaultIdempotencyLockTTL), ttl)
+	maxBody := cmp.Or(cfg.MaxBody, 1<<20)
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	info := RouteInfo{Headers: []string{IdempotencyKeyHeader}}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				if cfg.Required {
+					adapter.HandleError(w, r, StatusError(http.StatusBadRequest,
+						"the %s header is required", IdempotencyKeyHeader).WithCode("idempotency_key_missing"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				adapter.HandleError(w, r, StatusError(http.StatusBadRequest,
+					"the %s header is too long", IdempotencyKeyHeader).WithCode("invalid_idempotency_key"))
+				return
+			}
+			if cfg.Scope != nil {
+				key = cfg.Scope(r) + "\x00" + key
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBody)+1))
+			if err != nil {
+				adapter.HandleError(w, r, err)
+				return
+			}
+			if len(body) > maxBody {
+				adapter.HandleError(w, r, StatusError(http.StatusRequestEntityTooLarge,
+					"request body too large").WithCode("body_too_large"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fp := idempotencyFingerprint(r, body)
+			ctx := r.Context()
+			rec, err := cfg.Store.Lock(ctx, key, IdempotencyRecord{Fingerprint: fp}, lockTTL)
+			switch {
+			case errors.Is(err, ErrIdempotencyKeyExists):
+				replayIdempotent(adapter, w, r, rec, fp)
+				return
+			case err != nil:
+				adapter.HandleError(w, r, err)
+				return
+			}
+
+			rw := &recordWriter{ResponseWriter: w, limit: maxBody}
+			saved := false
+			defer func() {
+				if !saved {
+					// Runs on panics too, so a crashed request frees its key.
+					if err := cfg.Store.Release(context.WithoutCancel(ctx), key); err != nil {
+						adapter.report(r, err)
+					}
+				}
+			}()
+			next.ServeHTTP(rw, r)
+
+			status, header, respBody, ok := rw.recorded()
+			if !ok || status >= 500 {
+				return
+			}
+			header = header.Clone()
+			deletePerRequestHeaders(header)
+			rec = IdempotencyRecord{Fingerprint: fp, Done: true, Status: status, Header: header, Body: bytes.Clone(respBody)}
+			if err := cfg.Store.Save(context.WithoutCancel(ctx), key, rec, ttl); err != nil {
+				adapter.report(r, err)
+				return
+			}
+			saved = true
+		})
+	})
+}
+
+func replayIdempotent(adapter *HandlerAdapter, w http.ResponseWriter, r *http.Request, rec IdempotencyRecord, fp string) {
+	switch {
+	case rec.Fingerprint != fp:
+		adapter.HandleError(w, r, UnprocessableEntityError(
+			"idempotency key was already used with a different request").WithCode("idempotency_key_reused"))
+	case !rec.Done:
+		adapter.HandleError(w, r, ConflictError(
+			"a request with this idempotency key is still being processed").WithCode("idempotency_key_in_progress"))
+	default:
+		h := w.Header()
+		for k, v := range rec.Header {
+			h[k] = v
+		}
+		h.Set(IdempotencyReplayedHeader, "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+}
+
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryIdempotencyStore keeps records in process memory. It suits single
+// instances and tests; use a shared store when running several replicas.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]memoryIdempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Lock(_ context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clockNow()
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		return e.rec, ErrIdempotencyKeyExists
+	}
+	if len(s.records) >= 1024 {
+		s.prune(now)
+	}
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: now.Add(ttl)}
+	return rec, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: clockNow().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) prune(now time.Time) {
+	for k, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lockTTLStore records the TTL keys are claimed with.
+type lockTTLStore struct {
+	*MemoryIdempotencyStore
+	lockTTL time.Duration
+}
+
+func (s *lockTTLStore) Lock(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, error) {
+	s.lockTTL = ttl
+	return s.MemoryIdempotencyStore.Lock(ctx, key, rec, ttl)
+}
+
+func TestIdempotency(t *testing.T) {
+	adapter := NewDefaultHandlerAdapter(NewConfig())
+	store := &lockTTLStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore()}
+	calls := 0
+	h := Idempotency(adapter, IdempotencyConfig{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Panic") != "" {
+			panic("boom")
+		}
+		w.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+		w.Header().Set(RequestIDHeader, r.Header.Get(RequestIDHeader))
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	post := func(key, requestID string, panics bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"item":"book"}`))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req.Header.Set(RequestIDHeader, requestID)
+		if panics {
+			req.Header.Set("X-Panic", "1")
+		}
+		w := httptest.NewRecorder()
+		func() {
+			defer func() { recover() }()
+			h.ServeHTTP(w, req)
+		}()
+		return w
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		post("k1", "first", false)
+		w := post("k1", "second", false)
+		if calls != 1 {
+			t.Fatalf("%d handler calls, want 1", calls)
+		}
+		if store.lockTTL != DefaultIdempotencyLockTTL {
+			t.Errorf("key locked for %v, want %v", store.lockTTL, DefaultIdempotencyLockTTL)
+		}
+		tests := []struct{ header, want string }{
+			{IdempotencyReplayedHeader, "true"},
+			{"Location", "/orders/1"},
+			{"Date", ""},
+			{RequestIDHeader, ""},
+		}
+		for _, tt := range tests {
+			if got := w.Header().Get(tt.header); got != tt.want {
+				t.Errorf("replayed %s = %q, want %q", tt.header, got, tt.want)
+			}
+		}
+		if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` {
+			t.Errorf("replayed %d %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("panic releases the key", func(t *testing.T) {
+		calls = 0
+		post("k2", "first", true)
+		if w := post("k2", "second", false); w.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("retry after panic: %d after %d calls, want 201 after 2", w.Code, calls)
+		}
+	})
+}
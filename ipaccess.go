@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPAccessConfig restricts requests by client address, as resolved by
+// ClientIP (install RealIPMiddleware first when behind proxies).
+type IPAccessConfig struct {
+	// Allow, when non-empty, admits only addresses inside these prefixes.
+	Allow []netip.Prefix
+	// Deny rejects addresses inside these prefixes, even if allowed.
+	Deny []netip.Prefix
+	// Decide, if set, is consulted after the lists and has the final say
+	// for addresses they admit.
+	Decide func(r *http.Request, ip netip.Addr) bool
+}
+
+// IPAccess rejects requests from disallowed addresses with a 403 AppError
+// (code "ip_forbidden"). Requests without a parseable address are rejected
+// whenever Allow or Decide is configured.
+func IPAccess(adapter *HandlerAdapter, cfg IPAccessConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.permits(r, ClientIP(r)) {
+				adapter.HandleError(w, r, ForbiddenError("access denied").WithCode("ip_forbidden"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (c IPAccessConfig) permits(r *http.Request, ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return len(c.Allow) == 0 && c.Decide == nil
+	}
+	if prefixesContain(c.Deny, ip) {
+		return false
+	}
+	if len(c.Allow) > 0 && !prefixesContain(c.Allow, ip) {
+		return false
+	}
+	return c.Decide == nil || c.Decide(r, ip)
+}
+
+func prefixesContain(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package httpx
+
+import "errors"
+
+// classifyJoined picks the AppError with the highest status among the
+// constituents of a joined error (errors.Join or any Unwrap() []error). The
+// result keeps the chosen status, code and message but unwraps to the whole
+// joined error, so errors.Is matches every constituent.
+func classifyJoined(err error) (AppError, bool) {
+	var best AppError
+	var found bool
+
+	for _, e := range joinedErrors(err) {
+		var appErr AppError
+		if errors.As(e, &appErr) && (!found || appErr.StatusCode > best.StatusCode) {
+			best, found = appErr, true
+		}
+	}
+	if !found {
+		return AppError{}, false
+	}
+
+	best.Err = &wrappedError{msg: best.Error(), err: err}
+	return best, true
+}
+
+// joinedErrors returns the constituents of a joined error, or nil if err is
+// not one.
+func joinedErrors(err error) []error {
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	return j.Unwrap()
+}
@@ -0,0 +1,129 @@
+package httpx
+
+import (
+	"bufio"
+	"errors"
+	"iter"
+	"net/http"
+)
+
+// DefaultStreamFlushEvery is how many items JSONStream writes between
+// flushes.
+const DefaultStreamFlushEvery = 100
+
+type (
+	JSONStreamOption func(*jsonStreamConfig)
+
+	jsonStreamConfig struct {
+		ndjson     bool
+		flushEvery int
+	}
+)
+
+// WithNDJSON writes one JSON value per line with Content-Type
+// application/x-ndjson instead of a JSON array.
+func WithNDJSON() JSONStreamOption {
+	return func(c *jsonStreamConfig) {
+		c.ndjson = true
+	}
+}
+
+// WithFlushEvery flushes the response every n items; DefaultStreamFlushEvery
+// by default.
+func WithFlushEvery(n int) JSONStreamOption {
+	return func(c *jsonStreamConfig) {
+		c.flushEvery = n
+	}
+}
+
+// JSONStream encodes the items of seq as a JSON array, one at a time, so
+// large collections are sent without holding them in memory. The response
+// is flushed every DefaultStreamFlushEvery items, letting clients start
+// processing early.
+//
+// The status is written with the first item, so an error yielded before it
+// is returned unchanged for the adapter to render. Later errors end the
+// response early, leaving the array unterminated, and are returned as
+// *CommittedError like in Stream.
+//
+//	rows := store.Scan(ctx) // iter.Seq2[Row, error]
+//	return httpx.JSONStream(w, http.StatusOK, rows)
+func JSONStream[T any](w http.ResponseWriter, status int, seq iter.Seq2[T, error], opts ...JSONStreamOption) error {
+	cfg := jsonStreamConfig{flushEvery: DefaultStreamFlushEvery}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var bw *bufio.Writer
+	start := func() {
+		if cfg.ndjson {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		bw = bufio.NewWriter(w)
+		if !cfg.ndjson {
+			bw.WriteByte('[')
+		}
+	}
+	fail := func(err error) error {
+		if bw == nil {
+			return err
+		}
+		bw.Flush()
+		return &CommittedError{Err: err}
+	}
+
+	n := 0
+	for item, err := range seq {
+		if err != nil {
+			return fail(err)
+		}
+		data, err := currentJSON().Marshal(item)
+		if err != nil {
+			return fail(err)
+		}
+		if bw == nil {
+			start()
+		} else if !cfg.ndjson {
+			bw.WriteByte(',')
+		}
+		bw.Write(data)
+		if cfg.ndjson {
+			bw.WriteByte('\n')
+		}
+
+		if n++; cfg.flushEvery > 0 && n%cfg.flushEvery == 0 {
+			if err := bw.Flush(); err != nil {
+				return &CommittedError{Err: err}
+			}
+			if err := Flush(w); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return &CommittedError{Err: err}
+			}
+		}
+	}
+
+	if bw == nil {
+		start()
+	}
+	if !cfg.ndjson {
+		bw.WriteString("]\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return &CommittedError{Err: err}
+	}
+	return nil
+}
+
+// ChanSeq adapts a channel to the iterator taken by JSONStream. It ends
+// when ch is closed.
+func ChanSeq[T any](ch <-chan T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for v := range ch {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384/512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const jwtClaimsAttr = "jwt_claims"
+
+const (
+	// maxJWKSSize bounds the key set documents JWKS reads.
+	maxJWKSSize = 1 << 20
+	// jwksFetchTimeout bounds a key set fetch, which outlives the request
+	// that started it since other requests wait for it.
+	jwksFetchTimeout = 30 * time.Second
+	// maxJWTTime is the latest accepted exp, nbf and iat claim,
+	// 9999-12-31T23:59:59Z.
+	maxJWTTime = 253402300799
+)
+
+type (
+	// JWTKeyFunc returns the verification key for a token signed with alg and
+	// (optionally) identified by kid. HMAC algorithms expect a []byte key, RSA
+	// and ECDSA algorithms the corresponding public key, EdDSA an
+	// ed25519.PublicKey.
+	JWTKeyFunc func(ctx context.Context, alg, kid string) (interface{}, error)
+
+	// JWTAuthenticator authenticates requests carrying a bearer JWT in the
+	// Authorization header.
+	JWTAuthenticator struct {
+		Keys JWTKeyFunc
+		// Issuer and Audience are validated when non-empty.
+		Issuer   string
+		Audience string
+		// Algorithms restricts the accepted "alg" values. Empty means any
+		// supported algorithm compatible with the returned key.
+		Algorithms []string
+		// Leeway tolerates clock skew when validating exp/nbf.
+		Leeway time.Duration
+		Realm  string
+	}
+
+	// JWTClaims holds the registered claims of a verified token and gives
+	// typed access to the remaining ones.
+	JWTClaims struct {
+		Issuer    string
+		Subject   string
+		Audience  []string
+		ExpiresAt time.Time
+		NotBefore time.Time
+		IssuedAt  time.Time
+		ID        string
+
+		raw map[string]json.RawMessage
+	}
+
+	// JWKS is a JSON Web Key Set fetched from a URL and cached in memory.
+	// Its Key method can be used as a JWTKeyFunc.
+	JWKS struct {
+		URL    string
+		Client *http.Client
+		// TTL is how long a fetched key set is considered fresh.
+		TTL time.Duration
+		// MinRefreshInterval bounds refetches triggered by unknown key IDs,
+		// and retries after a failed fetch.
+		MinRefreshInterval time.Duration
+
+		mu          sync.Mutex
+		keys        map[string]interface{}
+		fetchedAt   time.Time
+		attemptedAt time.Time
+		err         error
+		// refreshing is closed when the fetch in progress ends.
+		refreshing chan struct{}
+	}
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+
+	jsonWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+func invalidTokenError(content string, params ...interface{}) AppError {
+	return UnauthorizedError(content, params...).WithCode("invalid_token")
+}
+
+// StaticJWTKey returns a JWTKeyFunc that always returns key.
+func StaticJWTKey(key interface{}) JWTKeyFunc {
+	return func(context.Context, string, string) (interface{}, error) {
+		return key, nil
+	}
+}
+
+func (a *JWTAuthenticator) Challenge() string {
+	if a.Realm != "" {
+		return fmt.Sprintf("Bearer realm=%q", a.Realm)
+	}
+	return "Bearer"
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Principal{
+		Subject:    claims.Subject,
+		Attributes: map[string]interface{}{jwtClaimsAttr: claims},
+	}
+
+	var scope string
+	if claims.Decode("scope", &scope) == nil {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		claims.Decode("scp", &p.Scopes)
+	}
+	claims.Decode("roles", &p.Roles)
+
+	return p, nil
+}
+
+// Verify checks the signature and registered claims of token. All failures
+// are returned as 401 AppErrors.
+func (a *JWTAuthenticator) Verify(ctx context.Context, token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidTokenError("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, invalidTokenError("malformed token header")
+	}
+	if !a.algorithmAllowed(header.Alg) {
+		return nil, invalidTokenError("token algorithm %q not allowed", header.Alg)
+	}
+
+	key, err := a.Keys(ctx, header.Alg, header.Kid)
+	if err != nil {
+		return nil, invalidTokenError("no key for token: %v", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidTokenError("malformed token signature")
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, invalidTokenError("invalid token signature: %v", err)
+	}
+
+	claims := &JWTClaims{}
+	if err := decodeJWTSegment(parts[1], &claims.raw); err != nil {
+		return nil, invalidTokenError("malformed token claims")
+	}
+	if err := claims.parseRegistered(); err != nil {
+		return nil, invalidTokenError("malformed token claims: %v", err)
+	}
+
+	return claims, a.validate(claims)
+}
+
+func (a *JWTAuthenticator) algorithmAllowed(alg string) bool {
+	if alg == "" || alg == "none" {
+		return false
+	}
+	if len(a.Algorithms) == 0 {
+		return true
+	}
+	for _, allowed := range a.Algorithms {
+		if alg == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *JWTAuthenticator) validate(c *JWTClaims) error {
+	now := clockNow()
+
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(a.Leeway)) {
+		return UnauthorizedError("token expired").WithCode("token_expired")
+	}
+	if !c.NotBefore.IsZero() && now.Add(a.Leeway).Before(c.NotBefore) {
+		return invalidTokenError("token not valid yet")
+	}
+	if a.Issuer != "" && c.Issuer != a.Issuer {
+		return invalidTokenError("unexpected token issuer")
+	}
+	if a.Audience != "" {
+		for _, aud := range c.Audience {
+			if aud == a.Audience {
+				return nil
+			}
+		}
+		return invalidTokenError("unexpected token audience")
+	}
+
+	return nil
+}
+
+// Decode unmarshals the named claim into v.
+func (c *JWTClaims) Decode(name string, v interface{}) error {
+	raw, ok := c.raw[name]
+	if !ok {
+		return fmt.Errorf("claim %q not present", name)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func (c *JWTClaims) parseRegistered() error {
+	for name, dst := range map[string]*string{"iss": &c.Issuer, "sub": &c.Subject, "jti": &c.ID} {
+		if _, ok := c.raw[name]; ok {
+			if err := c.Decode(name, dst); err != nil {
+				return fmt.Errorf("claim %q: %w", name, err)
+			}
+		}
+	}
+
+	for name, dst := range map[string]*time.Time{"exp": &c.ExpiresAt, "nbf": &c.NotBefore, "iat": &c.IssuedAt} {
+		if _, ok := c.raw[name]; ok {
+			var ts json.Number
+			if err := c.Decode(name, &ts); err != nil {
+				return fmt.Errorf("claim %q: %w", name, err)
+			}
+			f, err := ts.Float64()
+			if err != nil {
+				return fmt.Errorf("claim %q: %w", name, err)
+			}
+			if !(f >= 0 && f <= maxJWTTime) {
+				return fmt.Errorf("claim %q: time %s out of range", name, ts)
+			}
+			sec, frac := math.Modf(f)
+			*dst = time.Unix(int64(sec), int64(frac*float64(time.Second)))
+		}
+	}
+
+	if raw, ok := c.raw["aud"]; ok {
+		// "aud" is either a single string or an array of strings.
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			return c.Decode("aud", &c.Audience)
+		}
+		var aud string
+		if err := c.Decode("aud", &aud); err != nil {
+			return fmt.Errorf(`claim "aud": %w`, err)
+		}
+		c.Audience = []string{aud}
+	}
+
+	return nil
+}
+
+// JWTClaimsFromContext returns the claims of the token that authenticated
+// the request, if any.
+func JWTClaimsFromContext(ctx context.Context) (*JWTClaims, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	c, ok := p.Attributes[jwtClaimsAttr].(*JWTClaims)
+	return c, ok
+}
+
+// JWTClaim returns the named claim of the request's token decoded as T.
+func JWTClaim[T any](ctx context.Context, name string) (T, bool) {
+	var v T
+	c, ok := JWTClaimsFromContext(ctx)
+	if !ok {
+		return v, false
+	}
+	return v, c.Decode(name, &v) == nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+func decodeJWTSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func verifyJWTSignature(alg string, key interface{}, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch strings.TrimLeft(alg, "HRPSE") {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+
+	switch {
+	case alg == "EdDSA":
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || len(k) != ed25519.PublicKeySize {
+			return errors.New("key type mismatch")
+		}
+		if !ed25519.Verify(k, []byte(signed), sig) {
+			return errors.New("verification failed")
+		}
+		return nil
+
+	case hash == 0:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+
+	case strings.HasPrefix(alg, "HS"):
+		k, ok := key.([]byte)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("verification failed")
+		}
+		return nil
+	}
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		return rsa.VerifyPKCS1v15(k, hash, digest, sig)
+
+	case strings.HasPrefix(alg, "PS"):
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		return rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+
+	case strings.HasPrefix(alg, "ES"):
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("verification failed")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("verification failed")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+// NewJWKS returns a key set fetched from url, cached for an hour.
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		URL:                url,
+		TTL:                time.Hour,
+		MinRefreshInterval: time.Minute,
+	}
+}
+
+// Key implements JWTKeyFunc. Unknown key IDs trigger a refetch, rate limited
+// by MinRefreshInterval, to pick up rotated keys. One request fetches at a
+// time, without holding up requests for known keys, and a failed fetch is
+// retried after MinRefreshInterval, serving the previous keys meanwhile.
+func (s *JWKS) Key(ctx context.Context, alg, kid string) (interface{}, error) {
+	for fetched := false; ; fetched = true {
+		s.mu.Lock()
+		key, ok := s.keys[kid]
+		now := clockNow()
+		stale := s.keys == nil || now.Sub(s.fetchedAt) > s.TTL || !ok
+		recent := now.Sub(s.attemptedAt) <= s.MinRefreshInterval
+
+		if wait := s.refreshing; wait != nil && !ok && !fetched {
+			s.mu.Unlock()
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if fetched || !stale || recent || s.refreshing != nil {
+			err, loaded := s.err, s.keys != nil
+			s.mu.Unlock()
+			switch {
+			case ok:
+				return key, nil
+			case !loaded && err != nil:
+				return nil, err
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		s.refreshing = make(chan struct{})
+		s.attemptedAt = now
+		s.mu.Unlock()
+
+		keys, err := s.fetch(ctx)
+
+		s.mu.Lock()
+		if err == nil {
+			s.keys, s.fetchedAt = keys, clockNow()
+		}
+		s.err = err
+		close(s.refreshing)
+		s.refreshing = nil
+		s.mu.Unlock()
+	}
+}
+
+// fetch reads the key set. It is not canceled with ctx, since other requests
+// may be waiting for it.
+func (s *JWKS) fetch(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwks request: %w", err)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key length %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,210 @@
+package httpx
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+	now := time.Now()
+	valid := map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer.example.com",
+		"aud": "api",
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+	tampered := func(token string) string {
+		parts := strings.Split(token, ".")
+		claims, _ := json.Marshal(with("sub", "mallory"))
+		return parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+	}
+
+	hs := &JWTAuthenticator{Keys: StaticJWTKey(secret), Issuer: "https://issuer.example.com", Audience: "api"}
+	rs := &JWTAuthenticator{Keys: StaticJWTKey(&rsaKey.PublicKey), Algorithms: []string{"RS256"}}
+	rsAnyAlg := &JWTAuthenticator{Keys: StaticJWTKey(&rsaKey.PublicKey)}
+
+	tests := []struct {
+		name  string
+		authn *JWTAuthenticator
+		token string
+		code  string
+	}{
+		{name: "valid HMAC", authn: hs, token: signJWT(t, "HS256", secret, valid)},
+		{name: "valid RSA", authn: rs, token: signJWT(t, "RS256", rsaKey, valid)},
+		{name: "wrong secret", authn: hs, token: signJWT(t, "HS256", []byte("other"), valid), code: "invalid_token"},
+		{name: "tampered claims", authn: rs, token: tampered(signJWT(t, "RS256", rsaKey, valid)), code: "invalid_token"},
+		{name: "alg none", authn: hs, token: strings.TrimSuffix(signJWT(t, "none", nil, valid), "."), code: "invalid_token"},
+		{name: "alg not allowed", authn: rs, token: signJWT(t, "HS256", rsaPublicDER, valid), code: "invalid_token"},
+		{name: "HMAC with RSA public key", authn: rsAnyAlg, token: signJWT(t, "HS256", rsaPublicDER, valid), code: "invalid_token"},
+		{name: "expired", authn: hs, token: signJWT(t, "HS256", secret, with("exp", now.Add(-time.Minute).Unix())), code: "token_expired"},
+		{name: "not yet valid", authn: hs, token: signJWT(t, "HS256", secret, with("nbf", now.Add(time.Hour).Unix())), code: "invalid_token"},
+		{name: "wrong audience", authn: hs, token: signJWT(t, "HS256", secret, with("aud", []string{"other", "web"})), code: "invalid_token"},
+		{name: "audience in list", authn: hs, token: signJWT(t, "HS256", secret, with("aud", []string{"other", "api"}))},
+		{name: "wrong issuer", authn: hs, token: signJWT(t, "HS256", secret, with("iss", "https://evil.example.com")), code: "invalid_token"},
+		{name: "malformed", authn: hs, token: "not.a.token", code: "invalid_token"},
+		{name: "exp after 2262", authn: hs, token: signJWT(t, "HS256", secret, with("exp", int64(32503680000)))},
+		{name: "fractional exp", authn: hs, token: signJWT(t, "HS256", secret, with("exp", float64(now.Add(time.Hour).Unix())+0.5))},
+		{name: "exp out of range", authn: hs, token: signJWT(t, "HS256", secret, with("exp", 1e19)), code: "invalid_token"},
+		{name: "negative nbf", authn: hs, token: signJWT(t, "HS256", secret, with("nbf", -1)), code: "invalid_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.authn.Verify(context.Background(), tt.token)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if claims.Subject != "alice" {
+					t.Errorf("Subject = %q, want alice", claims.Subject)
+				}
+				return
+			}
+			var appErr AppError
+			if !errors.As(err, &appErr) || appErr.Code != tt.code || appErr.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Verify error = %v, want a 401 with code %q", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`))
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL)
+	jwks.MinRefreshInterval = time.Hour
+
+	// Concurrent requests share one fetch.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jwks.Key(context.Background(), "EdDSA", "k1"); err != nil {
+				t.Errorf("Key: %v", err)
+			}
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("%d fetches, want 1", n)
+	}
+
+	// A failed refetch for an unknown key backs off and keeps the known keys.
+	fail.Store(true)
+	jwks.mu.Lock()
+	jwks.attemptedAt = time.Time{}
+	jwks.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if _, err := jwks.Key(context.Background(), "EdDSA", "k2"); err == nil {
+			t.Error("Key(k2) succeeded")
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("%d fetches after failures, want 2", n)
+	}
+	if _, err := jwks.Key(context.Background(), "EdDSA", "k1"); err != nil {
+		t.Errorf("Key(k1) after failed refetch: %v", err)
+	}
+}
+
+func TestJWKSInvalidKeys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"short","x":"11qYAYKxCrfVS_7TyWQHOg"}]}`))
+	}))
+	defer srv.Close()
+
+	if key, err := NewJWKS(srv.URL).Key(context.Background(), "EdDSA", "short"); err == nil {
+		t.Errorf("Key returned %v for a truncated Ed25519 key", key)
+	}
+	authn := &JWTAuthenticator{Keys: StaticJWTKey(ed25519.PublicKey{1, 2, 3})}
+	token := "eyJhbGciOiJFZERTQSJ9.e30." + base64.RawURLEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+	if _, err := authn.Verify(context.Background(), token); err == nil {
+		t.Error("Verify accepted a token for a truncated Ed25519 key")
+	}
+}
+
+func TestJWKSBodyLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[],"padding":"`))
+		w.Write([]byte(strings.Repeat("x", maxJWKSSize)))
+		w.Write([]byte(`"}`))
+	}))
+	defer srv.Close()
+
+	if _, err := NewJWKS(srv.URL).Key(context.Background(), "EdDSA", "k1"); err == nil || !strings.Contains(err.Error(), "decoding jwks") {
+		t.Errorf("Key error = %v, want a decoding error", err)
+	}
+}
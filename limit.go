@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyLimiter caps the number of requests in flight. Share one
+// limiter between routes for a global cap or create one per route.
+type ConcurrencyLimiter struct {
+	adapter    *HandlerAdapter
+	sem        chan struct{}
+	wait       time.Duration
+	maxQueue   int64
+	retryAfter time.Duration
+
+	inFlight atomic.Int64
+	queued   atomic.Int64
+	shed     atomic.Uint64
+}
+
+type LimiterOption func(*ConcurrencyLimiter)
+
+// WithQueueWait lets excess requests wait up to d for a slot before being
+// shed. The default is to shed immediately.
+func WithQueueWait(d time.Duration) LimiterOption {
+	return func(l *ConcurrencyLimiter) { l.wait = d }
+}
+
+// WithMaxQueue bounds the number of waiting requests; 0 means unbounded.
+func WithMaxQueue(n int) LimiterOption {
+	return func(l *ConcurrencyLimiter) { l.maxQueue = int64(n) }
+}
+
+// WithShedRetryAfter sets the Retry-After sent with shed requests; 1s by
+// default.
+func WithShedRetryAfter(d time.Duration) LimiterOption {
+	return func(l *ConcurrencyLimiter) { l.retryAfter = d }
+}
+
+// LimiterStats is a snapshot of a limiter's gauges and counters.
+type LimiterStats struct {
+	InFlight int64
+	Queued   int64
+	Limit    int
+	Shed     uint64
+}
+
+func NewConcurrencyLimiter(adapter *HandlerAdapter, limit int, opts ...LimiterOption) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{adapter: adapter, sem: make(chan struct{}, max(limit, 1)), retryAfter: time.Second}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Middleware sheds requests that cannot get a slot as 503 AppErrors with
+// code "overloaded" and a Retry-After, see WithShedRetryAfter.
+func (l *ConcurrencyLimiter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l.serve(w, r, next)
+		})
+	}
+}
+
+func (l *ConcurrencyLimiter) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if !l.acquire(r) {
+		l.shed.Add(1)
+		l.adapter.HandleError(w, r, ServiceUnavailableWithRetry(l.retryAfter, "server is overloaded, try again later").WithCode("overloaded"))
+		return
+	}
+	l.inFlight.Add(1)
+	defer func() {
+		l.inFlight.Add(-1)
+		<-l.sem
+	}()
+	next.ServeHTTP(w, r)
+}
+
+func (l *ConcurrencyLimiter) Stats() LimiterStats {
+	return LimiterStats{
+		InFlight: l.inFlight.Load(),
+		Queued:   l.queued.Load(),
+		Limit:    cap(l.sem),
+		Shed:     l.shed.Load(),
+	}
+}
+
+func (l *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+	if l.wait <= 0 {
+		return false
+	}
+	if n := l.queued.Add(1); l.maxQueue > 0 && n > l.maxQueue {
+		l.queued.Add(-1)
+		return false
+	}
+	defer l.queued.Add(-1)
+
+	timer := CurrentClock().NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timer.C():
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Link is a hypermedia link. Href is absolute once added to a LinkSet.
+type Link struct {
+	Rel    string `json:"-"`
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// LinkSet builds the links of one response. Relative URLs are resolved
+// against the request's ExternalURL, so links stay correct behind proxies
+// when RealIPMiddleware is installed.
+//
+//	links := httpx.Links(r).Self().Rel("next", "?page=3")
+//	links.Apply(w)
+//	return httpx.Respond(w, r, http.StatusOK, Page{Items: items, Links: links})
+//
+// In JSON a LinkSet renders as a HAL-style object keyed by relation.
+type LinkSet struct {
+	r     *http.Request
+	base  *url.URL
+	links []Link
+}
+
+func Links(r *http.Request) *LinkSet {
+	base := ExternalURL(r)
+	base.Path = r.URL.Path
+	base.RawPath = r.URL.RawPath
+	return &LinkSet{r: r, base: base}
+}
+
+// Self adds a "self" link to the current request URL.
+func (l *LinkSet) Self() *LinkSet {
+	return l.Rel("self", l.r.URL.RequestURI())
+}
+
+// Rel adds a link with the given relation.
+func (l *LinkSet) Rel(rel, href string) *LinkSet {
+	return l.Add(Link{Rel: rel, Href: href})
+}
+
+// Add adds a link with extra attributes, resolving its Href.
+func (l *LinkSet) Add(link Link) *LinkSet {
+	if u, err := url.Parse(link.Href); err == nil {
+		link.Href = l.base.ResolveReference(u).String()
+	}
+	l.links = append(l.links, link)
+	return l
+}
+
+// All returns the links in the order they were added.
+func (l *LinkSet) All() []Link {
+	return append([]Link(nil), l.links...)
+}
+
+// Header returns the links as an RFC 8288 Link header value.
+func (l *LinkSet) Header() string {
+	parts := make([]string, 0, len(l.links))
+	for _, link := range l.links {
+		var b strings.Builder
+		b.WriteString("<" + link.Href + `>; rel="` + link.Rel + `"`)
+		if link.Type != "" {
+			b.WriteString(`; type="` + link.Type + `"`)
+		}
+		if link.Title != "" {
+			b.WriteString("; title=" + strconv.Quote(link.Title))
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply adds the links to the response's Link header and, in envelope
+// mode, to the "links" meta.
+func (l *LinkSet) Apply(w http.ResponseWriter) {
+	if len(l.links) > 0 {
+		w.Header().Add("Link", l.Header())
+		SetMeta(l.r.Context(), "links", l)
+	}
+}
+
+// MarshalJSON renders {"rel": {"href": ...}}, using an array for
+// relations that occur more than once.
+func (l *LinkSet) MarshalJSON() ([]byte, error) {
+	byRel := make(map[string][]Link)
+	for _, link := range l.links {
+		byRel[link.Rel] = append(byRel[link.Rel], link)
+	}
+
+	out := make(map[string]interface{}, len(byRel))
+	for rel, links := range byRel {
+		if len(links) == 1 {
+			out[rel] = links[0]
+		} else {
+			out[rel] = links
+		}
+	}
+	return json.Marshal(out)
+}
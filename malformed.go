@@ -0,0 +1,177 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// malformedHeaders follow the status line of the responses net/http writes
+// itself for requests it cannot parse. Handler responses differ: their
+// headers are sorted and carry a Date.
+const malformedHeaders = "\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n"
+
+const tlsHandshakeLog = "http: TLS handshake error from "
+
+type (
+	// MalformedRequestError describes a request net/http rejected before
+	// any handler ran, such as one with oversized headers (431), an invalid
+	// request line or Host header (400), or an unsupported transfer
+	// encoding (501).
+	MalformedRequestError struct {
+		RemoteAddr string
+		// Status is the status net/http answered with, 0 for failed TLS
+		// handshakes, which get no response.
+		Status int
+		Reason string
+	}
+
+	malformedListener struct {
+		net.Listener
+		s *Server
+	}
+
+	malformedConn struct {
+		net.Conn
+		s *Server
+	}
+
+	malformedLog struct {
+		s    *Server
+		next *log.Logger
+	}
+)
+
+func (e *MalformedRequestError) Error() string {
+	if e.Status == 0 {
+		return "malformed request from " + e.RemoteAddr + ": " + e.Reason
+	}
+	return fmt.Sprintf("malformed request from %s: %d %s", e.RemoteAddr, e.Status, e.Reason)
+}
+
+// WithMalformedRequests makes requests that net/http rejects on its own,
+// and which therefore never reach the adapter, visible: each is reported
+// to the adapter's Reporter as a *MalformedRequestError and observed by its
+// OnResponse hooks as a client error, with a request carrying only
+// RemoteAddr. hook, when not nil, additionally receives them, failed TLS
+// handshakes included; those are common with scanners and load balancer
+// probes, so they are neither reported nor observed.
+//
+// Rejections are detected on plaintext connections, h2c included. On TLS
+// connections only handshake failures are seen, through the server's
+// ErrorLog, which keeps logging as before.
+func WithMalformedRequests(hook func(*MalformedRequestError)) ServerOption {
+	return func(s *Server) {
+		s.malformed = true
+		s.onMalformed = hook
+	}
+}
+
+// malformedListener wraps ln to detect rejected requests, and installs
+// the ErrorLog catching failed TLS handshakes.
+func (s *Server) malformedListener(ln net.Listener) net.Listener {
+	s.HTTP.ErrorLog = log.New(&malformedLog{s: s, next: s.HTTP.ErrorLog}, "", 0)
+	if s.HTTP.TLSConfig != nil {
+		// net/http needs the *tls.Conn to negotiate HTTP/2.
+		return ln
+	}
+	return &malformedListener{Listener: ln, s: s}
+}
+
+func (s *Server) rejected(e *MalformedRequestError) {
+	if s.onMalformed != nil {
+		s.onMalformed(e)
+	}
+	if e.Status == 0 || s.Adapter == nil {
+		return
+	}
+
+	req := (&http.Request{
+		URL:        &url.URL{},
+		Header:     http.Header{},
+		RemoteAddr: e.RemoteAddr,
+	}).WithContext(context.Background())
+	a := s.Adapter
+	a.report(req, e)
+	if len(a.onResponse) > 0 {
+		req = a.classified(req, WrapStatus(e, e.Status, e.Reason).WithCode("malformed_request"))
+		for _, hook := range a.onResponse {
+			hook(req, e.Status, 0)
+		}
+	}
+}
+
+func (l *malformedListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &malformedConn{Conn: c, s: l.s}, nil
+}
+
+// Write spots the responses net/http writes directly to the connection for
+// rejected requests, which it does in a single call.
+func (c *malformedConn) Write(b []byte) (int, error) {
+	if e := parseMalformed(b); e != nil {
+		e.RemoteAddr = c.RemoteAddr().String()
+		c.s.rejected(e)
+	}
+	return c.Conn.Write(b)
+}
+
+// CloseWrite keeps net/http's graceful close of TCP connections.
+func (c *malformedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// parseMalformed parses a response net/http wrote for a rejected request,
+// "HTTP/1.1 400 Bad Request<headers>400 Bad Request: reason", returning nil
+// for any other write.
+func parseMalformed(b []byte) *MalformedRequestError {
+	const proto = "HTTP/1.1 "
+	if len(b) < len(proto)+3 || string(b[:len(proto)]) != proto {
+		return nil
+	}
+	i := bytes.Index(b, []byte(malformedHeaders))
+	if i < 0 || bytes.IndexByte(b[:i], '\n') >= 0 {
+		return nil
+	}
+	status, err := strconv.Atoi(string(b[len(proto) : len(proto)+3]))
+	if err != nil {
+		return nil
+	}
+
+	reason := http.StatusText(status)
+	body := string(b[i+len(malformedHeaders):])
+	if _, detail, ok := strings.Cut(body, ": "); ok {
+		reason = detail
+	} else if status == http.StatusNotImplemented {
+		reason = body
+	}
+	return &MalformedRequestError{Status: status, Reason: reason}
+}
+
+// Write forwards log lines to the original ErrorLog and picks out failed
+// TLS handshakes.
+func (l *malformedLog) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+	if rest, ok := strings.CutPrefix(line, tlsHandshakeLog); ok {
+		addr, reason, _ := strings.Cut(rest, ": ")
+		l.s.rejected(&MalformedRequestError{RemoteAddr: addr, Reason: "TLS handshake: " + reason})
+	}
+	if l.next != nil {
+		l.next.Print(line)
+	} else {
+		log.Print(line)
+	}
+	return len(p), nil
+}
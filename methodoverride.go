@@ -0,0 +1,74 @@
+package httpx
+
+import (
+	"cmp"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// MethodOverrideHeader is the header MethodOverride reads by default.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideConfig configures MethodOverride. Zero values select the
+// defaults noted on each field.
+type MethodOverrideConfig struct {
+	// Header defaults to MethodOverrideHeader.
+	Header string
+	// FormField defaults to "_method". It is read from url-encoded and
+	// multipart form bodies only.
+	FormField string
+	// Methods lists the methods a POST may turn into; PUT, PATCH and
+	// DELETE by default.
+	Methods []string
+}
+
+// MethodOverride lets POST requests stand in for the methods HTML forms
+// cannot send. The override comes from the header or, failing that, the
+// form field; other methods are never changed. Overrides outside
+// cfg.Methods are rejected with a 400 AppError (code
+// "invalid_method_override").
+//
+// Install it outside the router so that routing sees the overridden
+// method. Reading the form field parses the body into Request.PostForm.
+func MethodOverride(adapter *HandlerAdapter, cfg MethodOverrideConfig) Middleware {
+	header := cmp.Or(cfg.Header, MethodOverrideHeader)
+	field := cmp.Or(cfg.FormField, "_method")
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method := r.Header.Get(header)
+			if method == "" && isFormBody(r) {
+				method = r.PostFormValue(field)
+			}
+			if method == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if !slices.Contains(methods, method) {
+				adapter.HandleError(w, r, BadRequestError("method override to %q is not allowed", method).
+					WithCode("invalid_method_override"))
+				return
+			}
+			r.Method = method
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isFormBody(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data"
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Chain composes middleware so that the first one is the outermost:
+// Chain(a, b)(h) serves requests as a(b(h)).
+func Chain(mws ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// When applies mw only to requests matching pred; the others go straight
+// to the next handler. mw is instantiated once. What mw declares through
+// DescribeRoute still applies to the route, since some requests reach it.
+//
+//	httpx.Unless(httpx.PathPrefix("/healthz"), httpx.AuthMiddleware(adapter, authn))
+func When(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return &conditionalHandler{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if pred(r) {
+					wrapped.ServeHTTP(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+			}),
+			wrapped: wrapped,
+		}
+	}
+}
+
+// conditionalHandler is the handler of When, describing routes as the
+// middleware it applies does.
+type conditionalHandler struct {
+	http.Handler
+	wrapped http.Handler
+}
+
+func (h *conditionalHandler) describeRoute(info *RouteInfo) string {
+	if d, ok := h.wrapped.(routeDescriber); ok {
+		d.describeRoute(info)
+	}
+	return ""
+}
+
+// Unless applies mw only to requests not matching pred.
+func Unless(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return When(func(r *http.Request) bool { return !pred(r) }, mw)
+}
+
+// PathPrefix matches request paths under any of prefixes, on segment
+// boundaries: "/api" matches "/api" and "/api/users" but not "/apis".
+func PathPrefix(prefixes ...string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, prefix := range prefixes {
+			rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+			if ok && (rest == "" || rest[0] == '/' || strings.HasSuffix(prefix, "/")) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Method matches requests with any of methods.
+func Method(methods ...string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return slices.Contains(methods, r.Method)
+	}
+}
+
+// Header matches requests carrying the header name with value, compared
+// case-insensitively, or with any value if value is empty.
+func Header(name, value string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, v := range r.Header.Values(name) {
+			if value == "" || strings.EqualFold(strings.TrimSpace(v), value) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,168 @@
+package httpx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// Misuse names a handler bug in writing the response. The values are
+// stable and suitable as metric labels.
+type Misuse string
+
+const (
+	// MisuseErrorAfterWrite is a handler returning an error after it
+	// already wrote the response, so the error response cannot be sent.
+	// Wrap the error in *CommittedError when this is intended.
+	MisuseErrorAfterWrite Misuse = "error_after_write"
+	// MisuseWriteAfterReturn is a write to the response after the handler
+	// returned, usually from a goroutine it left running.
+	MisuseWriteAfterReturn Misuse = "write_after_return"
+	// MisuseWriteHeaderTwice is a second WriteHeader call with a final
+	// status; net/http ignores it.
+	MisuseWriteHeaderTwice Misuse = "write_header_twice"
+	// MisuseBodyNotAllowed is a body written with a 204 or 304 status;
+	// net/http rejects it.
+	MisuseBodyNotAllowed Misuse = "body_not_allowed"
+)
+
+// MisuseError describes a detected Misuse. Status is the status already
+// written, if any, and Err the error returned with MisuseErrorAfterWrite.
+type MisuseError struct {
+	Kind   Misuse
+	Status int
+	Err    error
+}
+
+func (e *MisuseError) Error() string {
+	msg := fmt.Sprintf("httpx: response misuse: %s", e.Kind)
+	if e.Status != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.Status)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *MisuseError) Unwrap() error {
+	return e.Err
+}
+
+// OnMisuse registers a hook observing every Misuse detected in handlers
+// served by Handle or Wrap, typically to count them. Registering a hook
+// enables detection. Hooks must be registered before serving.
+func (a *HandlerAdapter) OnMisuse(hook func(r *http.Request, err *MisuseError)) {
+	a.onMisuse = append(a.onMisuse, hook)
+}
+
+// misuseWriter returns a writer detecting Misuse in the handler, or nil if
+// detection is disabled.
+func (a *HandlerAdapter) misuseWriter(w http.ResponseWriter, req *http.Request) *misuseWriter {
+	if !a.PanicOnMisuse && len(a.onMisuse) == 0 {
+		return nil
+	}
+	return &misuseWriter{ResponseWriter: w, adapter: a, req: req}
+}
+
+// misused runs the OnMisuse hooks and, with PanicOnMisuse, panics while the
+// handler is running or reports once it has returned.
+func (a *HandlerAdapter) misused(req *http.Request, err *MisuseError, running bool) {
+	for _, hook := range a.onMisuse {
+		hook(req, err)
+	}
+	if !a.PanicOnMisuse {
+		return
+	}
+	if running {
+		panic(err)
+	}
+	a.report(req, err)
+}
+
+// misuseWriter watches the handler's use of the response. Each Misuse is
+// raised at most once per request.
+type misuseWriter struct {
+	http.ResponseWriter
+	adapter  *HandlerAdapter
+	req      *http.Request
+	status   int
+	done     atomic.Bool
+	reported []Misuse
+}
+
+func (w *misuseWriter) WriteHeader(status int) {
+	w.checkDone()
+	if status < 100 || status > 199 {
+		if w.status != 0 {
+			w.misuse(MisuseWriteHeaderTwice, nil)
+		} else {
+			w.status = status
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *misuseWriter) Write(b []byte) (int, error) {
+	w.checkDone()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if len(b) > 0 && (w.status == http.StatusNoContent || w.status == http.StatusNotModified) {
+		w.misuse(MisuseBodyNotAllowed, nil)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *misuseWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *misuseWriter) FlushError() error {
+	w.checkDone()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *misuseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *misuseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// returned checks err returned by the handler and wraps it in
+// *CommittedError if the response was already written.
+func (w *misuseWriter) returned(err error) error {
+	var committed *CommittedError
+	if w.status == 0 || errors.As(err, &committed) {
+		return err
+	}
+	w.misuse(MisuseErrorAfterWrite, err)
+	return &CommittedError{Err: err}
+}
+
+func (w *misuseWriter) finish() {
+	w.done.Store(true)
+}
+
+func (w *misuseWriter) checkDone() {
+	if w.done.Load() {
+		w.misuse(MisuseWriteAfterReturn, nil)
+	}
+}
+
+func (w *misuseWriter) misuse(kind Misuse, err error) {
+	if slices.Contains(w.reported, kind) {
+		return
+	}
+	w.reported = append(w.reported, kind)
+	w.adapter.misused(w.req, &MisuseError{Kind: kind, Status: w.status, Err: err}, !w.done.Load())
+}
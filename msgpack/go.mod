@@ -0,0 +1,12 @@
+module github.com/radim/httpx/msgpack
+
+go 1.24
+
+require (
+	github.com/radim/httpx v0.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+
+replace github.com/radim/httpx => ../
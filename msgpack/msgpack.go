@@ -0,0 +1,28 @@
+// Package msgpack provides an httpx.Codec for MessagePack bodies.
+package msgpack
+
+import (
+	vmsgpack "github.com/vmihailenco/msgpack/v5"
+
+	"github.com/radim/httpx"
+)
+
+// ContentType is the media type of MessagePack bodies.
+const ContentType = "application/msgpack"
+
+// Codec encodes values with MessagePack, honoring `msgpack` struct tags.
+type Codec struct{}
+
+var _ httpx.Codec = Codec{}
+
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return vmsgpack.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return vmsgpack.Unmarshal(data, v)
+}
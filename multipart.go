@@ -0,0 +1,125 @@
+package httpx
+
+import (
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// DefaultMaxPartSize is the per-part limit applied by Parts unless overridden.
+const DefaultMaxPartSize = 32 << 20
+
+type (
+	// Part is a single multipart form part. Body reads lazily from the
+	// request stream and is only valid until the next iteration step.
+	Part struct {
+		FormName string
+		FileName string
+		Header   textproto.MIMEHeader
+		Body     io.Reader
+	}
+
+	PartsOption func(*partsConfig)
+
+	partsConfig struct {
+		maxPartSize int64
+		maxParts    int
+	}
+
+	limitedPartReader struct {
+		r *multipart.Part
+		n int64
+	}
+)
+
+// WithMaxPartSize limits the number of bytes readable from a single part.
+// Zero or a negative value disables the limit.
+func WithMaxPartSize(n int64) PartsOption {
+	return func(c *partsConfig) {
+		c.maxPartSize = n
+	}
+}
+
+// WithMaxParts limits the number of parts accepted from a single request.
+func WithMaxParts(n int) PartsOption {
+	return func(c *partsConfig) {
+		c.maxParts = n
+	}
+}
+
+func (p Part) IsFile() bool {
+	return p.FileName != ""
+}
+
+// Parts iterates over the multipart parts of r without buffering the form in
+// memory or on disk. Malformed bodies are reported as 400 and exceeded limits
+// as 413 AppErrors; iteration stops after the first error.
+func Parts(r *http.Request, opts ...PartsOption) iter.Seq2[Part, error] {
+	cfg := partsConfig{maxPartSize: DefaultMaxPartSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(yield func(Part, error) bool) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			yield(Part{}, BadRequestError("invalid multipart request: %v", err))
+			return
+		}
+
+		for n := 0; ; n++ {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Part{}, BadRequestError("reading multipart part: %v", err))
+				return
+			}
+
+			if cfg.maxParts > 0 && n >= cfg.maxParts {
+				p.Close()
+				yield(Part{}, StatusError(http.StatusRequestEntityTooLarge, "too many parts (max %d)", cfg.maxParts))
+				return
+			}
+
+			part := Part{
+				FormName: p.FormName(),
+				FileName: p.FileName(),
+				Header:   p.Header,
+				Body:     p,
+			}
+			if cfg.maxPartSize > 0 {
+				part.Body = &limitedPartReader{r: p, n: cfg.maxPartSize}
+			}
+
+			more := yield(part, nil)
+			p.Close()
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+func (l *limitedPartReader) Read(b []byte) (int, error) {
+	if l.n < 0 {
+		return 0, StatusError(http.StatusRequestEntityTooLarge, "multipart part %q exceeds size limit", l.r.FormName())
+	}
+
+	// Allow reading one byte past the limit so an exactly sized part still
+	// terminates with io.EOF rather than an error.
+	if int64(len(b)) > l.n+1 {
+		b = b[:l.n+1]
+	}
+
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		n += int(l.n)
+		return n, StatusError(http.StatusRequestEntityTooLarge, "multipart part %q exceeds size limit", l.r.FormName())
+	}
+	return n, err
+}
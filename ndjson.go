@@ -0,0 +1,197 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const DefaultMaxLineSize = 1 << 20
+
+type (
+	// IngestResult summarizes an NDJSON ingestion. It is written as the
+	// response body: 200 when every line was accepted, 207 otherwise.
+	IngestResult struct {
+		Accepted int               `json:"accepted"`
+		Rejected int               `json:"rejected"`
+		Errors   []IngestLineError `json:"errors,omitempty"`
+		// Aborted is set when ingestion stopped early after too many rejects
+		// or at a line over the size limit.
+		Aborted bool `json:"aborted,omitempty"`
+
+		tooLarge bool
+	}
+
+	IngestLineError struct {
+		Line  int    `json:"line"`
+		Error string `json:"error"`
+		Code  string `json:"code,omitempty"`
+	}
+
+	IngestOption func(*ingestConfig)
+
+	ingestConfig struct {
+		maxLineSize int
+		maxRejected int
+	}
+)
+
+// WithMaxLineSize limits the size of a single NDJSON line. Longer lines abort
+// the request with 413; IngestNDJSON still writes the summary of the lines
+// before it.
+func WithMaxLineSize(n int) IngestOption {
+	return func(c *ingestConfig) {
+		c.maxLineSize = n
+	}
+}
+
+// WithMaxRejected stops ingestion once n lines were rejected.
+func WithMaxRejected(n int) IngestOption {
+	return func(c *ingestConfig) {
+		c.maxRejected = n
+	}
+}
+
+// IngestNDJSON reads the request body line by line, decodes each line into T
+// and passes it to fn. The next line is only read after fn returns, so a slow
+// consumer naturally applies backpressure to the client.
+//
+// Lines that fail to decode, or for which fn returns an AppError, are
+// rejected and reported in the summary with their line number. Any other
+// error from fn aborts the request and is returned for the adapter to handle
+// as an internal error. A line longer than the WithMaxLineSize limit ends
+// ingestion with a 413 whose summary reports it as rejected (code
+// "line_too_large") and still counts the lines passed to fn before it.
+func IngestNDJSON[T any](w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, item T) error, opts ...IngestOption) error {
+	cfg := ingestConfig{maxLineSize: DefaultMaxLineSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	result, err := ingestNDJSON(r, fn, cfg)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	switch {
+	case result.tooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case result.Rejected > 0:
+		status = http.StatusMultiStatus
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return writeJSON(w, result)
+}
+
+func ingestNDJSON[T any](r *http.Request, fn func(ctx context.Context, item T) error, cfg ingestConfig) (*IngestResult, error) {
+	ctx := r.Context()
+	result := &IngestResult{}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, cfg.maxLineSize)), cfg.maxLineSize)
+
+	line := 1
+	for ; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var item T
+		err := currentJSON().Unmarshal(data, &item)
+		if err != nil {
+			err = BadRequestError("invalid JSON: %v", err)
+		} else {
+			err = fn(ctx, item)
+		}
+
+		if err != nil {
+			appErr, ok := err.(AppError)
+			if !ok {
+				return nil, fmt.Errorf("ingesting line %d: %w", line, err)
+			}
+
+			result.Rejected++
+			result.Errors = append(result.Errors, IngestLineError{Line: line, Error: appErr.Error(), Code: appErr.Code})
+			if cfg.maxRejected > 0 && result.Rejected >= cfg.maxRejected {
+				result.Aborted = true
+				return result, nil
+			}
+			continue
+		}
+
+		result.Accepted++
+	}
+
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			result.Rejected++
+			result.Errors = append(result.Errors, IngestLineError{Line: line, Error: fmt.Sprintf("NDJSON line exceeds %d bytes", cfg.maxLineSize), Code: "line_too_large"})
+			result.Aborted = true
+			result.tooLarge = true
+			return result, nil
+		}
+		return nil, fmt.Errorf("reading NDJSON body: %w", err)
+	}
+
+	return result, nil
+}
+
+// BindNDJSON decodes a newline-delimited JSON request body into T one line
+// at a time, passing each item to fn, for bulk endpoints that cannot
+// buffer the whole payload. Unlike IngestNDJSON it stops at the first
+// failure: an undecodable line is a 400 AppError (code "invalid_ndjson")
+// and an AppError from fn is returned with its message prefixed by the line
+// number. Lines longer than the WithMaxLineSize limit, DefaultMaxLineSize
+// by default, are a 413; WithMaxRejected does not apply. Blank lines are
+// skipped.
+//
+//	err := httpx.BindNDJSON(r, func(e Event) error {
+//		return batch.Add(e)
+//	})
+func BindNDJSON[T any](r *http.Request, fn func(item T) error, opts ...IngestOption) error {
+	cfg := ingestConfig{maxLineSize: DefaultMaxLineSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, cfg.maxLineSize)), cfg.maxLineSize)
+
+	line := 1
+	for ; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var item T
+		if err := currentJSON().Unmarshal(data, &item); err != nil {
+			return BadRequestError("line %d: invalid JSON: %v", line, err).WithCode("invalid_ndjson")
+		}
+		if err := fn(item); err != nil {
+			if appErr, ok := err.(AppError); ok {
+				appErr.Err = fmt.Errorf("line %d: %w", line, appErr.Err)
+				return appErr
+			}
+			return fmt.Errorf("binding NDJSON line %d: %w", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return StatusError(http.StatusRequestEntityTooLarge, "line %d: NDJSON line exceeds %d bytes", line, cfg.maxLineSize).WithCode("line_too_large")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIngestNDJSON(t *testing.T) {
+	type event struct {
+		Name string `json:"name"`
+	}
+	long := `{"name":"` + strings.Repeat("x", 64) + `"}`
+
+	tests := []struct {
+		name     string
+		body     string
+		opts     []IngestOption
+		status   int
+		ingested []string
+		want     IngestResult
+	}{
+		{
+			name:     "all accepted",
+			body:     "{\"name\":\"a\"}\n\n{\"name\":\"b\"}\n",
+			status:   http.StatusOK,
+			ingested: []string{"a", "b"},
+			want:     IngestResult{Accepted: 2},
+		},
+		{
+			name:     "rejected lines",
+			body:     "{\"name\":\"a\"}\nnot json\n{\"name\":\"\"}\n",
+			status:   http.StatusMultiStatus,
+			ingested: []string{"a"},
+			want: IngestResult{Accepted: 1, Rejected: 2, Errors: []IngestLineError{
+				{Line: 2, Error: "invalid JSON: invalid character 'o' in literal null (expecting 'u')"},
+				{Line: 3, Error: "name is required", Code: "invalid_event"},
+			}},
+		},
+		{
+			name:     "too many rejects",
+			body:     "nope\n{\"name\":\"a\"}\nnope\n{\"name\":\"b\"}\n",
+			opts:     []IngestOption{WithMaxRejected(2)},
+			status:   http.StatusMultiStatus,
+			ingested: []string{"a"},
+			want: IngestResult{Accepted: 1, Rejected: 2, Aborted: true, Errors: []IngestLineError{
+				{Line: 1, Error: "invalid JSON: invalid character 'o' in literal null (expecting 'u')"},
+				{Line: 3, Error: "invalid JSON: invalid character 'o' in literal null (expecting 'u')"},
+			}},
+		},
+		{
+			name:     "line too large keeps earlier results",
+			body:     "{\"name\":\"a\"}\n{\"name\":\"b\"}\n" + long + "\n{\"name\":\"c\"}\n",
+			opts:     []IngestOption{WithMaxLineSize(32)},
+			status:   http.StatusRequestEntityTooLarge,
+			ingested: []string{"a", "b"},
+			want: IngestResult{Accepted: 2, Rejected: 1, Aborted: true, Errors: []IngestLineError{
+				{Line: 3, Error: "NDJSON line exceeds 32 bytes", Code: "line_too_large"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ingested []string
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tt.body))
+			err := IngestNDJSON(w, r, func(_ context.Context, e event) error {
+				if e.Name == "" {
+					return BadRequestError("name is required").WithCode("invalid_event")
+				}
+				ingested = append(ingested, e.Name)
+				return nil
+			}, tt.opts...)
+			if err != nil {
+				t.Fatalf("IngestNDJSON: %v", err)
+			}
+
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if !reflect.DeepEqual(ingested, tt.ingested) {
+				t.Errorf("ingested %q, want %q", ingested, tt.ingested)
+			}
+			var got IngestResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding summary %q: %v", w.Body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summary %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
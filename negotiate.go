@@ -0,0 +1,127 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type (
+	// NegotiatingRenderer picks the renderer whose media type best matches
+	// the request's Accept header, falling back to the first offer.
+	NegotiatingRenderer struct {
+		Offers []RendererOffer
+	}
+
+	RendererOffer struct {
+		MediaType string
+		Renderer  Renderer
+	}
+)
+
+var _ RequestRenderer = (*NegotiatingRenderer)(nil)
+
+// NegotiateContentType returns the offer best matching the Accept header of
+// r, honoring q-values and preferring specific media ranges over wildcards.
+// It returns the first offer when r has no Accept header, and "" when
+// nothing is acceptable.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the q-value of the most specific media range in
+// the Accept header values matching offer.
+func acceptQuality(accept []string, offer string) float64 {
+	offerType, offerSub, _ := strings.Cut(strings.ToLower(offer), "/")
+
+	q, specificity := 0.0, -1
+	for _, header := range accept {
+		for _, item := range strings.Split(header, ",") {
+			mediaRange, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+			typ, sub, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mediaRange)), "/")
+
+			var s int
+			switch {
+			case typ == offerType && sub == offerSub:
+				s = 2
+			case typ == offerType && sub == "*":
+				s = 1
+			case typ == "*" && sub == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+
+			specificity, q = s, 1.0
+			for _, p := range strings.Split(params, ";") {
+				if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						q = f
+					}
+				}
+			}
+		}
+	}
+	return q
+}
+
+// Render500 and RenderAppError have no request to negotiate with and use
+// the first offer.
+func (n *NegotiatingRenderer) Render500(ctx context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	if len(n.Offers) > 0 {
+		n.Offers[0].Renderer.Render500(ctx, w, errInfo)
+	}
+}
+
+func (n *NegotiatingRenderer) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError) {
+	if len(n.Offers) > 0 {
+		n.Offers[0].Renderer.RenderAppError(ctx, w, appErr)
+	}
+}
+
+func (n *NegotiatingRenderer) Render500Request(w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo) {
+	if rr := n.pick(r); rr != nil {
+		Render500(rr, w, r, errInfo)
+	}
+}
+
+func (n *NegotiatingRenderer) RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr AppError) {
+	if rr := n.pick(r); rr != nil {
+		RenderAppError(rr, w, r, appErr)
+	}
+}
+
+func (n *NegotiatingRenderer) pick(r *http.Request) Renderer {
+	if len(n.Offers) == 0 {
+		return nil
+	}
+	types := make([]string, len(n.Offers))
+	for i, o := range n.Offers {
+		types[i] = o.MediaType
+	}
+	chosen := NegotiateContentType(r, types...)
+	for _, o := range n.Offers {
+		if o.MediaType == chosen {
+			return o.Renderer
+		}
+	}
+	return n.Offers[0].Renderer
+}
@@ -0,0 +1,126 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// TrailingSlashPolicy selects how NormalizePath treats a trailing slash.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashKeep leaves trailing slashes as the client sent them.
+	TrailingSlashKeep TrailingSlashPolicy = iota
+	// TrailingSlashRemove strips the trailing slash of every path but "/".
+	TrailingSlashRemove
+	// TrailingSlashAdd appends a trailing slash to every path.
+	TrailingSlashAdd
+)
+
+// NormalizePathConfig configures NormalizePath.
+type NormalizePathConfig struct {
+	TrailingSlash TrailingSlashPolicy
+	// Redirect answers non-canonical paths with a permanent redirect to the
+	// canonical one (301 for GET and HEAD, 308 otherwise) instead of
+	// rewriting the request in place.
+	Redirect bool
+}
+
+// NormalizePath collapses duplicate slashes, resolves "." and ".."
+// segments and applies the trailing slash policy, so every route needs one
+// registration and each resource has one canonical URL. Escaped slashes
+// ("%2F") are kept as they are. Install it outside the router.
+func NormalizePath(cfg NormalizePathConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			escaped := r.URL.EscapedPath()
+			canonical := canonicalPath(escaped, cfg.TrailingSlash)
+			if canonical == escaped {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.Redirect {
+				code := http.StatusPermanentRedirect
+				if r.Method == http.MethodGet || r.Method == http.MethodHead {
+					code = http.StatusMovedPermanently
+				}
+				target := canonical
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, code)
+				return
+			}
+
+			p, err := url.PathUnescape(canonical)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			u := *r.URL
+			u.Path, u.RawPath = p, ""
+			if u.EscapedPath() != canonical {
+				u.RawPath = canonical
+			}
+			r2 := r.WithContext(r.Context())
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+var dotReplacer = strings.NewReplacer("%2e", ".", "%2E", ".")
+
+// canonicalPath cleans an escaped path and applies policy.
+func canonicalPath(p string, policy TrailingSlashPolicy) string {
+	if isCanonicalPath(p, policy) {
+		return p
+	}
+	if p == "" {
+		return "/"
+	}
+	trailing := strings.HasSuffix(p, "/")
+	// "." is unreserved, so "%2e" is the same character; decode it so that
+	// encoded dot segments are resolved too.
+	p = dotReplacer.Replace(p)
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return p
+	}
+	switch policy {
+	case TrailingSlashKeep:
+		if trailing {
+			p += "/"
+		}
+	case TrailingSlashAdd:
+		p += "/"
+	}
+	return p
+}
+
+// isCanonicalPath reports, without allocating, whether canonicalPath would
+// return p unchanged, as it does for nearly every request.
+func isCanonicalPath(p string, policy TrailingSlashPolicy) bool {
+	if p == "/" {
+		return true
+	}
+	if len(p) < 2 || p[0] != '/' {
+		return false
+	}
+	switch trailing := p[len(p)-1] == '/'; {
+	case policy == TrailingSlashRemove && trailing, policy == TrailingSlashAdd && !trailing:
+		return false
+	}
+	if strings.Contains(p, "//") || strings.Contains(p, "%2e") || strings.Contains(p, "%2E") {
+		return false
+	}
+	for seg := range strings.SplitSeq(p[1:], "/") {
+		if seg == "." || seg == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultOAuthLoginTTL is how long a started login may take before its
+// callback is rejected.
+const DefaultOAuthLoginTTL = 10 * time.Minute
+
+const sessionOAuthLogin = "_oauth"
+
+type (
+	// OAuthLogin implements the OAuth 2.0 authorization-code flow with PKCE,
+	// and OpenID Connect when IDToken is set, on top of SessionMiddleware.
+	// Mount Login and Callback as HTTPHandlerExt routes:
+	//
+	//	login := &httpx.OAuthLogin{
+	//		ClientID:     id,
+	//		ClientSecret: secret,
+	//		AuthURL:      "https://accounts.example.com/authorize",
+	//		TokenURL:     "https://accounts.example.com/token",
+	//		RedirectURL:  "https://app.example.com/auth/callback",
+	//		Scopes:       []string{"openid", "email"},
+	//		IDToken: &httpx.JWTAuthenticator{
+	//			Keys:     httpx.NewJWKS("https://accounts.example.com/jwks").Key,
+	//			Issuer:   "https://accounts.example.com",
+	//			Audience: id,
+	//		},
+	//	}
+	//	g.HandleExt("GET /auth/login", login.Login)
+	//	g.HandleExt("GET /auth/callback", login.Callback)
+	//
+	// The resulting Principal is stored with LoginSession, so
+	// SessionAuthenticator resolves it on later requests.
+	OAuthLogin struct {
+		ClientID     string
+		ClientSecret string
+		// AuthURL is the provider's authorization endpoint.
+		AuthURL string
+		// TokenURL is the provider's token endpoint, resolved against
+		// Client's base URL.
+		TokenURL string
+		// RedirectURL is the absolute URL of the Callback route, as
+		// registered with the provider.
+		RedirectURL string
+		Scopes      []string
+		// AuthParams are added to the authorization URL, e.g. "prompt".
+		AuthParams url.Values
+
+		// Client sends token requests. It defaults to a client without
+		// base URL.
+		Client *Client
+		// IDToken verifies the ID token of OpenID Connect providers; set
+		// its Audience to ClientID. The token's nonce is checked against
+		// the one sent with the login. Nil for plain OAuth 2.0.
+		IDToken *JWTAuthenticator
+		// Principal builds the Principal of a completed login. It defaults
+		// to one built from the ID token claims; plain OAuth 2.0 logins
+		// must set it, e.g. to fetch a user info endpoint.
+		Principal func(ctx context.Context, tok *OAuthToken) (*Principal, error)
+		// DefaultReturnTo is where Callback redirects when the login did
+		// not name a page. It defaults to "/".
+		DefaultReturnTo string
+		// TTL bounds the time between Login and Callback. It defaults to
+		// DefaultOAuthLoginTTL.
+		TTL time.Duration
+	}
+
+	// OAuthToken is the token endpoint response of a completed login.
+	OAuthToken struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		// ExpiresIn is the access token lifetime in seconds.
+		ExpiresIn int    `json:"expires_in,omitempty"`
+		Scope     string `json:"scope,omitempty"`
+		IDToken   string `json:"id_token,omitempty"`
+		// Claims holds the verified ID token claims when IDToken is set.
+		Claims *JWTClaims `json:"-"`
+	}
+
+	// oauthLogin is the pending login kept in the session between Login
+	// and Callback.
+	oauthLogin struct {
+		State    string    `json:"state"`
+		Nonce    string    `json:"nonce"`
+		Verifier string    `json:"verifier"`
+		ReturnTo string    `json:"return_to,omitempty"`
+		Expires  time.Time `json:"expires"`
+	}
+)
+
+// Login starts a login: it records a fresh state, nonce and PKCE verifier in
+// the session and redirects to the provider. A local path in the
+// "return_to" query parameter is where Callback sends the user afterwards.
+func (o *OAuthLogin) Login(w http.ResponseWriter, r *http.Request) error {
+	s, ok := SessionFromContext(r.Context())
+	if !ok {
+		return ErrNoSession
+	}
+	ttl := o.TTL
+	if ttl <= 0 {
+		ttl = DefaultOAuthLoginTTL
+	}
+	pending := oauthLogin{
+		State:    randomHex(32),
+		Nonce:    randomHex(32),
+		Verifier: randomHex(32),
+		Expires:  clockNow().Add(ttl),
+	}
+	if returnTo := r.URL.Query().Get("return_to"); localPath(returnTo) {
+		pending.ReturnTo = returnTo
+	}
+	if err := s.Set(sessionOAuthLogin, pending); err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, o.AuthCodeURL(pending.State, pending.Nonce, pending.Verifier), http.StatusFound)
+	return nil
+}
+
+// AuthCodeURL returns the authorization URL for a login with the given
+// state, nonce and PKCE verifier. The nonce is omitted when empty.
+func (o *OAuthLogin) AuthCodeURL(state, nonce, verifier string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.ClientID},
+		"redirect_uri":          {o.RedirectURL},
+		"state":                 {state},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if len(o.Scopes) > 0 {
+		q.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if nonce != "" {
+		q.Set("nonce", nonce)
+	}
+	for k, vs := range o.AuthParams {
+		q[k] = append(q[k], vs...)
+	}
+
+	sep := "?"
+	if strings.Contains(o.AuthURL, "?") {
+		sep = "&"
+	}
+	return o.AuthURL + sep + q.Encode()
+}
+
+// Callback completes a login started by Login: it validates the state,
+// exchanges the code, verifies the ID token and its nonce, stores the
+// Principal with LoginSession and redirects to the page the login was
+// started from.
+//
+// Missing, expired or mismatched logins get a 400 AppError (code
+// "invalid_oauth_state"); errors sent back by the provider, such as a
+// denied consent, a 401 AppError with code "oauth_" followed by the
+// provider's error code.
+func (o *OAuthLogin) Callback(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	s, ok := SessionFromContext(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	var pending oauthLogin
+	err := s.Decode(sessionOAuthLogin, &pending)
+	s.Delete(sessionOAuthLogin)
+
+	q := r.URL.Query()
+	if err != nil || pending.State == "" || !secureCompare(q.Get("state"), pending.State) {
+		return BadRequestError("invalid login state").WithCode("invalid_oauth_state")
+	}
+	if clockNow().After(pending.Expires) {
+		return BadRequestError("login expired").WithCode("invalid_oauth_state")
+	}
+	if code := q.Get("error"); code != "" {
+		msg := q.Get("error_description")
+		if msg == "" {
+			msg = code
+		}
+		return UnauthorizedError("login failed: %s", msg).WithCode("oauth_" + code)
+	}
+
+	tok, err := o.Exchange(ctx, q.Get("code"), pending.Verifier)
+	if err != nil {
+		return err
+	}
+	if o.IDToken != nil {
+		if tok.IDToken == "" {
+			return UnauthorizedError("token response has no ID token").WithCode("invalid_id_token")
+		}
+		claims, err := o.IDToken.Verify(ctx, tok.IDToken)
+		if err != nil {
+			return err
+		}
+		var nonce string
+		if claims.Decode("nonce", &nonce) != nil || !secureCompare(nonce, pending.Nonce) {
+			return UnauthorizedError("ID token nonce mismatch").WithCode("invalid_id_token")
+		}
+		tok.Claims = claims
+	}
+
+	p, err := o.principal(ctx, tok)
+	if err != nil {
+		return err
+	}
+	if err := LoginSession(ctx, p); err != nil {
+		return err
+	}
+
+	returnTo := pending.ReturnTo
+	if returnTo == "" {
+		returnTo = o.DefaultReturnTo
+	}
+	if returnTo == "" {
+		returnTo = "/"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, returnTo, http.StatusFound)
+	return nil
+}
+
+// Exchange trades an authorization code for tokens at TokenURL, sending
+// the client credentials in the form body. Provider errors come back as
+// AppErrors with the provider's status, as from any Client request.
+func (o *OAuthLogin) Exchange(ctx context.Context, code, verifier string) (*OAuthToken, error) {
+	if code == "" {
+		return nil, BadRequestError("missing authorization code").WithCode("invalid_oauth_state")
+	}
+	client := o.Client
+	if client == nil {
+		var err error
+		if client, err = NewClient(""); err != nil {
+			return nil, err
+		}
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.RedirectURL},
+		"client_id":     {o.ClientID},
+		"code_verifier": {verifier},
+	}
+	if o.ClientSecret != "" {
+		form.Set("client_secret", o.ClientSecret)
+	}
+
+	var tok OAuthToken
+	err := client.Post(o.TokenURL).
+		Header("Content-Type", "application/x-www-form-urlencoded").
+		Header("Accept", "application/json").
+		Body(strings.NewReader(form.Encode())).
+		Do(ctx, &tok)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("exchanging authorization code: response has no access token")
+	}
+	return &tok, nil
+}
+
+func (o *OAuthLogin) principal(ctx context.Context, tok *OAuthToken) (*Principal, error) {
+	if o.Principal != nil {
+		return o.Principal(ctx, tok)
+	}
+	if tok.Claims == nil {
+		return nil, errors.New("httpx: OAuthLogin needs Principal without IDToken")
+	}
+	p := &Principal{Subject: tok.Claims.Subject, Attributes: map[string]interface{}{"issuer": tok.Claims.Issuer}}
+	for _, name := range []string{"email", "name"} {
+		var v string
+		if tok.Claims.Decode(name, &v) == nil && v != "" {
+			p.Attributes[name] = v
+		}
+	}
+	return p, nil
+}
+
+// pkceChallenge derives the S256 code challenge of verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// localPath reports whether p is a path on this site, so redirecting to
+// it cannot send the user elsewhere.
+func localPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
@@ -0,0 +1,463 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// OpenAPIInfo describes the API in a generated OpenAPI document.
+	OpenAPIInfo struct {
+		Title       string
+		Version     string
+		Description string
+		// Servers lists base URLs; when empty the document omits them.
+		Servers []string
+		// SecuritySchemes declares the schemes named in requirements passed
+		// to Secure, keyed by scheme name.
+		SecuritySchemes map[string]SecurityScheme
+		// ErrorBody is an example error response value; its type documents
+		// error responses. When nil they are documented as plain objects.
+		ErrorBody interface{}
+	}
+
+	// SecurityScheme is an OpenAPI security scheme object.
+	SecurityScheme struct {
+		Type         string `json:"type"`
+		Scheme       string `json:"scheme,omitempty"`
+		BearerFormat string `json:"bearerFormat,omitempty"`
+		In           string `json:"in,omitempty"`
+		Name         string `json:"name,omitempty"`
+		Description  string `json:"description,omitempty"`
+	}
+
+	// OperationOption documents a typed route and, for Secure, WithTimeout,
+	// WithMaxBody and WithRequiredScopes, installs what it documents.
+	OperationOption func(*operation)
+
+	operation struct {
+		method, path string
+		pathParams   []string
+		summary      string
+		description  string
+		operationID  string
+		tags         []string
+		deprecated   bool
+		errors       []int
+		security     []SecurityRequirement
+		in, out      reflect.Type
+		middleware   []Middleware
+		timeout      time.Duration
+		maxBody      int64
+		scopes       []string
+	}
+
+	// openAPIRegistry collects the operations of a group tree.
+	openAPIRegistry struct {
+		mu  sync.Mutex
+		ops []*operation
+	}
+)
+
+func WithSummary(summary string) OperationOption {
+	return func(op *operation) { op.summary = summary }
+}
+
+func WithDescription(description string) OperationOption {
+	return func(op *operation) { op.description = description }
+}
+
+func WithOperationID(id string) OperationOption {
+	return func(op *operation) { op.operationID = id }
+}
+
+func WithTags(tags ...string) OperationOption {
+	return func(op *operation) { op.tags = append(op.tags, tags...) }
+}
+
+func Deprecated() OperationOption {
+	return func(op *operation) { op.deprecated = true }
+}
+
+// WithErrors declares the error statuses the handler returns.
+func WithErrors(statuses ...int) OperationOption {
+	return func(op *operation) { op.errors = append(op.errors, statuses...) }
+}
+
+// Secure guards the route with sec.Enforce(reqs...) and documents the
+// requirements, so the document matches what is enforced.
+func Secure(sec *Security, reqs ...SecurityRequirement) OperationOption {
+	return func(op *operation) {
+		op.security = append(op.security, reqs...)
+		op.middleware = append(op.middleware, sec.Enforce(reqs...))
+	}
+}
+
+// WithTimeout bounds the route's requests to d, see RequestTimeout;
+// clients may ask for less. The 504 is documented.
+func WithTimeout(d time.Duration) OperationOption {
+	return func(op *operation) { op.timeout = d }
+}
+
+// WithMaxBody limits the route's request bodies to n bytes, see
+// BodyLimit. The 413 is documented.
+func WithMaxBody(n int64) OperationOption {
+	return func(op *operation) { op.maxBody = n }
+}
+
+// WithRequiredScopes guards the route with RequireScopes, checking the
+// Principal set by the group's authentication middleware or by Secure.
+// The 401 and 403 are documented, along with the scopes as
+// "x-required-scopes".
+func WithRequiredScopes(scopes ...string) OperationOption {
+	return func(op *operation) { op.scopes = append(op.scopes, scopes...) }
+}
+
+// HandleTyped registers fn on g like g.HandleExt(pattern, Typed(fn)) and
+// records it for g.OpenAPI, with request and response schemas reflected
+// from In and Out. Patterns without a method are documented as GET when
+// In is struct{} and as POST otherwise.
+func HandleTyped[In, Out any](g *Group, pattern string, fn func(ctx context.Context, in In) (Out, error), opts ...OperationOption) {
+	op := newOperation(g.pattern(pattern), reflect.TypeFor[In](), reflect.TypeFor[Out]())
+	for _, opt := range opts {
+		opt(op)
+	}
+	g.api.add(op)
+
+	mws := append([]Middleware(nil), op.middleware...)
+	if len(op.scopes) > 0 {
+		mws = append(mws, RequireScopes(g.adapter, op.scopes...))
+	}
+	if op.timeout > 0 {
+		mws = append(mws, RequestTimeout(g.adapter, RequestTimeoutConfig{Default: op.timeout, Max: op.timeout}))
+	}
+	var handleOpts []HandleOption
+	if op.maxBody > 0 {
+		handleOpts = append(handleOpts, WithHandleMaxBodyBytes(op.maxBody))
+	}
+	g.Handle(pattern, Chain(mws...)(g.adapter.Handle(Typed(fn), handleOpts...)))
+}
+
+// OpenAPI generates an OpenAPI 3.0 document for the typed routes
+// registered on g and every group sharing its root.
+func (g *Group) OpenAPI(info OpenAPIInfo) map[string]interface{} {
+	return g.api.document(info)
+}
+
+// ServeOpenAPI serves the document as JSON at pattern, for example
+// "GET /openapi.json". It is generated on each request, so routes
+// registered later are included.
+func (g *Group) ServeOpenAPI(pattern string, info OpenAPIInfo) {
+	g.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(g.OpenAPI(info))
+	}))
+}
+
+var pathParamRe = regexp.MustCompile(`\{([^}]*)\}`)
+
+func newOperation(pattern string, in, out reflect.Type) *operation {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+	path = strings.TrimLeft(path, " \t")
+	// Drop a host prefix; OpenAPI paths start at the first slash.
+	if i := strings.Index(path, "/"); i > 0 {
+		path = path[i:]
+	}
+
+	op := &operation{in: in, out: out}
+	op.path = pathParamRe.ReplaceAllStringFunc(path, func(m string) string {
+		name := strings.TrimSuffix(m[1:len(m)-1], "...")
+		if name == "$" {
+			return ""
+		}
+		op.pathParams = append(op.pathParams, name)
+		return "{" + name + "}"
+	})
+
+	op.method = strings.ToLower(method)
+	if op.method == "" {
+		op.method = "post"
+		if in == reflect.TypeFor[struct{}]() {
+			op.method = "get"
+		}
+	}
+	return op
+}
+
+func (reg *openAPIRegistry) add(op *operation) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.ops = append(reg.ops, op)
+}
+
+func (reg *openAPIRegistry) document(info OpenAPIInfo) map[string]interface{} {
+	reg.mu.Lock()
+	ops := append([]*operation(nil), reg.ops...)
+	reg.mu.Unlock()
+
+	s := &schemaBuilder{components: map[string]interface{}{}, seen: map[reflect.Type]string{}}
+	var errorSchema interface{} = map[string]interface{}{"type": "object"}
+	if info.ErrorBody != nil {
+		errorSchema = s.schema(reflect.TypeOf(info.ErrorBody))
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, op := range ops {
+		item := paths[op.path]
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.path] = item
+		}
+		item[op.method] = op.document(s, errorSchema)
+	}
+
+	docInfo := map[string]interface{}{
+		"title":   orDefault(info.Title, "API"),
+		"version": orDefault(info.Version, "0.0.0"),
+	}
+	if info.Description != "" {
+		docInfo["description"] = info.Description
+	}
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    docInfo,
+		"paths":   paths,
+	}
+	if len(info.Servers) > 0 {
+		servers := make([]map[string]string, len(info.Servers))
+		for i, u := range info.Servers {
+			servers[i] = map[string]string{"url": u}
+		}
+		doc["servers"] = servers
+	}
+	components := map[string]interface{}{}
+	if len(s.components) > 0 {
+		components["schemas"] = s.components
+	}
+	if len(info.SecuritySchemes) > 0 {
+		components["securitySchemes"] = info.SecuritySchemes
+	}
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+	return doc
+}
+
+func (op *operation) document(s *schemaBuilder, errorSchema interface{}) map[string]interface{} {
+	doc := map[string]interface{}{}
+	if op.summary != "" {
+		doc["summary"] = op.summary
+	}
+	if op.description != "" {
+		doc["description"] = op.description
+	}
+	if op.operationID != "" {
+		doc["operationId"] = op.operationID
+	}
+	if len(op.tags) > 0 {
+		doc["tags"] = op.tags
+	}
+	if op.deprecated {
+		doc["deprecated"] = true
+	}
+
+	if len(op.pathParams) > 0 {
+		params := make([]map[string]interface{}, len(op.pathParams))
+		for i, name := range op.pathParams {
+			params[i] = map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			}
+		}
+		doc["parameters"] = params
+	}
+
+	empty := reflect.TypeFor[struct{}]()
+	if op.in != empty && op.method != "get" && op.method != "head" {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": s.schema(op.in)}},
+		}
+	}
+
+	responses := map[string]interface{}{}
+	if op.out == empty {
+		responses["204"] = map[string]string{"description": "No Content"}
+	} else {
+		responses["200"] = map[string]interface{}{
+			"description": "OK",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": s.schema(op.out)}},
+		}
+	}
+	errors := append([]int(nil), op.errors...)
+	if len(op.scopes) > 0 {
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+		doc["x-required-scopes"] = op.scopes
+	}
+	if op.maxBody > 0 {
+		errors = append(errors, http.StatusRequestEntityTooLarge)
+		doc["x-max-body-bytes"] = op.maxBody
+	}
+	if op.timeout > 0 {
+		errors = append(errors, http.StatusGatewayTimeout)
+		doc["x-timeout"] = op.timeout.String()
+	}
+	sort.Ints(errors)
+	errors = slices.Compact(errors)
+	for _, status := range errors {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}},
+		}
+	}
+	doc["responses"] = responses
+
+	if len(op.security) > 0 {
+		security := make([]map[string][]string, len(op.security))
+		for i, req := range op.security {
+			security[i] = map[string][]string{}
+			if req.Scheme != "" {
+				security[i][req.Scheme] = append([]string{}, req.Scopes...)
+			}
+		}
+		doc["security"] = security
+	}
+	return doc
+}
+
+// schemaBuilder reflects Go types into JSON schemas, registering named
+// struct types as components.
+type schemaBuilder struct {
+	components map[string]interface{}
+	seen       map[reflect.Type]string
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+func (s *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() != reflect.Struct && t.Implements(marshalerType):
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name, ok := s.seen[t]
+		if !ok {
+			name = s.componentName(t)
+			s.seen[t] = name
+			s.components[name] = s.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (s *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	s.fields(t, props, &required)
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *schemaBuilder) fields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// componentName derives a schema name from t, qualifying it with the
+// package name when two types share a name.
+func (s *schemaBuilder) componentName(t reflect.Type) string {
+	clean := func(n string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+				return r
+			}
+			return '_'
+		}, n)
+	}
+	name := clean(t.Name())
+	if _, taken := s.components[name]; taken {
+		pkg := t.PkgPath()
+		name = clean(pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name())
+	}
+	return name
+}
@@ -0,0 +1,532 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPISpec is a parsed OpenAPI 3 document used to validate requests.
+// Only the JSON form is supported; convert YAML specs at build time.
+type OpenAPISpec struct {
+	doc    map[string]interface{}
+	routes []specRoute
+	// MaxBody bounds request bodies read for validation; 10 MiB if zero.
+	MaxBody int64
+
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+type specRoute struct {
+	segments []string // literal segments, or "" for a template parameter
+	names    []string // parameter names, by segment index
+	item     map[string]interface{}
+}
+
+// LoadOpenAPI parses an OpenAPI 3 JSON document. Paths are matched below
+// the path of the first server URL, if any.
+func LoadOpenAPI(data []byte) (*OpenAPISpec, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("httpx: parsing OpenAPI document: %w", err)
+	}
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("httpx: unsupported OpenAPI version %q", v)
+	}
+
+	base := ""
+	if servers, _ := doc["servers"].([]interface{}); len(servers) > 0 {
+		if s, _ := servers[0].(map[string]interface{}); s != nil {
+			if u, err := url.Parse(fmt.Sprint(s["url"])); err == nil {
+				base = strings.TrimSuffix(u.Path, "/")
+			}
+		}
+	}
+
+	spec := &OpenAPISpec{doc: doc, patterns: map[string]*regexp.Regexp{}}
+	paths, _ := doc["paths"].(map[string]interface{})
+	for path, item := range paths {
+		item, _ := item.(map[string]interface{})
+		route := specRoute{item: item}
+		for _, seg := range strings.Split(strings.Trim(base+path, "/"), "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				route.segments = append(route.segments, "")
+				route.names = append(route.names, seg[1:len(seg)-1])
+			} else {
+				route.segments = append(route.segments, seg)
+				route.names = append(route.names, "")
+			}
+		}
+		spec.routes = append(spec.routes, route)
+	}
+	// Prefer literal segments over templates, as OpenAPI requires.
+	sort.SliceStable(spec.routes, func(i, j int) bool {
+		return literalCount(spec.routes[i]) > literalCount(spec.routes[j])
+	})
+	return spec, nil
+}
+
+func literalCount(r specRoute) int {
+	n := 0
+	for _, s := range r.segments {
+		if s != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidateOpenAPI checks requests against spec before they reach the
+// handler. Parameter violations are rejected as a 400 AppError (code
+// "invalid_parameters") and body violations as 422 (code
+// "validation_failed"); both carry a ValidationError listing every failed
+// constraint. Requests for paths or methods the spec does not describe are
+// passed through.
+func ValidateOpenAPI(adapter *HandlerAdapter, spec *OpenAPISpec) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, params, ok := spec.match(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := spec.validate(r, op, params); err != nil {
+				adapter.HandleError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *OpenAPISpec) match(r *http.Request) (map[string]interface{}, specParams, bool) {
+	segs := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, route := range s.routes {
+		if len(route.segments) != len(segs) {
+			continue
+		}
+		pathValues := map[string]string{}
+		matched := true
+		for i, seg := range route.segments {
+			if seg == "" {
+				pathValues[route.names[i]] = segs[i]
+			} else if seg != segs[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		op, _ := s.resolve(route.item[strings.ToLower(r.Method)]).(map[string]interface{})
+		if op == nil {
+			return nil, specParams{}, false
+		}
+		params := specParams{path: pathValues}
+		params.defs = append(s.list(route.item["parameters"]), s.list(op["parameters"])...)
+		return op, params, true
+	}
+	return nil, specParams{}, false
+}
+
+type specParams struct {
+	path map[string]string
+	defs []interface{}
+}
+
+func (s *OpenAPISpec) validate(r *http.Request, op map[string]interface{}, params specParams) error {
+	var paramErrs ValidationError
+	// Operation-level parameters override path-level ones of the same name.
+	seen := map[string]bool{}
+	for i := len(params.defs) - 1; i >= 0; i-- {
+		p, _ := s.resolve(params.defs[i]).(map[string]interface{})
+		if p == nil {
+			continue
+		}
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		if seen[in+"\x00"+name] {
+			continue
+		}
+		seen[in+"\x00"+name] = true
+		s.validateParam(r, p, name, in, params.path, &paramErrs)
+	}
+	if len(paramErrs.Fields) > 0 {
+		return AppError{
+			Err:        &paramErrs,
+			StatusCode: http.StatusBadRequest,
+			Code:       "invalid_parameters",
+		}
+	}
+
+	body, _ := s.resolve(op["requestBody"]).(map[string]interface{})
+	if body == nil {
+		return nil
+	}
+	return s.validateBody(r, body)
+}
+
+func (s *OpenAPISpec) validateParam(r *http.Request, p map[string]interface{}, name, in string, path map[string]string, errs *ValidationError) {
+	var values []string
+	switch in {
+	case "path":
+		if v, ok := path[name]; ok {
+			if u, err := url.PathUnescape(v); err == nil {
+				v = u
+			}
+			values = []string{v}
+		}
+	case "query":
+		values = r.URL.Query()[name]
+	case "header":
+		values = r.Header.Values(name)
+	case "cookie":
+		if c, err := r.Cookie(name); err == nil {
+			values = []string{c.Value}
+		}
+	default:
+		return
+	}
+
+	field := in + "." + name
+	if len(values) == 0 {
+		if required, _ := p["required"].(bool); required || in == "path" {
+			errs.Fields = append(errs.Fields, FieldError{Field: field, Message: "is required", Code: "required"})
+		}
+		return
+	}
+	schema, _ := s.resolve(p["schema"]).(map[string]interface{})
+	if schema == nil {
+		return
+	}
+	v, ok := coerceParam(values, schema, s)
+	if !ok {
+		errs.Fields = append(errs.Fields, FieldError{
+			Field: field, Message: fmt.Sprintf("must be of type %s", schema["type"]), Code: "type",
+		})
+		return
+	}
+	s.validateValue(v, schema, field, errs)
+}
+
+// coerceParam converts the string form of a parameter to the JSON value
+// its schema describes.
+func coerceParam(values []string, schema map[string]interface{}, s *OpenAPISpec) (interface{}, bool) {
+	typ, _ := schema["type"].(string)
+	if typ == "array" {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items, _ := s.resolve(schema["items"]).(map[string]interface{})
+		out := make([]interface{}, len(values))
+		for i, v := range values {
+			c, ok := coerceScalar(v, items)
+			if !ok {
+				return nil, false
+			}
+			out[i] = c
+		}
+		return out, true
+	}
+	return coerceScalar(values[0], schema)
+}
+
+func coerceScalar(v string, schema map[string]interface{}) (interface{}, bool) {
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "integer":
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(v), true
+	case "number":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(v), true
+	case "boolean":
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return v, true
+}
+
+func (s *OpenAPISpec) validateBody(r *http.Request, body map[string]interface{}) error {
+	content, _ := body["content"].(map[string]interface{})
+	required, _ := body["required"].(bool)
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		if required {
+			return BadRequestError("request body is required").WithCode("invalid_body")
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		if types := mapKeys(content); len(types) > 0 && !slices.Contains(types, "*/*") {
+			return StatusError(http.StatusUnsupportedMediaType,
+				"Content-Type must be one of %s", strings.Join(types, ", ")).WithCode("unsupported_media_type")
+		}
+		return nil
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		// Only JSON bodies are validated against their schema.
+		return nil
+	}
+	schema, _ := s.resolve(media["schema"]).(map[string]interface{})
+	if schema == nil {
+		return nil
+	}
+
+	maxBody := s.MaxBody
+	if maxBody <= 0 {
+		maxBody = 10 << 20
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxBody {
+		return StatusError(http.StatusRequestEntityTooLarge, "request body too large").WithCode("body_too_large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return BadRequestError("malformed JSON body").WithCode("invalid_body")
+	}
+	var errs ValidationError
+	s.validateValue(v, schema, "body", &errs)
+	if len(errs.Fields) > 0 {
+		return ValidationFailed(errs.Fields...)
+	}
+	return nil
+}
+
+// validateValue checks v against a JSON schema in the OpenAPI 3.0 dialect,
+// appending one FieldError per failed constraint.
+func (s *OpenAPISpec) validateValue(v interface{}, schema map[string]interface{}, field string, errs *ValidationError) {
+	fail := func(code, format string, args ...interface{}) {
+		errs.Fields = append(errs.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...), Code: code})
+	}
+
+	if v == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable && schema["type"] != nil {
+			fail("type", "must not be null")
+		}
+		return
+	}
+
+	for _, sub := range s.list(schema["allOf"]) {
+		if sub, _ := s.resolve(sub).(map[string]interface{}); sub != nil {
+			s.validateValue(v, sub, field, errs)
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		alts := s.list(schema[key])
+		if len(alts) == 0 {
+			continue
+		}
+		matches := 0
+		for _, sub := range alts {
+			if sub, _ := s.resolve(sub).(map[string]interface{}); sub != nil {
+				var e ValidationError
+				s.validateValue(v, sub, field, &e)
+				if len(e.Fields) == 0 {
+					matches++
+				}
+			}
+		}
+		if matches == 0 || (key == "oneOf" && matches > 1) {
+			fail(key, "must match %s one of the allowed schemas", map[string]string{"oneOf": "exactly", "anyOf": "at least"}[key])
+		}
+	}
+
+	if enum := s.list(schema["enum"]); len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of %v", enum)
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("type", "must be an object")
+			return
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range s.list(schema["required"]) {
+			if _, ok := obj[fmt.Sprint(name)]; !ok {
+				errs.Fields = append(errs.Fields, FieldError{Field: field + "." + fmt.Sprint(name), Message: "is required", Code: "required"})
+			}
+		}
+		for _, name := range mapKeys(obj) {
+			child := field + "." + name
+			if ps, ok := props[name]; ok {
+				if ps, _ := s.resolve(ps).(map[string]interface{}); ps != nil {
+					if ro, _ := ps["readOnly"].(bool); ro {
+						errs.Fields = append(errs.Fields, FieldError{Field: child, Message: "is read-only", Code: "read_only"})
+						continue
+					}
+					s.validateValue(obj[name], ps, child, errs)
+				}
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					errs.Fields = append(errs.Fields, FieldError{Field: child, Message: "is not allowed", Code: "additional_property"})
+				}
+			case map[string]interface{}:
+				if ap, _ := s.resolve(ap).(map[string]interface{}); ap != nil {
+					s.validateValue(obj[name], ap, child, errs)
+				}
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("type", "must be an array")
+			return
+		}
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < n {
+			fail("min_items", "must have at least %v items", n)
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > n {
+			fail("max_items", "must have at most %v items", n)
+		}
+		if items, _ := s.resolve(schema["items"]).(map[string]interface{}); items != nil {
+			for i, item := range arr {
+				s.validateValue(item, items, fmt.Sprintf("%s[%d]", field, i), errs)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("type", "must be a string")
+			return
+		}
+		n := float64(len([]rune(str)))
+		if min, ok := schemaNumber(schema, "minLength"); ok && n < min {
+			fail("min_length", "must be at least %v characters", min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && n > max {
+			fail("max_length", "must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re := s.regexp(pattern); re != nil && !re.MatchString(str) {
+				fail("pattern", "must match %s", pattern)
+			}
+		}
+	case "integer", "number", "boolean":
+		if typ == "boolean" {
+			if _, ok := v.(bool); !ok {
+				fail("type", "must be a boolean")
+			}
+			return
+		}
+		num, ok := v.(json.Number)
+		if !ok {
+			fail("type", "must be a %s", typ)
+			return
+		}
+		f, err := num.Float64()
+		if err != nil || (typ == "integer" && f != math.Trunc(f)) {
+			fail("type", "must be an integer")
+			return
+		}
+		exclMin, _ := schema["exclusiveMinimum"].(bool)
+		exclMax, _ := schema["exclusiveMaximum"].(bool)
+		if min, ok := schemaNumber(schema, "minimum"); ok && (f < min || exclMin && f == min) {
+			fail("minimum", "must be greater than %s%v", map[bool]string{false: "or equal to ", true: ""}[exclMin], min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && (f > max || exclMax && f == max) {
+			fail("maximum", "must be less than %s%v", map[bool]string{false: "or equal to ", true: ""}[exclMax], max)
+		}
+		if m, ok := schemaNumber(schema, "multipleOf"); ok && m > 0 && math.Mod(f, m) != 0 {
+			fail("multiple_of", "must be a multiple of %v", m)
+		}
+	}
+}
+
+// resolve follows local $ref pointers such as "#/components/schemas/Pet".
+func (s *OpenAPISpec) resolve(node interface{}) interface{} {
+	for depth := 0; depth < 32; depth++ {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		var cur interface{} = s.doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+			obj, _ := cur.(map[string]interface{})
+			cur = obj[part]
+		}
+		node = cur
+	}
+	return nil
+}
+
+func (s *OpenAPISpec) list(node interface{}) []interface{} {
+	l, _ := node.([]interface{})
+	return l
+}
+
+func (s *OpenAPISpec) regexp(pattern string) *regexp.Regexp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	re, ok := s.patterns[pattern]
+	if !ok {
+		re, _ = regexp.Compile(pattern)
+		s.patterns[pattern] = re
+	}
+	return re
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
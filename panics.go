@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+type (
+	// PanicError is a recovered panic converted into an error.
+	PanicError struct {
+		Value interface{}
+		Stack []byte
+	}
+
+	// ItemPanicPolicy controls how PanicSafe treats panics raised by per-item
+	// callbacks.
+	ItemPanicPolicy struct {
+		// FailFast aborts the request on the first panic.
+		FailFast bool
+		// MaxPanics is the per-request panic budget. Once more than MaxPanics
+		// items panicked the request is aborted. Zero means unlimited.
+		MaxPanics int
+		// OnPanic observes every recovered panic, including tolerated ones.
+		OnPanic func(ctx context.Context, p *PanicError)
+	}
+)
+
+func (p *PanicError) Error() string {
+	if err, ok := p.Value.(error); ok {
+		return fmt.Sprintf("panic: %v", err)
+	}
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// PanicSafe wraps a per-item callback (for IngestNDJSON, batch or stream
+// processing) so that a panic affects only the item that caused it.
+//
+// Within the budget, a panicking item yields a 500 AppError with code
+// "item_panic", which per-item helpers record as a rejected item. Once the
+// budget is exhausted, or immediately with FailFast, the *PanicError itself is
+// returned so the whole request is aborted as an internal error.
+//
+// Panics with http.ErrAbortHandler, even wrapped, are re-panicked so the
+// request is aborted as net/http intends.
+//
+// The budget is tracked by the returned func, so wrap the callback once per
+// request.
+func PanicSafe[T any](fn func(context.Context, T) error, policy ItemPanicPolicy) func(context.Context, T) error {
+	var panics int64
+
+	return func(ctx context.Context, item T) (err error) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				// Let net/http abort the response, as the adapter does.
+				panic(rec)
+			}
+
+			p := &PanicError{Value: rec, Stack: debug.Stack()}
+			if policy.OnPanic != nil {
+				policy.OnPanic(ctx, p)
+			}
+
+			n := atomic.AddInt64(&panics, 1)
+			if policy.FailFast || (policy.MaxPanics > 0 && n > int64(policy.MaxPanics)) {
+				err = p
+				return
+			}
+
+			err = StatusError(http.StatusInternalServerError, "item processing failed").WithCode("item_panic")
+		}()
+
+		return fn(ctx, item)
+	}
+}
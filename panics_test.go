@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPanicSafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ItemPanicPolicy
+		value   interface{}
+		panics  bool
+		request bool
+	}{
+		{name: "tolerated", value: "boom"},
+		{name: "fail fast", policy: ItemPanicPolicy{FailFast: true}, value: "boom", request: true},
+		{name: "abort handler", value: http.ErrAbortHandler, panics: true},
+		{name: "wrapped abort handler", value: fmt.Errorf("stream: %w", http.ErrAbortHandler), panics: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := PanicSafe(func(context.Context, int) error { panic(tt.value) }, tt.policy)
+
+			var err error
+			rec := func() (rec interface{}) {
+				defer func() { rec = recover() }()
+				err = fn(context.Background(), 1)
+				return nil
+			}()
+			if (rec != nil) != tt.panics {
+				t.Fatalf("recovered %v, want panic %v", rec, tt.panics)
+			}
+			if tt.panics {
+				return
+			}
+
+			var p *PanicError
+			if errors.As(err, &p) != tt.request {
+				t.Errorf("error %v, want *PanicError %v", err, tt.request)
+			}
+			var appErr AppError
+			if !tt.request && (!errors.As(err, &appErr) || appErr.Code != "item_panic") {
+				t.Errorf("error %v, want item_panic", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DefaultPollInterval is how long Poll waits between checks that found
+// nothing.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// Poll answers a long-polling request: it calls check until it reports
+// data or wait elapsed, then writes the data with Respond as 200, or an
+// empty 204 on timeout. check receives a context ending at the deadline,
+// so it may also block itself, e.g. on a channel; checks returning nothing
+// right away are repeated every DefaultPollInterval.
+//
+// When the client disconnects Poll returns nil without writing, and errors
+// of check caused by the disconnect or the deadline are not returned, so
+// nothing spurious is reported.
+func Poll(w http.ResponseWriter, r *http.Request, wait time.Duration, check func(ctx context.Context) (interface{}, bool, error)) error {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	for {
+		v, ok, err := check(ctx)
+		switch {
+		case r.Context().Err() != nil:
+			return nil
+		case err != nil && !(ctx.Err() != nil && errors.Is(err, ctx.Err())):
+			return err
+		case ok:
+			return Respond(w, r, http.StatusOK, v)
+		}
+
+		timer := time.NewTimer(DefaultPollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if r.Context().Err() != nil {
+				return nil
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// VersionETag returns a strong entity tag for a resource version, such as a
+// row's version column, so writes can be guarded without hashing the
+// representation.
+func VersionETag(version int64) string {
+	return `"v` + strconv.FormatInt(version, 10) + `"`
+}
+
+// CheckPrecondition guards a write against lost updates. It evaluates
+// If-Match, using strong comparison, or If-Unmodified-Since when If-Match is
+// absent, against the resource's current etag and modTime, and returns a 412
+// AppError (code "precondition_failed") when the client's copy is stale. The
+// current validators are set on the response, so the 412 tells the client
+// what to refetch. Requests without preconditions pass; see
+// RequirePrecondition.
+//
+// After a successful write, set the new validator with the response:
+//
+//	if err := httpx.CheckPrecondition(w, r, httpx.VersionETag(doc.Version), time.Time{}); err != nil {
+//		return err
+//	}
+//	doc, err = store.Update(ctx, doc.ID, patch)
+//	...
+//	w.Header().Set("ETag", httpx.VersionETag(doc.Version))
+func CheckPrecondition(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) error {
+	if Precondition(r, etag, modTime) {
+		return nil
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	return StatusError(http.StatusPreconditionFailed, "resource was modified").WithCode("precondition_failed")
+}
+
+// RequirePrecondition is CheckPrecondition for resources that must not be
+// written blindly: requests with neither If-Match nor If-Unmodified-Since
+// get a 428 AppError (code "precondition_required").
+func RequirePrecondition(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) error {
+	if r.Header.Get("If-Match") == "" && r.Header.Get("If-Unmodified-Since") == "" {
+		return StatusError(http.StatusPreconditionRequired, "If-Match header required").WithCode("precondition_required")
+	}
+	return CheckPrecondition(w, r, etag, modTime)
+}
+
+// Precondition reports whether the If-Match and If-Unmodified-Since
+// preconditions of r hold for a resource with the current etag and modTime
+// (RFC 9110 13.2.2). An empty etag means the resource does not exist, so
+// any If-Match fails.
+func Precondition(r *http.Request, etag string, modTime time.Time) bool {
+	if im := r.Header.Get("If-Match"); im != "" {
+		return etag != "" && etagListMatches(im, etag, true)
+	}
+
+	ius := r.Header.Get("If-Unmodified-Since")
+	if ius == "" || modTime.IsZero() {
+		return true
+	}
+	t, err := http.ParseTime(ius)
+	if err != nil {
+		return true
+	}
+	return !modTime.Truncate(time.Second).After(t)
+}
@@ -0,0 +1,10 @@
+module github.com/radim/httpx/protobuf
+
+go 1.24
+
+require (
+	github.com/radim/httpx v0.0.0
+	google.golang.org/protobuf v1.36.5
+)
+
+replace github.com/radim/httpx => ../
@@ -0,0 +1,39 @@
+// Package protobuf provides an httpx.Codec for Protocol Buffers bodies.
+package protobuf
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/radim/httpx"
+)
+
+// ContentType is the media type of protobuf bodies.
+const ContentType = "application/x-protobuf"
+
+// Codec encodes proto.Message values. Use it with httpx.Bind, httpx.Respond
+// and httpx.Typed, usually next to httpx.JSONCodec.
+type Codec struct{}
+
+var _ httpx.Codec = Codec{}
+
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,169 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+type (
+	// ProxyOption configures ReverseProxy.
+	ProxyOption func(*proxyConfig)
+
+	proxyConfig struct {
+		adapter   *HandlerAdapter
+		transport http.RoundTripper
+		timeout   time.Duration
+		retries   int
+		rewrites  []func(*httputil.ProxyRequest)
+		modify    []func(*http.Response) error
+		flush     time.Duration
+	}
+)
+
+// WithProxyAdapter renders and reports upstream failures through adapter.
+// Without it the proxy uses NewDefaultHandlerAdapter(nil).
+func WithProxyAdapter(adapter *HandlerAdapter) ProxyOption {
+	return func(c *proxyConfig) { c.adapter = adapter }
+}
+
+// WithProxyTransport sets the transport used to reach the upstream.
+func WithProxyTransport(rt http.RoundTripper) ProxyOption {
+	return func(c *proxyConfig) { c.transport = rt }
+}
+
+// WithProxyTimeout bounds the wait for the upstream's response headers.
+// Streaming response bodies are not cut off.
+func WithProxyTimeout(d time.Duration) ProxyOption {
+	return func(c *proxyConfig) { c.timeout = d }
+}
+
+// WithProxyRetries retries idempotent requests without a body up to n
+// times with NewRetryTransport, when the upstream cannot be reached or
+// answers 429, 502, 503 or 504.
+func WithProxyRetries(n int) ProxyOption {
+	return func(c *proxyConfig) { c.retries = n }
+}
+
+// WithProxyRewrite adjusts outgoing requests after the default rewrite,
+// e.g. to set or strip headers.
+func WithProxyRewrite(fn func(*httputil.ProxyRequest)) ProxyOption {
+	return func(c *proxyConfig) { c.rewrites = append(c.rewrites, fn) }
+}
+
+// WithProxyResponse adjusts upstream responses before they are copied to
+// the client; returning an error fails the request with 502.
+func WithProxyResponse(fn func(*http.Response) error) ProxyOption {
+	return func(c *proxyConfig) { c.modify = append(c.modify, fn) }
+}
+
+// WithProxyFlushInterval sets httputil.ReverseProxy.FlushInterval; a
+// negative value flushes after every write.
+func WithProxyFlushInterval(d time.Duration) ProxyOption {
+	return func(c *proxyConfig) { c.flush = d }
+}
+
+// ReverseProxy forwards requests to target with X-Forwarded-* headers set.
+// Upstream failures are reported and rendered through the adapter as
+// AppErrors: 504 with code "upstream_timeout" for timeouts and 502 with
+// code "bad_gateway" otherwise. Requests canceled by the client are
+// neither rendered nor reported.
+func ReverseProxy(target *url.URL, opts ...ProxyOption) http.Handler {
+	c := &proxyConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.adapter == nil {
+		c.adapter = NewDefaultHandlerAdapter(nil)
+	}
+
+	transport := c.transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = c.timeout
+		transport = t
+	} else if c.timeout > 0 {
+		transport = &headerTimeoutTransport{next: transport, timeout: c.timeout}
+	}
+	if c.retries > 0 {
+		transport = NewRetryTransport(transport, RetryConfig{MaxAttempts: c.retries + 1})
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			for _, fn := range c.rewrites {
+				fn(pr)
+			}
+		},
+		Transport:     transport,
+		FlushInterval: c.flush,
+		ModifyResponse: func(resp *http.Response) error {
+			for _, fn := range c.modify {
+				if err := fn(resp); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(r.Context().Err(), context.Canceled) {
+				return
+			}
+			c.adapter.report(r, err)
+			c.adapter.HandleError(w, r, upstreamError(err))
+		},
+	}
+}
+
+func upstreamError(err error) AppError {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return WrapStatus(err, http.StatusGatewayTimeout, "upstream timed out").WithCode("upstream_timeout")
+	}
+	return WrapStatus(err, http.StatusBadGateway, "upstream unavailable").WithCode("bad_gateway")
+}
+
+// headerTimeoutTransport fails requests whose response headers do not
+// arrive within timeout, for transports that cannot be configured with
+// ResponseHeaderTimeout.
+type headerTimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *headerTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.timeout, cancel)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,199 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type byteRange struct {
+	start, length int64
+}
+
+var errInvalidRange = errors.New("invalid range")
+
+// ServeRange serves content like http.ServeContent, for resumable downloads
+// and media seeking, but within the package's error model: validators are
+// checked with CheckNotModified (so 304 is returned as ErrNotModified) and
+// invalid or unsatisfiable ranges become a 416 AppError with code
+// "range_not_satisfiable" instead of a plain-text response.
+//
+// Range is honored for GET requests, subject to If-Range matching etag or
+// modTime. Multiple ranges are served as multipart/byteranges.
+func ServeRange(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, etag string, content io.ReadSeeker) error {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("serving %s: %w", name, err)
+	}
+
+	if err := CheckNotModified(w, r, etag, modTime); err != nil {
+		return err
+	}
+
+	h := w.Header()
+	h.Set("Accept-Ranges", "bytes")
+	ctype := h.Get("Content-Type")
+	if ctype == "" {
+		ctype = mime.TypeByExtension(path.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		h.Set("Content-Type", ctype)
+	}
+
+	var ranges []byteRange
+	if spec := r.Header.Get("Range"); spec != "" && r.Method == http.MethodGet && ifRangeMatches(r, etag, modTime) {
+		ranges, err = parseRanges(spec, size)
+		if err != nil {
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			return StatusError(http.StatusRequestedRangeNotSatisfiable, "%v for %d bytes", err, size).WithCode("range_not_satisfiable")
+		}
+		// Ranges adding up to more than the content are cheaper served whole.
+		var total int64
+		for _, br := range ranges {
+			total += br.length
+		}
+		if total > size {
+			ranges = nil
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		return copyRange(w, r, content, byteRange{0, size})
+
+	case 1:
+		br := ranges[0]
+		h.Set("Content-Range", br.contentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(br.length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		return copyRange(w, r, content, br)
+
+	default:
+		mw := multipart.NewWriter(w)
+		h.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusPartialContent)
+		if r.Method == http.MethodHead {
+			return nil
+		}
+
+		for _, br := range ranges {
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":  {ctype},
+				"Content-Range": {br.contentRange(size)},
+			})
+			if err != nil {
+				return &CommittedError{Err: err}
+			}
+			if err := copyRange(part, r, content, br); err != nil {
+				return err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return &CommittedError{Err: err}
+		}
+		return nil
+	}
+}
+
+func copyRange(w io.Writer, r *http.Request, content io.ReadSeeker, br byteRange) error {
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	if _, err := content.Seek(br.start, io.SeekStart); err != nil {
+		return &CommittedError{Err: err}
+	}
+	if _, err := io.CopyN(w, content, br.length); err != nil {
+		return &CommittedError{Err: err}
+	}
+	return nil
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// ifRangeMatches evaluates If-Range: the range applies only while the
+// client's validator is current. Weak entity tags never match.
+func ifRangeMatches(r *http.Request, etag string, modTime time.Time) bool {
+	ir := r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return etag != "" && !strings.HasPrefix(ir, "W/") && ir == etag
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && !modTime.IsZero() && modTime.UTC().Truncate(time.Second).Equal(t)
+}
+
+// parseRanges parses a "bytes=" Range header against a resource of size
+// bytes, clamping open and oversized ranges. Ranges starting past the end
+// are dropped; if none remain the range is unsatisfiable.
+func parseRanges(spec string, size int64) ([]byteRange, error) {
+	specs, ok := strings.CutPrefix(spec, "bytes=")
+	if !ok {
+		return nil, errInvalidRange
+	}
+
+	var ranges []byteRange
+	for _, s := range strings.Split(specs, ",") {
+		s = textproto.TrimString(s)
+		if s == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(s, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+		first, last = textproto.TrimString(first), textproto.TrimString(last)
+
+		var br byteRange
+		if first == "" {
+			// Suffix range: the last n bytes.
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			if n == 0 {
+				continue
+			}
+			n = min(n, size)
+			br = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errInvalidRange
+			}
+			if start >= size {
+				continue
+			}
+			end := size - 1
+			if last != "" {
+				end, err = strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errInvalidRange
+				}
+				end = min(end, size-1)
+			}
+			br = byteRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, br)
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.New("range not satisfiable")
+	}
+	return ranges, nil
+}
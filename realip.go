@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+type externalOriginKey struct{}
+
+// externalOrigin is the scheme and host the client used to reach the
+// outermost trusted proxy.
+type externalOrigin struct {
+	scheme, host string
+}
+
+// RealIPMiddleware resolves the client address and stores it for
+// ClientIPFromContext. Forwarding headers (Forwarded, X-Forwarded-For,
+// X-Real-IP) are only believed when the connection comes from one of the
+// trusted proxy prefixes, and forwarded chains are walked right to left so
+// the first untrusted hop wins: a client cannot spoof its address by
+// prepending entries of its own. From trusted proxies it also takes the
+// external scheme and host (Forwarded proto= and host=, or
+// X-Forwarded-Proto and X-Forwarded-Host) for ExternalURL.
+//
+//	httpx.RealIPMiddleware(netip.MustParsePrefix("10.0.0.0/8"))
+func RealIPMiddleware(trusted ...netip.Prefix) Middleware {
+	isTrusted := func(ip netip.Addr) bool { return prefixesContain(trusted, ip) }
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := parseHostAddr(r.RemoteAddr)
+			ctx := r.Context()
+			if ok && isTrusted(ip) {
+				ip = forwardedClient(r.Header, ip, isTrusted)
+				if origin, ok := forwardedOrigin(r); ok {
+					ctx = context.WithValue(ctx, externalOriginKey{}, origin)
+				}
+			}
+			if ip.IsValid() {
+				ctx = WithClientIP(ctx, ip)
+			}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIPFromContext returns the address resolved by RealIPMiddleware.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	return clientIPKey.Value(ctx)
+}
+
+func WithClientIP(ctx context.Context, ip netip.Addr) context.Context {
+	return clientIPKey.WithValue(ctx, ip)
+}
+
+// ClientIP returns the resolved client address, falling back to the
+// connection's remote address when RealIPMiddleware is not installed.
+func ClientIP(r *http.Request) netip.Addr {
+	if ip, ok := ClientIPFromContext(r.Context()); ok {
+		return ip
+	}
+	ip, _ := parseHostAddr(r.RemoteAddr)
+	return ip
+}
+
+// ExternalURL returns the scheme and host under which the client reached
+// the service, as resolved by RealIPMiddleware from trusted proxies, or
+// otherwise from the connection and Host header.
+func ExternalURL(r *http.Request) *url.URL {
+	if o, ok := r.Context().Value(externalOriginKey{}).(externalOrigin); ok {
+		return &url.URL{Scheme: o.scheme, Host: o.host}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: r.Host}
+}
+
+// forwardedOrigin reads the origin set by the outermost proxy, which is the
+// first element of the forwarding headers.
+func forwardedOrigin(r *http.Request) (externalOrigin, bool) {
+	var o externalOrigin
+	if v := r.Header.Get("Forwarded"); v != "" {
+		first, _, _ := strings.Cut(v, ",")
+		for _, pair := range strings.Split(first, ";") {
+			k, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			val = strings.Trim(val, `"`)
+			switch strings.ToLower(k) {
+			case "proto":
+				o.scheme = val
+			case "host":
+				o.host = val
+			}
+		}
+	} else {
+		o.scheme, _, _ = strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+		o.host, _, _ = strings.Cut(r.Header.Get("X-Forwarded-Host"), ",")
+	}
+	o.scheme = strings.ToLower(strings.TrimSpace(o.scheme))
+	o.host = strings.TrimSpace(o.host)
+	if o.scheme != "http" && o.scheme != "https" {
+		o.scheme = ""
+	}
+	if o.scheme == "" && o.host == "" {
+		return o, false
+	}
+	if o.scheme == "" {
+		o.scheme = "http"
+		if r.TLS != nil {
+			o.scheme = "https"
+		}
+	}
+	if o.host == "" {
+		o.host = r.Host
+	}
+	return o, true
+}
+
+func forwardedClient(h http.Header, peer netip.Addr, isTrusted func(netip.Addr) bool) netip.Addr {
+	var hops []string
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		hops = forwardedFor(values)
+	} else if values := h.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, v := range values {
+			for _, hop := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	} else if v := h.Get("X-Real-IP"); v != "" {
+		hops = []string{strings.TrimSpace(v)}
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseHostAddr(hops[i])
+		if !ok {
+			// Obfuscated or malformed hop: nothing beyond it can be trusted.
+			break
+		}
+		client = ip
+		if !isTrusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// forwardedFor extracts the for= parameters of RFC 7239 Forwarded headers.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					hops = append(hops, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseHostAddr accepts "ip", "ip:port", "[ipv6]" and "[ipv6]:port".
+func parseHostAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
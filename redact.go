@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+)
+
+// ErrorReferenceHeader carries the reference of an internal error on 500
+// responses.
+const ErrorReferenceHeader = "X-Error-Reference"
+
+type (
+	// RedactionPolicy keeps internal error strings, which may contain SQL
+	// fragments or file paths, away from clients. NewDefaultHandlerAdapter
+	// and InternalErrorsHandler apply it outside development and staging;
+	// reporters still receive the original errors.
+	RedactionPolicy struct {
+		// Message replaces redacted messages. Empty uses the status text.
+		Message string
+		// MinStatus is the lowest status whose AppError messages are
+		// replaced, 500 by default.
+		MinStatus int
+		// Scrub, when set, rewrites the messages of AppErrors below
+		// MinStatus, e.g. to strip identifiers.
+		Scrub func(msg string) string
+	}
+
+	errorReferenceKey struct{}
+)
+
+// ErrorReference returns the reference of the internal error being
+// reported, for reporters to attach to their records. Clients receive the
+// same reference in ErrorReferenceHeader and the rendered ErrorInfo.
+func ErrorReference(ctx context.Context) string {
+	ref, _ := ctx.Value(errorReferenceKey{}).(string)
+	return ref
+}
+
+func withErrorReference(ctx context.Context, ref string) context.Context {
+	return context.WithValue(ctx, errorReferenceKey{}, ref)
+}
+
+// newErrorReference returns a short opaque ID, easy to read out from a
+// screenshot.
+func newErrorReference() string {
+	var b [5]byte
+	rand.Read(b[:])
+	return base32.StdEncoding.EncodeToString(b[:])
+}
+
+// redactionFor returns the policy config asks for, nil when error details
+// are meant to be shown.
+func redactionFor(config AppConfig) *RedactionPolicy {
+	if config.IsDevelopment() || verboseErrors(config) {
+		return nil
+	}
+	if r, ok := config.(interface{ Redaction() *RedactionPolicy }); ok {
+		if p := r.Redaction(); p != nil {
+			return p
+		}
+	}
+	return &RedactionPolicy{}
+}
+
+func (p *RedactionPolicy) message(status int) string {
+	if p.Message != "" {
+		return p.Message
+	}
+	return http.StatusText(status)
+}
+
+// redact returns e with a client-safe message, keeping the original error
+// in the chain.
+func (p *RedactionPolicy) redact(e AppError) AppError {
+	minStatus := p.MinStatus
+	if minStatus == 0 {
+		minStatus = http.StatusInternalServerError
+	}
+
+	switch {
+	case e.StatusCode >= minStatus:
+		e.Err = &wrappedError{msg: p.message(e.StatusCode), err: e.Err}
+	case p.Scrub != nil:
+		e.Err = &wrappedError{msg: p.Scrub(e.Error()), err: e.Err}
+	}
+	return e
+}
@@ -0,0 +1,128 @@
+// Package redis provides Redis-backed stores for httpx middleware, for
+// deployments that run more than one replica.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/radim/httpx"
+)
+
+// IdempotencyStore implements httpx.IdempotencyStore on a Redis client.
+// Keys are claimed with SET NX, so concurrent replicas agree on a winner.
+type IdempotencyStore struct {
+	Client goredis.UniversalClient
+	// Prefix namespaces the Redis keys; "httpx:idem:" if empty.
+	Prefix string
+}
+
+var _ httpx.IdempotencyStore = (*IdempotencyStore)(nil)
+
+func (s *IdempotencyStore) Lock(ctx context.Context, key string, rec httpx.IdempotencyRecord, ttl time.Duration) (httpx.IdempotencyRecord, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	ok, err := s.Client.SetNX(ctx, s.key(key), data, ttl).Result()
+	if err != nil || ok {
+		return rec, err
+	}
+
+	data, err = s.Client.Get(ctx, s.key(key)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		// Expired or released between SETNX and GET: try once more.
+		return s.Lock(ctx, key, rec, ttl)
+	}
+	if err != nil {
+		return rec, err
+	}
+	var existing httpx.IdempotencyRecord
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return rec, err
+	}
+	return existing, httpx.ErrIdempotencyKeyExists
+}
+
+func (s *IdempotencyStore) Save(ctx context.Context, key string, rec httpx.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.Client.Set(ctx, s.key(key), data, ttl).Err()
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.Client.Del(ctx, s.key(key)).Err()
+}
+
+func (s *IdempotencyStore) key(key string) string {
+	if s.Prefix == "" {
+		return "httpx:idem:" + key
+	}
+	return s.Prefix + key
+}
+
+// SessionStore implements httpx.SessionStore on a Redis client. Sessions
+// expire with their Redis keys, whose TTL is derived from Session.Expires
+// by the httpx package clock.
+type SessionStore struct {
+	Client goredis.UniversalClient
+	// Prefix namespaces the Redis keys; "httpx:session:" if empty.
+	Prefix string
+}
+
+var _ httpx.SessionStore = (*SessionStore)(nil)
+
+func (s *SessionStore) Load(ctx context.Context, token string) (*httpx.Session, error) {
+	data, err := s.Client.Get(ctx, s.key(token)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess := &httpx.Session{ID: token}
+	if err := json.Unmarshal(data, &sess.Values); err != nil {
+		return nil, nil
+	}
+	ttl, err := s.Client.PTTL(ctx, s.key(token)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		// Gone since GET, or stored without an expiry.
+		return nil, nil
+	}
+	sess.Expires = httpx.CurrentClock().Now().Add(ttl)
+	return sess, nil
+}
+
+func (s *SessionStore) Save(ctx context.Context, sess *httpx.Session) (string, error) {
+	ttl := sess.Expires.Sub(httpx.CurrentClock().Now())
+	if ttl <= 0 {
+		// A zero TTL would store the key without an expiry.
+		return "", fmt.Errorf("session expired at %v", sess.Expires)
+	}
+	data, err := json.Marshal(sess.Values)
+	if err != nil {
+		return "", err
+	}
+	return sess.ID, s.Client.Set(ctx, s.key(sess.ID), data, ttl).Err()
+}
+
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	return s.Client.Del(ctx, s.key(id)).Err()
+}
+
+func (s *SessionStore) key(id string) string {
+	if s.Prefix == "" {
+		return "httpx:session:" + id
+	}
+	return s.Prefix + id
+}
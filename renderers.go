@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+)
+
+type (
+	// DebugRenderer renders an HTML page with the error message, cause and
+	// stack. It is meant for development only.
+	DebugRenderer struct{}
+
+	// ProblemRenderer renders RFC 9457 application/problem+json bodies.
+	// 500 responses never carry error details, only the error reference.
+	ProblemRenderer struct {
+		// TypeBase prefixes AppError codes to build the problem type URI.
+		// Empty uses "about:blank".
+		TypeBase string
+	}
+
+	problem struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+		Detail string `json:"detail,omitempty"`
+		Code   string `json:"code,omitempty"`
+		// Reference lets support find the report, see ErrorReference.
+		Reference string `json:"reference,omitempty"`
+		// Errors lists the failed constraints of a ValidationError.
+		Errors []FieldError `json:"errors,omitempty"`
+		// RetryAfter is AppError.RetryAfter in seconds.
+		RetryAfter int `json:"retry_after,omitempty"`
+	}
+)
+
+var (
+	_ Renderer = DebugRenderer{}
+	_ Renderer = ProblemRenderer{}
+)
+
+var debugPage = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Status}} {{.Title}}</title>
+<style>body{font-family:sans-serif;margin:2em}pre{background:#f4f4f4;padding:1em;overflow:auto}</style>
+</head>
+<body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{with .Message}}<h2>{{.}}</h2>{{end}}
+{{with .Code}}<p>Code: <code>{{.}}</code></p>{{end}}
+{{with .Reference}}<p>Reference: <code>{{.}}</code></p>{{end}}
+{{with .Cause}}<h3>Cause</h3><pre>{{.}}</pre>{{end}}
+{{with .Stack}}<h3>Stack</h3><pre>{{.}}</pre>{{end}}
+</body>
+</html>
+`))
+
+type debugPageData struct {
+	Status    int
+	Title     string
+	Message   string
+	Code      string
+	Reference string
+	Cause     string
+	Stack     string
+}
+
+func (DebugRenderer) Render500(_ context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	data := debugPageData{Status: http.StatusInternalServerError, Title: http.StatusText(http.StatusInternalServerError)}
+	if errInfo != nil {
+		data.Message, data.Reference = errInfo.Message, errInfo.Reference
+		data.Cause, data.Stack = errInfo.Cause, errInfo.Stack
+	}
+	writeDebugPage(w, data)
+}
+
+func (DebugRenderer) RenderAppError(_ context.Context, w http.ResponseWriter, appErr AppError) {
+	writeDebugPage(w, debugPageData{
+		Status:  appErr.StatusCode,
+		Title:   http.StatusText(appErr.StatusCode),
+		Message: appErr.Error(),
+		Code:    appErr.Code,
+	})
+}
+
+func writeDebugPage(w http.ResponseWriter, data debugPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.Status)
+	debugPage.Execute(w, data)
+}
+
+func (p ProblemRenderer) Render500(_ context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	pr := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+	}
+	if errInfo != nil {
+		pr.Reference = errInfo.Reference
+	}
+	p.write(w, pr)
+}
+
+func (p ProblemRenderer) RenderAppError(_ context.Context, w http.ResponseWriter, appErr AppError) {
+	pr := problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(appErr.StatusCode),
+		Status:     appErr.StatusCode,
+		Code:       appErr.Code,
+		RetryAfter: retryAfterSeconds(appErr.RetryAfter),
+	}
+	// Server-side failures keep their message private.
+	if appErr.StatusCode < 500 {
+		pr.Detail = appErr.Error()
+		if v, ok := AsValidationError(appErr); ok {
+			pr.Errors = v.Fields
+		}
+	}
+	if p.TypeBase != "" && appErr.Code != "" {
+		pr.Type = p.TypeBase + appErr.Code
+	}
+	p.write(w, pr)
+}
+
+func (ProblemRenderer) write(w http.ResponseWriter, pr problem) {
+	h := w.Header()
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(pr.Status)
+	writeJSON(w, pr)
+}
+
+// verboseErrors reports whether config asks for error details outside
+// development, as NewConfig does in staging.
+func verboseErrors(config AppConfig) bool {
+	v, ok := config.(interface{ VerboseErrors() bool })
+	return ok && v.VerboseErrors()
+}
@@ -0,0 +1,78 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ReportContext collects tags that reporters attach to every error reported
+// for a request, such as the tenant, user or enabled feature flags.
+// Middleware adds tags with SetReportTag as it learns them; reporters read
+// them with ReportTags instead of re-deriving them from the request.
+//
+// The tags are shared by every context derived from the one holding the
+// ReportContext, so tags set deep in the chain also reach errors reported
+// by outer middleware. Install ReportContextMiddleware outermost for that.
+type ReportContext struct {
+	mu   sync.Mutex
+	tags []ReportTag
+}
+
+// ReportTag is a key/value pair attached to error reports.
+type ReportTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+var reportContextKey = NewContextKey[*ReportContext]("report context")
+
+// ReportContextMiddleware gives every request an empty ReportContext.
+func ReportContextMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithReportContext(r.Context())))
+		})
+	}
+}
+
+// WithReportContext returns a context holding a new, empty ReportContext.
+func WithReportContext(ctx context.Context) context.Context {
+	return reportContextKey.WithValue(ctx, &ReportContext{})
+}
+
+// SetReportTag sets the tag key to value, replacing an earlier value. It
+// adds to the ReportContext of ctx and returns ctx as is, or returns a
+// context with a new ReportContext if ctx has none.
+func SetReportTag(ctx context.Context, key, value string) context.Context {
+	rc, ok := reportContextKey.Value(ctx)
+	if !ok {
+		ctx = WithReportContext(ctx)
+		rc, _ = reportContextKey.Value(ctx)
+	}
+	rc.set(key, value)
+	return ctx
+}
+
+// ReportTags returns the tags of ctx in the order they were first set.
+func ReportTags(ctx context.Context) []ReportTag {
+	rc, ok := reportContextKey.Value(ctx)
+	if !ok {
+		return nil
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return slices.Clone(rc.tags)
+}
+
+func (rc *ReportContext) set(key, value string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	i := slices.IndexFunc(rc.tags, func(t ReportTag) bool { return t.Key == key })
+	if i >= 0 {
+		rc.tags[i].Value = value
+		return
+	}
+	rc.tags = append(rc.tags, ReportTag{Key: key, Value: value})
+}
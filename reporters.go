@@ -0,0 +1,105 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+type (
+	// ReporterFunc adapts a plain function to ErrorReporter.
+	ReporterFunc func(ctx context.Context, err error)
+
+	multiReporter []ErrorReporter
+)
+
+func (f ReporterFunc) ReportError(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+// NopReporter discards every error.
+func NopReporter() ErrorReporter {
+	return ReporterFunc(func(context.Context, error) {})
+}
+
+// SlogReporter logs every error at error level, with its ErrorClass, the
+// request method and path, route pattern, request ID, trace and span IDs,
+// the service of an UpstreamError (as "upstream"), error reference,
+// principal subject, report tags (grouped under "tags") and stack trace
+// attached when known. A nil logger uses slog.Default.
+//
+// Method and path are recorded when the adapter handles or reports an
+// error; errors reported by other means are logged without.
+func SlogReporter(logger *slog.Logger) ErrorReporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		l.LogAttrs(ctx, slog.LevelError, "request failed", reportAttrs(ctx, err)...)
+	})
+}
+
+// reportAttrs describes err and the request it failed.
+func reportAttrs(ctx context.Context, err error) []slog.Attr {
+	attrs := []slog.Attr{slog.Any("error", err), slog.String("class", string(Classify(err).Class))}
+	if line, ok := requestLineKey.Value(ctx); ok {
+		attrs = append(attrs, slog.String("method", line.method), slog.String("path", line.path))
+	}
+	if p, ok := RoutePatternFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("route", p))
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	attrs = append(attrs, traceAttrs(ctx)...)
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		attrs = append(attrs, slog.String("upstream", upstreamErr.Service))
+	}
+	if ref := ErrorReference(ctx); ref != "" {
+		attrs = append(attrs, slog.String("reference", ref))
+	}
+	if p, ok := PrincipalFromContext(ctx); ok && p.Subject != "" {
+		attrs = append(attrs, slog.String("principal", p.Subject))
+	}
+	if tags := ReportTags(ctx); len(tags) > 0 {
+		group := make([]interface{}, len(tags))
+		for i, t := range tags {
+			group[i] = slog.String(t.Key, t.Value)
+		}
+		attrs = append(attrs, slog.Group("tags", group...))
+	}
+	if stack := errorStack(err); stack != "" {
+		attrs = append(attrs, slog.String("stack", stack))
+	}
+	return attrs
+}
+
+// MultiReporter sends every error to all reporters, in order. Nil reporters
+// are skipped.
+func MultiReporter(reporters ...ErrorReporter) ErrorReporter {
+	var m multiReporter
+	for _, r := range reporters {
+		if r != nil {
+			m = append(m, r)
+		}
+	}
+	return m
+}
+
+func (m multiReporter) ReportError(ctx context.Context, err error) {
+	for _, r := range m {
+		r.ReportError(ctx, err)
+	}
+}
+
+// FilterReporter forwards to r only the errors pred accepts, for per-sink
+// filtering inside MultiReporter.
+func FilterReporter(pred func(error) bool, r ErrorReporter) ErrorReporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		if pred(err) {
+			r.ReportError(ctx, err)
+		}
+	})
+}
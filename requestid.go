@@ -0,0 +1,57 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader carries request IDs in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID assigns every request an ID, reusing a well-formed incoming
+// RequestIDHeader (from a proxy or the calling service) and generating one
+// otherwise. The ID is echoed in the response and available through
+// RequestIDFromContext.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestIDKey.WithValue(ctx, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := requestIDKey.Value(ctx)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts up to 128 printable ASCII characters without
+// spaces, so client-provided IDs cannot inject into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package httpx
+
+import (
+	"bytes"
+	"cmp"
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatusHeader reports how ResponseCache served a response: HIT,
+// MISS or BYPASS.
+const CacheStatusHeader = "X-Cache"
+
+// CachedResponse is a stored response.
+type CachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Stored  time.Time
+	Expires time.Time
+}
+
+// CacheStore holds cached responses. Implementations must be safe for
+// concurrent use and must not return entries past their expiry.
+type CacheStore interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool)
+	Set(ctx context.Context, key string, resp *CachedResponse)
+}
+
+type ResponseCacheConfig struct {
+	Store CacheStore
+	// TTL applies to responses without max-age or s-maxage. When zero such
+	// responses are not stored.
+	TTL time.Duration
+	// Vary lists request headers that select different representations,
+	// in addition to those of a CachePolicy installed on the route.
+	Vary []string
+	// MaxBody bounds stored bodies; 1 MiB if zero.
+	MaxBody int
+}
+
+// ResponseCache serves GET and HEAD requests from cfg.Store, storing
+// successful responses (and 404/410) for their freshness lifetime. Keys
+// are CachePolicy.CacheKey, so install it inside a CachePolicy middleware
+// to key on the policy's Vary headers. Responses that vary on headers not
+// in the key, set cookies, or are private or no-store are never stored,
+// and requests carrying Authorization, Cookie or Cache-Control: no-cache
+// bypass the cache. Per-request headers such as Date, the request ID and
+// trace context are not stored, so hits keep those of the current request.
+func ResponseCache(cfg ResponseCacheConfig) Middleware {
+	maxBody := cmp.Or(cfg.MaxBody, 1<<20)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+				r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" ||
+				cacheControlHas(r.Header.Get("Cache-Control"), "no-cache", "no-store") {
+				w.Header().Set(CacheStatusHeader, "BYPASS")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			policy, ok := CachePolicyFromContext(r.Context())
+			if !ok {
+				policy = Cache()
+			}
+			policy = policy.Vary(cfg.Vary...)
+			// HEAD is answered from the GET representation.
+			get := *r
+			get.Method = http.MethodGet
+			key := policy.CacheKey(&get)
+
+			ctx := r.Context()
+			if resp, ok := cfg.Store.Get(ctx, key); ok {
+				h := w.Header()
+				for k, v := range resp.Header {
+					h[k] = v
+				}
+				h.Set("Age", strconv.FormatInt(int64(clockSince(resp.Stored)/time.Second), 10))
+				h.Set(CacheStatusHeader, "HIT")
+				w.WriteHeader(resp.Status)
+				if r.Method == http.MethodGet {
+					w.Write(resp.Body)
+				}
+				return
+			}
+
+			w.Header().Set(CacheStatusHeader, "MISS")
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rw := &recordWriter{ResponseWriter: w, limit: maxBody}
+			next.ServeHTTP(rw, r)
+
+			status, header, body, ok := rw.recorded()
+			if !ok || !cacheableStatus(status) {
+				return
+			}
+			ttl, ok := responseTTL(header, policy.VaryHeaders(), cfg.TTL)
+			if !ok {
+				return
+			}
+			header = header.Clone()
+			deletePerRequestHeaders(header)
+			now := clockNow()
+			cfg.Store.Set(ctx, key, &CachedResponse{
+				Status:  status,
+				Header:  header,
+				Body:    bytes.Clone(body),
+				Stored:  now,
+				Expires: now.Add(ttl),
+			})
+		})
+	}
+}
+
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// responseTTL decides whether a response may be shared and for how long.
+func responseTTL(h http.Header, keyed []string, def time.Duration) (time.Duration, bool) {
+	cc := h.Get("Cache-Control")
+	if cacheControlHas(cc, "private", "no-store", "no-cache") || len(h.Values("Set-Cookie")) > 0 {
+		return 0, false
+	}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" || !containsHeaderName(keyed, name) {
+				return 0, false
+			}
+		}
+	}
+	if d, ok := cacheControlSeconds(cc, "s-maxage"); ok {
+		return d, d > 0
+	}
+	if d, ok := cacheControlSeconds(cc, "max-age"); ok {
+		return d, d > 0
+	}
+	return def, def > 0
+}
+
+func containsHeaderName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheControlHas(cc string, directives ...string) bool {
+	for _, part := range strings.Split(cc, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		for _, d := range directives {
+			if strings.EqualFold(name, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func cacheControlSeconds(cc, directive string) (time.Duration, bool) {
+	for _, part := range strings.Split(cc, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && strings.EqualFold(name, directive) {
+			n, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(n) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// LRUCacheStore is an in-memory CacheStore that evicts the least recently
+// used entry once it holds MaxEntries responses.
+type LRUCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+func NewLRUCacheStore(maxEntries int) *LRUCacheStore {
+	return &LRUCacheStore{
+		maxEntries: max(maxEntries, 1),
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (s *LRUCacheStore) Get(_ context.Context, key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !clockNow().Before(e.resp.Expires) {
+		s.ll.Remove(el)
+		delete(s.entries, key)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return e.resp, true
+}
+
+func (s *LRUCacheStore) Set(_ context.Context, key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*lruEntry).resp = resp
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.ll.PushFront(&lruEntry{key: key, resp: resp})
+	for s.ll.Len() > s.maxEntries {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of stored entries, including expired ones not
+// yet evicted.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	h := Chain(RequestID(), ResponseCache(ResponseCacheConfig{Store: NewLRUCacheStore(16), TTL: time.Minute}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Server-Timing", "db;dur=12")
+		w.Write([]byte("catalog"))
+	}))
+
+	get := func(requestID, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		req.Header.Set(RequestIDHeader, requestID)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	get("first-request", "")
+	hit := get("second-request", "")
+	tests := []struct{ header, want string }{
+		{CacheStatusHeader, "HIT"},
+		{RequestIDHeader, "second-request"},
+		{"Content-Type", "text/plain"},
+		{"Server-Timing", ""},
+		{"Date", ""},
+	}
+	for _, tt := range tests {
+		if got := hit.Header().Get(tt.header); got != tt.want {
+			t.Errorf("hit %s = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if calls != 1 || hit.Body.String() != "catalog" {
+		t.Errorf("hit served %q after %d calls", hit.Body, calls)
+	}
+
+	if w := get("third-request", "_session=abc"); w.Header().Get(CacheStatusHeader) != "BYPASS" || calls != 2 {
+		t.Errorf("request with Cookie: %s after %d calls, want BYPASS", w.Header().Get(CacheStatusHeader), calls)
+	}
+}
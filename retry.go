@@ -0,0 +1,185 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type (
+	// RetryConfig configures NewRetryTransport. Zero values select the
+	// defaults noted on each field.
+	RetryConfig struct {
+		// MaxAttempts includes the first attempt; 3 if zero.
+		MaxAttempts int
+		// BaseDelay is the backoff before the first retry, doubling for each
+		// further one with full jitter; 100ms if zero.
+		BaseDelay time.Duration
+		// MaxDelay caps backoff and Retry-After waits; 10s if zero.
+		MaxDelay time.Duration
+		// AttemptTimeout bounds each attempt until its response headers and
+		// body are read; unlimited if zero.
+		AttemptTimeout time.Duration
+		// RetryStatuses are retried in addition to transport errors;
+		// 429, 502, 503 and 504 if nil.
+		RetryStatuses []int
+		// AllowNonIdempotent retries every method. Otherwise only GET, HEAD,
+		// OPTIONS, TRACE, PUT and DELETE are retried, plus requests carrying
+		// an Idempotency-Key header.
+		AllowNonIdempotent bool
+		// OnRetry is called before each retry with the failed attempt's
+		// response or error, e.g. to count retries.
+		OnRetry func(e RetryEvent)
+	}
+
+	// RetryEvent describes a failed attempt about to be retried.
+	RetryEvent struct {
+		Request  *http.Request
+		Attempt  int
+		Delay    time.Duration
+		Response *http.Response
+		Err      error
+	}
+
+	retryTransport struct {
+		next http.RoundTripper
+		cfg  RetryConfig
+	}
+)
+
+// NewRetryTransport retries failed requests with exponential backoff and
+// jitter, waiting as long as a Retry-After header asks (up to MaxDelay).
+// Requests whose body cannot be replayed through GetBody are sent once.
+func NewRetryTransport(next http.RoundTripper, cfg RetryConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 10 * time.Second
+	}
+	if cfg.RetryStatuses == nil {
+		cfg.RetryStatuses = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	return &retryTransport{next: next, cfg: cfg}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !replayable || !t.retryable(req) {
+		return t.attempt(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		if attempt >= t.cfg.MaxAttempts || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if t.cfg.OnRetry != nil {
+			t.cfg.OnRetry(RetryEvent{Request: req, Attempt: attempt, Delay: delay, Response: resp, Err: err})
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt sends req once, under AttemptTimeout if set. The deadline stays
+// in force until the response body is closed.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.cfg.AttemptTimeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.cfg.AttemptTimeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *retryTransport) retryable(req *http.Request) bool {
+	if t.cfg.AllowNonIdempotent || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		// A per-attempt timeout is retried; the caller's own deadline is not.
+		return !errors.Is(err, context.Canceled)
+	}
+	return slices.Contains(t.cfg.RetryStatuses, resp.StatusCode)
+}
+
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, t.cfg.MaxDelay)
+		}
+	}
+	ceiling := t.cfg.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > t.cfg.MaxDelay {
+		ceiling = t.cfg.MaxDelay
+	}
+	return rand.N(ceiling) + 1
+}
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date values.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
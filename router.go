@@ -0,0 +1,320 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type (
+	// Router is the registration API shared by *http.ServeMux and RadixMux.
+	Router interface {
+		http.Handler
+		Handle(pattern string, handler http.Handler)
+	}
+
+	// RadixMux is an alternative to http.ServeMux for applications with
+	// thousands of routes. Patterns use the ServeMux syntax ("GET /users/{id}",
+	// "/files/{path...}", "/static/", "/{$}") except host matching, and
+	// matched wildcards are available through Request.PathValue. Like
+	// ServeMux it redirects requests for unclean paths ("//x", "/a/../x")
+	// to their cleaned form.
+	//
+	// Precedence is resolved per segment: literals before single wildcards
+	// before multi-segment wildcards, so matching cost depends on path depth
+	// rather than the number of registered routes.
+	RadixMux struct {
+		root radixNode
+
+		// NotFound and MethodNotAllowed replace the plain-text defaults.
+		// MethodNotAllowed is called after the Allow header was set.
+		NotFound         http.Handler
+		MethodNotAllowed http.Handler
+	}
+
+	radixNode struct {
+		static   map[string]*radixNode
+		param    *radixNode
+		catchAll *radixNode
+		// subtree holds routes registered with a trailing slash, matching
+		// every path below the node.
+		subtree *radixNode
+		routes  map[string]*radixRoute
+	}
+
+	radixRoute struct {
+		pattern string
+		names   []string
+		handler http.Handler
+	}
+)
+
+var _ Router = (*http.ServeMux)(nil)
+
+func NewRadixMux() *RadixMux {
+	return &RadixMux{}
+}
+
+func (m *RadixMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Handle registers handler for pattern. Like http.ServeMux it panics on
+// invalid or conflicting patterns.
+func (m *RadixMux) Handle(pattern string, handler http.Handler) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+	path = strings.TrimLeft(path, " \t")
+	if !strings.HasPrefix(path, "/") {
+		panic("httpx: RadixMux pattern must start with a path: " + pattern)
+	}
+
+	route := &radixRoute{pattern: pattern, handler: handler}
+	n := &m.root
+	segs := strings.Split(path[1:], "/")
+
+	for i, seg := range segs {
+		last := i == len(segs)-1
+
+		switch {
+		case last && seg == "":
+			// Trailing slash: subtree match.
+			if n.subtree == nil {
+				n.subtree = &radixNode{}
+			}
+			n = n.subtree
+
+		case last && seg == "{$}":
+			n = n.child("")
+
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "...}"):
+			if !last {
+				panic("httpx: RadixMux multi-segment wildcard must be last: " + pattern)
+			}
+			route.names = append(route.names, seg[1:len(seg)-4])
+			if n.catchAll == nil {
+				n.catchAll = &radixNode{}
+			}
+			n = n.catchAll
+
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			route.names = append(route.names, seg[1:len(seg)-1])
+			if n.param == nil {
+				n.param = &radixNode{}
+			}
+			n = n.param
+
+		default:
+			n = n.child(seg)
+		}
+	}
+
+	if n.routes == nil {
+		n.routes = map[string]*radixRoute{}
+	}
+	if existing, ok := n.routes[method]; ok {
+		panic("httpx: RadixMux pattern " + pattern + " conflicts with " + existing.pattern)
+	}
+	n.routes[method] = route
+}
+
+func (m *RadixMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	if r.Method != http.MethodConnect {
+		// Mirror ServeMux: redirect to the cleaned path, so that dot
+		// segments never reach a wildcard as its value.
+		if clean := canonicalPath(path, TrailingSlashKeep); clean != path {
+			if r.URL.RawQuery != "" {
+				clean += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, clean, http.StatusTemporaryRedirect)
+			return
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	var buf [8]string
+	route, values, found := m.root.lookup(path[1:], false, r.Method, buf[:0])
+	if route == nil && !found {
+		// Mirror ServeMux: redirect /dir to /dir/ when only the subtree exists.
+		if _, _, sub := m.root.lookup(path[1:]+"/", false, r.Method, nil); sub && !strings.HasSuffix(path, "/") {
+			u := *r.URL
+			u.Path += "/"
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
+		}
+		m.notFound(w, r)
+		return
+	}
+	if route == nil {
+		var allowed []string
+		m.root.allowed(path[1:], false, &allowed)
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(slices.Compact(allowed), ", "))
+		if m.MethodNotAllowed != nil {
+			m.MethodNotAllowed.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	for i, name := range route.names {
+		if i < len(values) {
+			r.SetPathValue(name, values[i])
+		}
+	}
+	r.Pattern = route.pattern
+	route.handler.ServeHTTP(w, r)
+}
+
+func (m *RadixMux) notFound(w http.ResponseWriter, r *http.Request) {
+	if m.NotFound != nil {
+		m.NotFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (n *radixNode) child(seg string) *radixNode {
+	if n.static == nil {
+		n.static = map[string]*radixNode{}
+	}
+	c, ok := n.static[seg]
+	if !ok {
+		c = &radixNode{}
+		n.static[seg] = c
+	}
+	return c
+}
+
+// lookup returns the route serving method for the segments of rest, the
+// path below n without its leading slash, or none when end. Literal
+// segments are tried before wildcards, and a branch without a route for
+// method falls back to the next, so "GET /users/new" does not hide
+// "POST /users/{id}". found reports whether any route matched the path,
+// whatever its method.
+func (n *radixNode) lookup(rest string, end bool, method string, values []string) (*radixRoute, []string, bool) {
+	if end {
+		if n.routes == nil {
+			return nil, nil, false
+		}
+		return n.route(method), values, true
+	}
+
+	found := false
+	seg, tail, more := strings.Cut(rest, "/")
+	if c, ok := n.static[unescapeSegment(seg)]; ok {
+		route, v, f := c.lookup(tail, !more, method, values)
+		if route != nil {
+			return route, v, true
+		}
+		found = f
+	}
+	if n.param != nil && seg != "" {
+		route, v, f := n.param.lookup(tail, !more, method, append(values, unescapeSegment(seg)))
+		if route != nil {
+			return route, v, true
+		}
+		found = found || f
+	}
+	if n.catchAll != nil && n.catchAll.routes != nil {
+		if route := n.catchAll.route(method); route != nil {
+			value, err := url.PathUnescape(rest)
+			if err != nil {
+				value = rest
+			}
+			return route, append(values, value), true
+		}
+		found = true
+	}
+	if n.subtree != nil && n.subtree.routes != nil {
+		if route := n.subtree.route(method); route != nil {
+			return route, values, true
+		}
+		found = true
+	}
+	return nil, nil, found
+}
+
+// route returns the route of n serving method.
+func (n *radixNode) route(method string) *radixRoute {
+	if route := n.routes[method]; route != nil {
+		return route
+	}
+	if method == http.MethodHead {
+		if route := n.routes[http.MethodGet]; route != nil {
+			return route
+		}
+	}
+	return n.routes[""]
+}
+
+// allowed appends the methods of every route matching the segments of rest
+// to methods, for the Allow header of 405 responses.
+func (n *radixNode) allowed(rest string, end bool, methods *[]string) {
+	if end {
+		n.methods(methods)
+		return
+	}
+	seg, tail, more := strings.Cut(rest, "/")
+	if c, ok := n.static[unescapeSegment(seg)]; ok {
+		c.allowed(tail, !more, methods)
+	}
+	if n.param != nil && seg != "" {
+		n.param.allowed(tail, !more, methods)
+	}
+	if n.catchAll != nil {
+		n.catchAll.methods(methods)
+	}
+	if n.subtree != nil {
+		n.subtree.methods(methods)
+	}
+}
+
+func (n *radixNode) methods(methods *[]string) {
+	for method := range n.routes {
+		if method != "" {
+			*methods = append(*methods, method)
+		}
+	}
+	if _, ok := n.routes[http.MethodGet]; ok {
+		*methods = append(*methods, http.MethodHead)
+	}
+}
+
+func unescapeSegment(seg string) string {
+	if !strings.Contains(seg, "%") {
+		return seg
+	}
+	if s, err := url.PathUnescape(seg); err == nil {
+		return s
+	}
+	return seg
+}
+
+// NotFoundHandler renders 404 through the adapter, for RadixMux.NotFound or
+// a catch-all ServeMux route.
+func (a *HandlerAdapter) NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.HandleError(w, r, NotFoundError("%s not found", r.URL.Path).WithCode("not_found"))
+	})
+}
+
+// MethodNotAllowedHandler renders 405 through the adapter. allowed sets the
+// Allow header; without it an Allow header set by the router is kept.
+func (a *HandlerAdapter) MethodNotAllowedHandler(allowed ...string) http.Handler {
+	allow := strings.Join(allowed, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		a.HandleError(w, r, StatusError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method).WithCode("method_not_allowed"))
+	})
+}
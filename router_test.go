@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const benchRoutes = 5000
+
+func TestRadixMuxMatchesServeMux(t *testing.T) {
+	patterns := []string{
+		"GET /users/new",
+		"POST /users/{id}",
+		"GET /users/{id}",
+		"DELETE /users/{id}/sessions/{sid}",
+		"GET /files/{path...}",
+		"/static/",
+		"GET /{$}",
+		"PUT /items/{id}",
+	}
+	tests := []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/users/new"},
+		{http.MethodPost, "/users/new"},
+		{http.MethodGet, "/users/42"},
+		{http.MethodHead, "/users/42"},
+		{http.MethodPatch, "/users/42"},
+		{http.MethodDelete, "/users/42/sessions/7"},
+		{http.MethodGet, "/users/42/sessions/7"},
+		{http.MethodGet, "/users/a%2Fb"},
+		{http.MethodGet, "/files/css/app.css"},
+		{http.MethodGet, "/static/js/app.js"},
+		{http.MethodPost, "/static/js/app.js"},
+		{http.MethodGet, "/static"},
+		{http.MethodGet, "/"},
+		{http.MethodPost, "/"},
+		{http.MethodGet, "/items/1"},
+		{http.MethodGet, "/missing"},
+		{http.MethodGet, "/files/.."},
+		{http.MethodGet, "/users/42/../new"},
+		{http.MethodGet, "//users/42"},
+		{http.MethodGet, "/users/./42?tab=1"},
+	}
+
+	record := func(r Router) func(method, path string) string {
+		for _, p := range patterns {
+			r.Handle(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "%s id=%s sid=%s path=%s", r.Pattern, r.PathValue("id"), r.PathValue("sid"), r.PathValue("path"))
+			}))
+		}
+		return func(method, path string) string {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			if w.Code == http.StatusMovedPermanently || w.Code == http.StatusTemporaryRedirect {
+				// Newer ServeMux versions redirect with 307.
+				return "redirect to " + w.Header().Get("Location")
+			}
+			return fmt.Sprintf("%d allow=%q %s", w.Code, w.Header().Get("Allow"), strings.TrimSpace(w.Body.String()))
+		}
+	}
+	radix, std := record(NewRadixMux()), record(http.NewServeMux())
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got, want := radix(tt.method, tt.path), std(tt.method, tt.path); got != want {
+				t.Errorf("RadixMux: %s\nServeMux: %s", got, want)
+			}
+		})
+	}
+}
+
+func registerBenchRoutes(r Router) []*http.Request {
+	h := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	reqs := make([]*http.Request, 0, benchRoutes)
+
+	for i := 0; i < benchRoutes; i++ {
+		svc, res := i/100, i%100
+		switch i % 3 {
+		case 0:
+			r.Handle(fmt.Sprintf("GET /api/v1/svc%d/res%d/{id}", svc, res), h)
+			reqs = append(reqs, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/svc%d/res%d/42", svc, res), nil))
+		case 1:
+			r.Handle(fmt.Sprintf("POST /api/v1/svc%d/res%d/{id}/items/{item}", svc, res), h)
+			reqs = append(reqs, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/svc%d/res%d/42/items/7", svc, res), nil))
+		default:
+			r.Handle(fmt.Sprintf("GET /static/svc%d/res%d/{path...}", svc, res), h)
+			reqs = append(reqs, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/static/svc%d/res%d/css/app.css", svc, res), nil))
+		}
+	}
+	return reqs
+}
+
+func benchmarkRouter(b *testing.B, r Router) {
+	reqs := registerBenchRoutes(r)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, reqs[i%len(reqs)])
+	}
+}
+
+func BenchmarkServeMux5k(b *testing.B) {
+	benchmarkRouter(b, http.NewServeMux())
+}
+
+func BenchmarkRadixMux5k(b *testing.B) {
+	benchmarkRouter(b, NewRadixMux())
+}
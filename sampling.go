@@ -0,0 +1,111 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type (
+	// SamplingConfig configures NewSamplingReporter.
+	SamplingConfig struct {
+		// Window and Burst rate-limit identical errors: at most Burst (default
+		// 1) reports per key within Window. Zero Window disables deduplication.
+		Window time.Duration
+		Burst  int
+		// Rate is the probability that an error passing deduplication is
+		// reported. Zero reports everything.
+		Rate float64
+		// Key identifies identical errors. The default combines the route
+		// pattern, the error type and its message.
+		Key func(ctx context.Context, err error) string
+	}
+
+	// SamplingReporter protects error-tracker quotas during error storms by
+	// deduplicating and sampling reports before forwarding them. The first
+	// report of a key after a suppressed period mentions how many were
+	// suppressed.
+	SamplingReporter struct {
+		next ErrorReporter
+		cfg  SamplingConfig
+
+		mu      sync.Mutex
+		windows map[string]*sampleWindow
+	}
+
+	sampleWindow struct {
+		start      time.Time
+		count      int
+		suppressed int
+	}
+)
+
+func NewSamplingReporter(next ErrorReporter, cfg SamplingConfig) *SamplingReporter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.Key == nil {
+		cfg.Key = defaultSampleKey
+	}
+	return &SamplingReporter{next: next, cfg: cfg, windows: map[string]*sampleWindow{}}
+}
+
+func (s *SamplingReporter) ReportError(ctx context.Context, err error) {
+	if s.cfg.Window > 0 {
+		suppressed, ok := s.admit(s.cfg.Key(ctx, err), clockNow())
+		if !ok {
+			return
+		}
+		if suppressed > 0 {
+			err = fmt.Errorf("%w (%d similar errors suppressed)", err, suppressed)
+		}
+	}
+
+	if s.cfg.Rate > 0 && s.cfg.Rate < 1 && rand.Float64() >= s.cfg.Rate {
+		return
+	}
+	s.next.ReportError(ctx, err)
+}
+
+// admit counts a report for key and returns whether it is within the burst,
+// along with the number of reports suppressed in the previous window.
+func (s *SamplingReporter) admit(key string, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[key]
+	if !ok || now.Sub(w.start) >= s.cfg.Window {
+		var suppressed int
+		if ok {
+			suppressed = w.suppressed
+		}
+		if !ok && len(s.windows) >= 1024 {
+			s.prune(now)
+		}
+		s.windows[key] = &sampleWindow{start: now, count: 1}
+		return suppressed, true
+	}
+
+	if w.count >= s.cfg.Burst {
+		w.suppressed++
+		return 0, false
+	}
+	w.count++
+	return 0, true
+}
+
+// prune drops expired windows so that unique errors do not accumulate.
+func (s *SamplingReporter) prune(now time.Time) {
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= s.cfg.Window {
+			delete(s.windows, key)
+		}
+	}
+}
+
+func defaultSampleKey(ctx context.Context, err error) string {
+	return routePattern(ctx) + "|" + reflect.TypeOf(err).String() + "|" + err.Error()
+}
@@ -0,0 +1,185 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+)
+
+type (
+	// SecurityRequirement describes what a route needs from a caller: a
+	// principal from the named scheme holding all Scopes and, when Roles is
+	// set, at least one of the Roles. An empty Scheme allows anonymous access.
+	SecurityRequirement struct {
+		Scheme string
+		Scopes []string
+		Roles  []string
+	}
+
+	// Security is a registry of authentication schemes and per-route
+	// requirements. Routes are declared once through Handle, which both
+	// enforces the requirements and records them, so generated documentation
+	// always matches what is enforced.
+	Security struct {
+		adapter *HandlerAdapter
+
+		mu      sync.RWMutex
+		schemes map[string]Authenticator
+		routes  map[string][]SecurityRequirement
+	}
+)
+
+func NewSecurity(adapter *HandlerAdapter) *Security {
+	return &Security{
+		adapter: adapter,
+		schemes: map[string]Authenticator{},
+		routes:  map[string][]SecurityRequirement{},
+	}
+}
+
+// AddScheme registers authn under name for use in SecurityRequirement.Scheme.
+func (s *Security) AddScheme(name string, authn Authenticator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemes[name] = authn
+}
+
+// Scheme returns the authenticator registered under name.
+func (s *Security) Scheme(name string) (Authenticator, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	authn, ok := s.schemes[name]
+	return authn, ok
+}
+
+// Handle registers handler on router for pattern, guarded by reqs. Like
+// OpenAPI security, reqs are alternatives: the first one the request satisfies
+// wins. Without reqs the route is public but still recorded. It panics if a
+// requirement references an unknown scheme.
+func (s *Security) Handle(router Router, pattern string, handler http.Handler, reqs ...SecurityRequirement) {
+	s.mu.Lock()
+	for _, req := range reqs {
+		if _, ok := s.schemes[req.Scheme]; req.Scheme != "" && !ok {
+			s.mu.Unlock()
+			panic("httpx: unknown security scheme " + req.Scheme + " for " + pattern)
+		}
+	}
+	s.routes[pattern] = reqs
+	s.mu.Unlock()
+
+	if len(reqs) > 0 {
+		handler = s.Enforce(reqs...)(handler)
+	}
+	router.Handle(pattern, handler)
+}
+
+// Requirements returns the requirements declared for pattern.
+func (s *Security) Requirements(pattern string) ([]SecurityRequirement, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reqs, ok := s.routes[pattern]
+	return reqs, ok
+}
+
+// Patterns returns all declared route patterns in sorted order.
+func (s *Security) Patterns() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	patterns := make([]string, 0, len(s.routes))
+	for p := range s.routes {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// Enforce returns middleware checking reqs without recording them. Missing
+// or invalid credentials yield 401 with the challenges of all candidate
+// schemes; an authenticated principal lacking scopes or roles yields 403 with
+// code "insufficient_scope". Schemes are resolved when Enforce is called,
+// and it panics if a requirement references an unknown scheme.
+func (s *Security) Enforce(reqs ...SecurityRequirement) Middleware {
+	var info RouteInfo
+	authns := make([]Authenticator, len(reqs))
+	for i, req := range reqs {
+		if req.Scheme == "" {
+			continue
+		}
+		authn, ok := s.Scheme(req.Scheme)
+		if !ok {
+			panic("httpx: unknown security scheme " + req.Scheme)
+		}
+		authns[i] = authn
+		info.Headers = append(info.Headers, credentialHeaders(authn)...)
+		info.Credentials = true
+	}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var authErr error
+			var denied bool
+
+			for i, req := range reqs {
+				if req.Scheme == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				p, err := authns[i].Authenticate(r)
+				if err == nil && p == nil {
+					err = ErrNoCredentials
+				}
+				if err != nil {
+					// Keep the most specific failure for the response.
+					if authErr == nil || errors.Is(authErr, ErrNoCredentials) {
+						authErr = err
+					}
+					continue
+				}
+
+				if !req.satisfiedBy(p) {
+					denied = true
+					continue
+				}
+
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+				return
+			}
+
+			if denied {
+				s.adapter.HandleError(w, r, ForbiddenError("insufficient permissions").WithCode("insufficient_scope"))
+				return
+			}
+
+			if authErr == nil || errors.Is(authErr, ErrNoCredentials) {
+				authErr = UnauthorizedError("authentication required").WithCode("unauthenticated")
+			}
+			if e, ok := authErr.(AppError); ok && e.StatusCode == http.StatusUnauthorized {
+				for _, authn := range authns {
+					if c, ok := authn.(Challenger); ok {
+						w.Header().Add("WWW-Authenticate", c.Challenge())
+					}
+				}
+			}
+			s.adapter.HandleError(w, r, authErr)
+		})
+	})
+}
+
+func (req SecurityRequirement) satisfiedBy(p *Principal) bool {
+	for _, scope := range req.Scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	if len(req.Roles) == 0 {
+		return true
+	}
+	for _, role := range req.Roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
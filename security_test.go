@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityEnforce(t *testing.T) {
+	sec := NewSecurity(NewDefaultHandlerAdapter(NewConfig()))
+	sec.AddScheme("token", AuthenticatorFunc(func(r *http.Request) (*Principal, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer reader":
+			return &Principal{Subject: "reader", Scopes: []string{"read"}}, nil
+		case "Bearer writer":
+			return &Principal{Subject: "writer", Scopes: []string{"read", "write"}}, nil
+		}
+		return nil, ErrNoCredentials
+	}))
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		defer func() {
+			if v := recover(); v != "httpx: unknown security scheme tokn" {
+				t.Errorf("Enforce panicked with %v", v)
+			}
+		}()
+		sec.Enforce(SecurityRequirement{Scheme: "tokn"})
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := PrincipalFromContext(r.Context())
+		if p != nil {
+			w.Write([]byte(p.Subject))
+		}
+	})
+	write := sec.Enforce(SecurityRequirement{Scheme: "token", Scopes: []string{"write"}})(ok)
+	optional := sec.Enforce(SecurityRequirement{Scheme: "token"}, SecurityRequirement{})(ok)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		status  int
+		body    string
+	}{
+		{name: "scope granted", handler: write, token: "Bearer writer", status: http.StatusOK, body: "writer"},
+		{name: "scope missing", handler: write, token: "Bearer reader", status: http.StatusForbidden},
+		{name: "no credentials", handler: write, status: http.StatusUnauthorized},
+		{name: "anonymous alternative", handler: optional, status: http.StatusOK},
+		{name: "authenticated alternative", handler: optional, token: "Bearer reader", status: http.StatusOK, body: "reader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, req)
+			if w.Code != tt.status || (tt.status == http.StatusOK && w.Body.String() != tt.body) {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body, tt.status, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultDrainTimeout bounds how long Run waits for in-flight requests
+// during shutdown.
+const DefaultDrainTimeout = 30 * time.Second
+
+type (
+	// Server runs an http.Server with the adapter and middleware stack
+	// installed, and shuts it down gracefully on SIGINT/SIGTERM.
+	Server struct {
+		HTTP    *http.Server
+		Adapter *HandlerAdapter
+		// DrainTimeout bounds the graceful shutdown. Connections still
+		// active afterwards are closed.
+		DrainTimeout time.Duration
+		// DrainDelay keeps the listener open for this long after draining
+		// began, before the graceful shutdown, so load balancers notice the
+		// failing readiness check while new requests are still answered.
+		DrainDelay time.Duration
+
+		middleware []Middleware
+		warmup     []warmupHook
+		ready      atomic.Bool
+		draining   atomic.Bool
+		rejectWait time.Duration
+		onStart    []func(context.Context) error
+		onShutdown []shutdownHook
+		inFlight   atomic.Int64
+
+		malformed   bool
+		onMalformed func(*MalformedRequestError)
+	}
+
+	ServerOption func(*Server)
+
+	// ShutdownError labels the error of one component (the listener, the
+	// HTTP drain or a named shutdown hook). Shutdown and Run return them
+	// combined with errors.Join.
+	ShutdownError struct {
+		Component string
+		Err       error
+	}
+
+	shutdownHook struct {
+		name string
+		fn   func(context.Context) error
+	}
+)
+
+// WithMiddleware appends middleware to the server's stack. The first
+// middleware is the outermost, inside panic recovery.
+func WithMiddleware(mws ...Middleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, mws...)
+	}
+}
+
+func WithDrainTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.DrainTimeout = d
+	}
+}
+
+// WithDrainDelay sets Server.DrainDelay.
+func WithDrainDelay(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.DrainDelay = d
+	}
+}
+
+// WithRejectWhileDraining answers requests arriving once draining began
+// with a 503 AppError (code "draining") through the adapter, with
+// "Connection: close" and a Retry-After of retryAfter (rounded up to whole
+// seconds, 1s if zero), instead of letting them race the drain timeout.
+func WithRejectWhileDraining(retryAfter time.Duration) ServerOption {
+	return func(s *Server) {
+		s.rejectWait = max(retryAfter, time.Second)
+	}
+}
+
+// WithHTTPServer customizes the underlying http.Server (timeouts, TLS,
+// error log...).
+func WithHTTPServer(fn func(*http.Server)) ServerOption {
+	return func(s *Server) {
+		fn(s.HTTP)
+	}
+}
+
+// WithH2C additionally serves HTTP/2 over cleartext connections (prior
+// knowledge h2c), for deployments behind proxies or gRPC-gateway style
+// clients that speak HTTP/2 without TLS. HTTP/1 keeps working.
+func WithH2C() ServerOption {
+	return func(s *Server) {
+		if s.HTTP.Protocols == nil {
+			s.HTTP.Protocols = new(http.Protocols)
+			s.HTTP.Protocols.SetHTTP1(true)
+			s.HTTP.Protocols.SetHTTP2(true)
+		}
+		s.HTTP.Protocols.SetUnencryptedHTTP2(true)
+	}
+}
+
+// WithHTTP2 tunes the HTTP/2 settings (max concurrent streams, ping
+// timeouts, buffer sizes...) used for both TLS and h2c connections.
+func WithHTTP2(cfg http.HTTP2Config) ServerOption {
+	return func(s *Server) {
+		s.HTTP.HTTP2 = &cfg
+	}
+}
+
+// WithIdleTimeout closes keep-alive connections, HTTP/2 included, after d
+// without requests.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.HTTP.IdleTimeout = d
+	}
+}
+
+// NewServer builds a server listening on addr. handler is wrapped, from the
+// outside in, by in-flight tracking, RecoverMiddleware and the configured
+// middleware.
+func NewServer(addr string, adapter *HandlerAdapter, handler http.Handler, opts ...ServerOption) *Server {
+	s := &Server{
+		HTTP: &http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		Adapter:      adapter,
+		DrainTimeout: DefaultDrainTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	handler = Chain(s.middleware...)(handler)
+	if s.rejectWait > 0 {
+		handler = s.rejectDraining(handler)
+	}
+	s.HTTP.Handler = s.track(RecoverMiddleware(adapter, handler))
+	return s
+}
+
+// OnStart registers a hook run after the listener is bound and before
+// requests are served. A failing hook aborts Run.
+func (s *Server) OnStart(fn func(ctx context.Context) error) {
+	s.onStart = append(s.onStart, fn)
+}
+
+// OnShutdown registers a hook run after in-flight requests drained (or the
+// drain timeout expired), in registration order. name labels its error.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, shutdownHook{name: name, fn: fn})
+}
+
+// Draining reports whether shutdown began.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// InFlight returns the number of requests currently being served.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// Run serves until ctx is canceled, a termination signal arrives or the
+// server fails, then shuts down gracefully. Failures of the listener, the
+// drain and the shutdown hooks are all returned, see ShutdownError.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", s.HTTP.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Run with an existing listener and without signal handling.
+// Warm-up hooks run first, then start hooks, before any request is accepted.
+// TLS is served when the http.Server has a TLSConfig.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if err := s.runWarmup(ctx); err != nil {
+		ln.Close()
+		return err
+	}
+
+	for _, hook := range s.onStart {
+		if err := hook(ctx); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+
+	if s.malformed {
+		ln = s.malformedListener(ln)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if s.HTTP.TLSConfig != nil {
+			// Certificates come from TLSConfig (static or GetCertificate).
+			errc <- s.HTTP.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- s.HTTP.Serve(ln)
+	}()
+	s.ready.Store(true)
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return errors.Join(&ShutdownError{Component: "listener", Err: err}, s.runShutdownHooks(context.Background()))
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown marks the server as draining, waits DrainDelay, stops accepting
+// connections, waits up to DrainTimeout for in-flight requests and runs the
+// shutdown hooks. Every failure is returned as a *ShutdownError, joined
+// with errors.Join.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+	s.draining.Store(true)
+
+	if s.DrainDelay > 0 {
+		t := time.NewTimer(s.DrainDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, s.DrainTimeout)
+	defer cancel()
+
+	var drainErr error
+	if err := s.HTTP.Shutdown(drainCtx); err != nil {
+		// Drain timed out, drop the remaining connections.
+		s.HTTP.Close()
+		drainErr = &ShutdownError{Component: "http", Err: err}
+	}
+
+	// Hooks get their own budget so a slow drain does not starve them.
+	hookCtx, cancel := context.WithTimeout(ctx, s.DrainTimeout)
+	defer cancel()
+
+	return errors.Join(drainErr, s.runShutdownHooks(hookCtx))
+}
+
+// runShutdownHooks runs every hook, even after failures.
+func (s *Server) runShutdownHooks(ctx context.Context) error {
+	var errs []error
+	for _, hook := range s.onShutdown {
+		if err := hook.fn(ctx); err != nil {
+			errs = append(errs, &ShutdownError{Component: hook.name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *ShutdownError) Error() string {
+	return e.Component + ": " + e.Err.Error()
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+func (s *Server) rejectDraining(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.draining.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Connection", "close")
+		s.Adapter.HandleError(w, r, ServiceUnavailableWithRetry(s.rejectWait, "server is shutting down").WithCode("draining"))
+	})
+}
+
+func (s *Server) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
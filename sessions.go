@@ -0,0 +1,452 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSessionTTL = 24 * time.Hour
+
+	sessionIDLen     = 32
+	sessionFlashKey  = "_flash"
+	sessionPrincipal = "_principal"
+)
+
+// ErrNoSession is returned by the session helpers when the request did not
+// pass through SessionMiddleware.
+var ErrNoSession = errors.New("httpx: no session")
+
+type (
+	// Session is the per-client state loaded by SessionMiddleware. Values are
+	// kept JSON encoded, so they survive any store unchanged; use Decode or
+	// SessionValue to read them back into a typed value.
+	//
+	// The exported fields are meant for SessionStore implementations;
+	// handlers use the methods, which are safe for concurrent use.
+	Session struct {
+		ID      string
+		Values  map[string]json.RawMessage
+		Expires time.Time
+
+		mu       sync.Mutex
+		oldID    string
+		modified bool
+	}
+
+	// SessionStore loads and persists sessions. The token is the cookie
+	// value: the session ID for server-side stores, the encoded session
+	// itself for CookieSessionStore.
+	SessionStore interface {
+		// Load returns the session for token, or nil when it is unknown,
+		// expired or fails verification.
+		Load(ctx context.Context, token string) (*Session, error)
+		// Save persists s and returns the token to send to the client.
+		Save(ctx context.Context, s *Session) (string, error)
+		// Delete discards the session with the given ID.
+		Delete(ctx context.Context, id string) error
+	}
+
+	// SessionConfig configures SessionMiddleware. Zero values select the
+	// defaults noted on each field.
+	SessionConfig struct {
+		Store SessionStore
+		// TTL is how long a session lives after its last change. It
+		// defaults to DefaultSessionTTL.
+		TTL time.Duration
+		// CookieName defaults to "_session".
+		CookieName string
+
+		Path     string
+		Domain   string
+		Secure   bool
+		SameSite http.SameSite
+	}
+)
+
+func newSessionID() (string, error) {
+	b := make([]byte, sessionIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating session ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return sessionKey.WithValue(ctx, s)
+}
+
+// SessionFromContext returns the session of the current request.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	return sessionKey.Value(ctx)
+}
+
+// SessionValue returns the named session value decoded as T.
+func SessionValue[T any](ctx context.Context, key string) (T, bool) {
+	var v T
+	s, ok := SessionFromContext(ctx)
+	if !ok {
+		return v, false
+	}
+	return v, s.Decode(key, &v) == nil
+}
+
+// Decode unmarshals the named value into v.
+func (s *Session) Decode(key string, v interface{}) error {
+	s.mu.Lock()
+	raw, ok := s.Values[key]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("session value %q not present", key)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Set stores v under key. It fails only when v cannot be marshaled.
+func (s *Session) Set(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding session value %q: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Values == nil {
+		s.Values = make(map[string]json.RawMessage)
+	}
+	s.Values[key] = raw
+	s.modified = true
+	return nil
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Values[key]; ok {
+		delete(s.Values, key)
+		s.modified = true
+	}
+}
+
+// AddFlash queues a message for the next request that calls Flashes.
+func (s *Session) AddFlash(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var flashes []string
+	json.Unmarshal(s.Values[sessionFlashKey], &flashes)
+	raw, _ := json.Marshal(append(flashes, msg))
+	if s.Values == nil {
+		s.Values = make(map[string]json.RawMessage)
+	}
+	s.Values[sessionFlashKey] = raw
+	s.modified = true
+}
+
+// Flashes returns the queued flash messages and removes them from the
+// session.
+func (s *Session) Flashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, ok := s.Values[sessionFlashKey]
+	if !ok {
+		return nil
+	}
+	var flashes []string
+	json.Unmarshal(raw, &flashes)
+	delete(s.Values, sessionFlashKey)
+	s.modified = true
+	return flashes
+}
+
+// Rotate moves the session to a fresh ID, keeping its values. The old ID is
+// deleted from the store when the response is written. Rotate on every
+// privilege change to defeat session fixation.
+func (s *Session) Rotate() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID == "" {
+		s.oldID = s.ID
+	}
+	s.ID = id
+	s.modified = true
+	return nil
+}
+
+// Invalidate discards all values and rotates the ID. Values set afterwards
+// (a "logged out" flash, say) start a new session; otherwise the cookie is
+// removed.
+func (s *Session) Invalidate() error {
+	if err := s.Rotate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.Values)
+	return nil
+}
+
+// LoginSession records p as the session's principal, rotating the session
+// ID first. SessionAuthenticator resolves it on later requests.
+func LoginSession(ctx context.Context, p *Principal) error {
+	s, ok := SessionFromContext(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	if err := s.Rotate(); err != nil {
+		return err
+	}
+	return s.Set(sessionPrincipal, p)
+}
+
+// LogoutSession invalidates the session.
+func LogoutSession(ctx context.Context) error {
+	s, ok := SessionFromContext(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	return s.Invalidate()
+}
+
+// SessionAuthenticator resolves the Principal stored by LoginSession, so
+// session logins plug into AuthMiddleware. SessionMiddleware must run first.
+type SessionAuthenticator struct{}
+
+// CredentialHeaders returns nil: sessions travel in cookies.
+func (SessionAuthenticator) CredentialHeaders() []string {
+	return nil
+}
+
+func (SessionAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	p, ok := SessionValue[*Principal](r.Context(), sessionPrincipal)
+	if !ok || p == nil {
+		return nil, ErrNoCredentials
+	}
+	return p, nil
+}
+
+// SessionMiddleware loads the session named by the request cookie (or starts
+// an empty one) and stores it in the request context. Changed sessions are
+// saved right before the response header is written; sessions left empty
+// have their cookie removed. Store failures while loading are rendered
+// through the adapter, failures while saving are reported.
+func SessionMiddleware(adapter *HandlerAdapter, cfg SessionConfig) Middleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultSessionTTL
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "_session"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	info := RouteInfo{Credentials: true}
+	return DescribeRoute(info, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var s *Session
+			c, cookieErr := r.Cookie(cfg.CookieName)
+			if cookieErr == nil {
+				var err error
+				if s, err = cfg.Store.Load(r.Context(), c.Value); err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+				if s != nil && !clockNow().Before(s.Expires) {
+					s = nil
+				}
+			}
+			if s == nil {
+				id, err := newSessionID()
+				if err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+				s = &Session{ID: id}
+			}
+			w.Header().Add("Vary", "Cookie")
+
+			hw := &hookWriter{
+				ResponseWriter: w,
+				beforeHeader: func(http.Header, int) {
+					if err := saveSession(r.Context(), cfg, s, w, cookieErr == nil); err != nil {
+						adapter.report(r, err)
+					}
+				},
+			}
+			next.ServeHTTP(hw, r.WithContext(WithSession(r.Context(), s)))
+			if !hw.wroteHeader {
+				hw.wroteHeader = true
+				hw.beforeHeader(w.Header(), http.StatusOK)
+			}
+		})
+	})
+}
+
+func saveSession(ctx context.Context, cfg SessionConfig, s *Session, w http.ResponseWriter, hadCookie bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.modified {
+		return nil
+	}
+	if s.oldID != "" {
+		if err := cfg.Store.Delete(ctx, s.oldID); err != nil {
+			return fmt.Errorf("deleting rotated session: %w", err)
+		}
+		s.oldID = ""
+	}
+
+	cookie := &http.Cookie{
+		Name:     cfg.CookieName,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+	}
+	if len(s.Values) == 0 {
+		if err := cfg.Store.Delete(ctx, s.ID); err != nil {
+			return fmt.Errorf("deleting session: %w", err)
+		}
+		if !hadCookie {
+			return nil
+		}
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return nil
+	}
+
+	s.Expires = clockNow().Add(cfg.TTL)
+	token, err := cfg.Store.Save(ctx, s)
+	if err != nil {
+		return fmt.Errorf("saving session: %w", err)
+	}
+	cookie.Value = token
+	cookie.Expires = s.Expires
+	http.SetCookie(w, cookie)
+	s.modified = false
+	return nil
+}
+
+// CookieSessionStore keeps the whole session in the cookie, encrypted like
+// SetEncryptedCookie. The first key encrypts; all keys are tried for
+// decryption, so keys can be rotated by prepending a new one.
+//
+// Sessions cannot be revoked server-side: Delete is a no-op and a copied
+// cookie stays valid until it expires. Encoded sessions must fit the 4 KiB
+// cookie limit.
+type CookieSessionStore struct {
+	keys [][]byte
+}
+
+type cookieSession struct {
+	ID      string                     `json:"id"`
+	Values  map[string]json.RawMessage `json:"v"`
+	Expires int64                      `json:"exp"`
+}
+
+// cookieSessionAD binds encrypted sessions to their purpose, since the store
+// does not know the cookie name.
+const cookieSessionAD = "httpx.session"
+
+func NewCookieSessionStore(keys ...[]byte) (*CookieSessionStore, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoCookieKeys
+	}
+	return &CookieSessionStore{keys: keys}, nil
+}
+
+func (s *CookieSessionStore) Load(_ context.Context, token string) (*Session, error) {
+	plain, ok := decryptCookieValue(cookieSessionAD, token, s.keys)
+	if !ok {
+		return nil, nil
+	}
+	var cs cookieSession
+	if json.Unmarshal([]byte(plain), &cs) != nil {
+		return nil, nil
+	}
+	return &Session{ID: cs.ID, Values: cs.Values, Expires: time.Unix(cs.Expires, 0)}, nil
+}
+
+func (s *CookieSessionStore) Save(_ context.Context, sess *Session) (string, error) {
+	plain, err := json.Marshal(cookieSession{ID: sess.ID, Values: sess.Values, Expires: sess.Expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+	token, err := encryptCookieValue(cookieSessionAD, string(plain), s.keys[0])
+	if err != nil {
+		return "", err
+	}
+	if len(token) > maxCookieSize {
+		return "", fmt.Errorf("httpx: session cookie of %d bytes exceeds %d", len(token), maxCookieSize)
+	}
+	return token, nil
+}
+
+func (s *CookieSessionStore) Delete(context.Context, string) error {
+	return nil
+}
+
+// MemorySessionStore keeps sessions in process memory. It suits single
+// instances and tests; use a shared store when running several replicas.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	values  map[string]json.RawMessage
+	expires time.Time
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+func (s *MemorySessionStore) Load(_ context.Context, token string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[token]
+	if !ok || !clockNow().Before(e.expires) {
+		return nil, nil
+	}
+	return &Session{ID: token, Values: maps.Clone(e.values), Expires: e.expires}, nil
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, sess *Session) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= 1024 {
+		s.prune(clockNow())
+	}
+	s.sessions[sess.ID] = memorySession{values: maps.Clone(sess.Values), expires: sess.Expires}
+	return sess.ID, nil
+}
+
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemorySessionStore) prune(now time.Time) {
+	for k, e := range s.sessions {
+		if !now.Before(e.expires) {
+			delete(s.sessions, k)
+		}
+	}
+}
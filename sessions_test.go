@@ -0,0 +1,115 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	cookieStore, err := NewCookieSessionStore([]byte("session-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]SessionStore{
+		"memory": NewMemorySessionStore(),
+		"cookie": cookieStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			adapter := NewDefaultHandlerAdapter(NewConfig())
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /anonymous", func(w http.ResponseWriter, r *http.Request) {})
+			mux.HandleFunc("POST /visit", func(w http.ResponseWriter, r *http.Request) {
+				s, _ := SessionFromContext(r.Context())
+				s.Set("theme", "dark")
+			})
+			mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+				if err := LoginSession(r.Context(), &Principal{Subject: "alice"}); err != nil {
+					t.Error(err)
+				}
+				s, _ := SessionFromContext(r.Context())
+				s.AddFlash("welcome")
+			})
+			mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+				s, _ := SessionFromContext(r.Context())
+				flashes := strings.Join(s.Flashes(), ",")
+				p, err := SessionAuthenticator{}.Authenticate(r)
+				if err != nil {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(p.Subject + " " + flashes))
+			})
+			mux.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+				if err := LogoutSession(r.Context()); err != nil {
+					t.Error(err)
+				}
+			})
+			h := SessionMiddleware(adapter, SessionConfig{Store: store})(mux)
+
+			send := func(method, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+				req := httptest.NewRequest(method, path, nil)
+				if cookie != nil {
+					req.AddCookie(cookie)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				for _, c := range w.Result().Cookies() {
+					if c.Name == "_session" {
+						return w, c
+					}
+				}
+				return w, nil
+			}
+
+			if _, c := send(http.MethodGet, "/anonymous", nil); c != nil {
+				t.Errorf("untouched session set cookie %v", c)
+			}
+
+			// A session planted before login must not survive it.
+			_, planted := send(http.MethodPost, "/visit", nil)
+			_, session := send(http.MethodPost, "/login", planted)
+			if session == nil || session.Value == planted.Value || !session.HttpOnly {
+				t.Fatalf("login cookie %v after %v", session, planted)
+			}
+			if name == "memory" {
+				if w, _ := send(http.MethodGet, "/me", planted); w.Code != http.StatusUnauthorized {
+					t.Errorf("rotated session still authenticates: %d %s", w.Code, w.Body)
+				}
+			}
+
+			w, next := send(http.MethodGet, "/me", session)
+			if got := w.Body.String(); got != "alice welcome" {
+				t.Errorf("first /me = %q", got)
+			}
+			if next != nil {
+				session = next
+			}
+			if w, _ := send(http.MethodGet, "/me", session); w.Body.String() != "alice " {
+				t.Errorf("second /me = %q, want the flash consumed", w.Body)
+			}
+
+			flipped := "A"
+			if session.Value[0] == 'A' {
+				flipped = "B"
+			}
+			tampered := &http.Cookie{Name: "_session", Value: flipped + session.Value[1:]}
+			if w, _ := send(http.MethodGet, "/me", tampered); w.Code != http.StatusUnauthorized {
+				t.Errorf("tampered cookie authenticated: %d %s", w.Code, w.Body)
+			}
+
+			_, cleared := send(http.MethodPost, "/logout", session)
+			if cleared == nil || cleared.MaxAge >= 0 {
+				t.Errorf("logout cookie %v, want it removed", cleared)
+			}
+			if name == "memory" {
+				if w, _ := send(http.MethodGet, "/me", session); w.Code != http.StatusUnauthorized {
+					t.Errorf("session authenticates after logout: %d", w.Code)
+				}
+			}
+		})
+	}
+}
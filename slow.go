@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultSlowThreshold is the SlowRequestConfig.Threshold used when none is
+// set.
+const DefaultSlowThreshold = time.Second
+
+type (
+	// SlowRequest describes a request that took longer than the configured
+	// threshold. It implements error so it can travel through an
+	// ErrorReporter; use errors.As to tell it apart from failures.
+	SlowRequest struct {
+		Method  string
+		Path    string
+		Pattern string
+		Status  int
+
+		Threshold time.Duration
+		Duration  time.Duration
+		// TimeToHeader is the time until the handler committed the
+		// response; the rest of Duration was spent writing the body.
+		TimeToHeader time.Duration
+	}
+
+	// SlowRequestConfig configures SlowRequests.
+	SlowRequestConfig struct {
+		// Threshold defaults to DefaultSlowThreshold.
+		Threshold time.Duration
+		// OnSlow receives every slow request. When nil, slow requests are
+		// reported through the adapter as *SlowRequest errors.
+		OnSlow func(r *http.Request, s *SlowRequest)
+	}
+)
+
+func (s *SlowRequest) Error() string {
+	route := cmp.Or(s.Pattern, s.Method+" "+s.Path)
+	return fmt.Sprintf("slow request: %s took %s (threshold %s, header after %s, status %d)",
+		route, s.Duration.Round(time.Millisecond), s.Threshold, s.TimeToHeader.Round(time.Millisecond), s.Status)
+}
+
+// BodyTime is the time spent writing the response body.
+func (s *SlowRequest) BodyTime() time.Duration {
+	return s.Duration - s.TimeToHeader
+}
+
+// SlowRequests flags requests that take longer than the threshold. The route
+// pattern is picked up from the router or ServeMux wrapped by the middleware.
+func SlowRequests(adapter *HandlerAdapter, cfg SlowRequestConfig) Middleware {
+	threshold := cmp.Or(cfg.Threshold, DefaultSlowThreshold)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := clockNow()
+			t := slowTimerPool.Get().(*slowTimer)
+			defer t.release()
+			t.hw.ResponseWriter = w
+			next.ServeHTTP(&t.hw, r)
+
+			d := clockSince(start)
+			if d < threshold {
+				return
+			}
+			headerAt := t.headerAt
+			if headerAt.IsZero() {
+				headerAt = clockNow()
+			}
+			s := &SlowRequest{
+				Method:       r.Method,
+				Path:         r.URL.Path,
+				Pattern:      r.Pattern,
+				Status:       t.status,
+				Threshold:    threshold,
+				Duration:     d,
+				TimeToHeader: headerAt.Sub(start),
+			}
+			if cfg.OnSlow != nil {
+				cfg.OnSlow(r, s)
+				return
+			}
+			adapter.report(r, s)
+		})
+	}
+}
+
+// slowTimer records when and with which status the response header was
+// written. Timers are pooled with their hook bound, so timing a request
+// allocates nothing.
+type slowTimer struct {
+	hw       hookWriter
+	headerAt time.Time
+	status   int
+}
+
+var slowTimerPool = sync.Pool{New: func() interface{} {
+	t := &slowTimer{status: http.StatusOK}
+	t.hw.beforeHeader = func(_ http.Header, status int) {
+		t.headerAt = clockNow()
+		t.status = status
+	}
+	return t
+}}
+
+func (t *slowTimer) release() {
+	t.hw.ResponseWriter, t.hw.wroteHeader = nil, false
+	t.headerAt, t.status = time.Time{}, http.StatusOK
+	slowTimerPool.Put(t)
+}
@@ -0,0 +1,201 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSSEHeartbeat is the keep-alive interval of SSE streams unless
+// overridden.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// ErrStreamClosed is returned when writing to a stream whose handler
+// returned.
+var ErrStreamClosed = errors.New("stream closed")
+
+type (
+	// EventStream writes server-sent events. It is safe for concurrent use.
+	EventStream struct {
+		w   http.ResponseWriter
+		ctx context.Context
+
+		mu     sync.Mutex
+		closed bool
+		stop   chan struct{}
+	}
+
+	// Event is a single server-sent event. Data that is not a string or
+	// []byte is encoded as JSON.
+	Event struct {
+		ID    string
+		Event string
+		Data  interface{}
+		// Retry advises the client's reconnection delay.
+		Retry time.Duration
+	}
+
+	SSEOption func(*sseConfig)
+
+	sseConfig struct {
+		heartbeat time.Duration
+	}
+)
+
+// WithSSEHeartbeat sets the interval of keep-alive comments, which stop
+// proxies from closing idle streams. Zero disables them.
+func WithSSEHeartbeat(d time.Duration) SSEOption {
+	return func(c *sseConfig) {
+		c.heartbeat = d
+	}
+}
+
+// SSE turns the response into an event stream and runs fn with it until fn
+// returns or the client disconnects (the stream's Done channel).
+//
+// Setup failures, such as a writer that cannot flush, are returned before
+// anything is written so the adapter renders them. Once streaming started,
+// errors from fn are wrapped in *CommittedError and only reported.
+func SSE(w http.ResponseWriter, r *http.Request, fn func(*EventStream) error, opts ...SSEOption) error {
+	cfg := sseConfig{heartbeat: DefaultSSEHeartbeat}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !canFlush(w) {
+		return fmt.Errorf("event stream: %w", http.ErrNotSupported)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	// Streams outlive the server's WriteTimeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	s := &EventStream{w: w, ctx: r.Context(), stop: make(chan struct{})}
+	defer s.close()
+
+	if err := Flush(w); err != nil {
+		return &CommittedError{Err: err}
+	}
+	if cfg.heartbeat > 0 {
+		go s.heartbeat(cfg.heartbeat)
+	}
+
+	if err := fn(s); err != nil {
+		return &CommittedError{Err: err}
+	}
+	return nil
+}
+
+// Done is closed when the client disconnects.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes an event with the given type and data. An empty event uses
+// the client's default "message" type.
+func (s *EventStream) Send(event string, data interface{}) error {
+	return s.SendEvent(Event{Event: event, Data: data})
+}
+
+func (s *EventStream) SendEvent(e Event) error {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		writeSSEField(&buf, "id", e.ID)
+	}
+	if e.Event != "" {
+		writeSSEField(&buf, "event", e.Event)
+	}
+	if e.Retry > 0 {
+		writeSSEField(&buf, "retry", fmt.Sprint(e.Retry.Milliseconds()))
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := currentJSON().Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		writeSSEField(&buf, "data", line)
+	}
+	buf.WriteByte('\n')
+
+	return s.write(buf.Bytes())
+}
+
+// Comment writes a comment line, ignored by clients.
+func (s *EventStream) Comment(text string) error {
+	return s.write([]byte(": " + strings.ReplaceAll(text, "\n", " ") + "\n\n"))
+}
+
+func (s *EventStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	return Flush(s.w)
+}
+
+func (s *EventStream) heartbeat(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if s.write([]byte(":\n\n")) != nil {
+				return
+			}
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// close stops the heartbeat; the writer must not be used after the handler
+// returned.
+func (s *EventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+}
+
+var sseFieldReplacer = strings.NewReplacer("\r", "", "\n", " ")
+
+func writeSSEField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(sseFieldReplacer.Replace(value))
+	buf.WriteByte('\n')
+}
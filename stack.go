@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// errorStack returns the stack trace recorded by err or the first error in
+// its chain that has one. Besides *PanicError it recognizes the
+// StackTrace() method of github.com/pkg/errors and compatible packages
+// without depending on them.
+func errorStack(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if p, ok := e.(*PanicError); ok && len(p.Stack) > 0 {
+			return string(p.Stack)
+		}
+
+		m := reflect.ValueOf(e).MethodByName("StackTrace")
+		if m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+			return fmt.Sprintf("%+v", m.Call(nil)[0].Interface())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package httpx
+
+import (
+	"net/http"
+)
+
+// StreamErrorTrailer is the trailer set by Stream with WithErrorTrailer when
+// the stream fails after the status was sent.
+const StreamErrorTrailer = "X-Stream-Error"
+
+type (
+	// StreamWriter is the response writer passed to Stream callbacks.
+	StreamWriter struct {
+		http.ResponseWriter
+		cw *commitWriter
+	}
+
+	StreamOption func(*streamConfig)
+
+	streamConfig struct {
+		trailer bool
+	}
+)
+
+// WithErrorTrailer declares StreamErrorTrailer up front and fills it when
+// the stream fails mid-way, so clients reading trailers can tell a complete
+// body from a truncated one. The trailer carries the error class (status
+// text and AppError code), never the internal message.
+func WithErrorTrailer() StreamOption {
+	return func(c *streamConfig) {
+		c.trailer = true
+	}
+}
+
+// Stream runs fn to produce a streamed response. Errors returned before
+// anything was written are returned unchanged for the adapter to render.
+// Errors after the status was sent are wrapped in *CommittedError, so the
+// adapter reports them without attempting a second status line, and the
+// response ends cleanly at the last written byte.
+func Stream(w http.ResponseWriter, r *http.Request, fn func(sw *StreamWriter) error, opts ...StreamOption) error {
+	var cfg streamConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.trailer {
+		w.Header().Add("Trailer", StreamErrorTrailer)
+	}
+
+	cw := &commitWriter{ResponseWriter: w}
+	err := fn(&StreamWriter{ResponseWriter: cw, cw: cw})
+	if err == nil {
+		return nil
+	}
+	if !cw.committed {
+		if cfg.trailer {
+			w.Header().Del("Trailer")
+		}
+		return err
+	}
+
+	if cfg.trailer {
+		c := Classify(err)
+		value := http.StatusText(c.Status)
+		if c.Code != "" {
+			value += "; code=" + c.Code
+		}
+		w.Header().Set(StreamErrorTrailer, value)
+	}
+	return &CommittedError{Err: err}
+}
+
+// Flush sends buffered data to the client.
+func (sw *StreamWriter) Flush() {
+	sw.cw.FlushError()
+}
+
+func (sw *StreamWriter) FlushError() error {
+	return sw.cw.FlushError()
+}
+
+// Committed reports whether the status was sent, after which errors can no
+// longer be rendered.
+func (sw *StreamWriter) Committed() bool {
+	return sw.cw.committed
+}
+
+func (sw *StreamWriter) Unwrap() http.ResponseWriter {
+	return sw.cw
+}
@@ -0,0 +1,147 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// SwappableHandler is an http.Handler whose implementation can be
+	// replaced atomically while serving. Register it once on the router and
+	// swap the handler behind it at runtime.
+	SwappableHandler struct {
+		original *handlerRef
+		current  atomic.Pointer[handlerRef]
+	}
+
+	// KillSwitches is a registry of named swappable routes, intended to be
+	// driven by an operational config endpoint.
+	KillSwitches struct {
+		adapter *HandlerAdapter
+
+		mu       sync.RWMutex
+		handlers map[string]*SwappableHandler
+	}
+
+	handlerRef struct {
+		h http.Handler
+	}
+)
+
+func NewSwappableHandler(h http.Handler) *SwappableHandler {
+	s := &SwappableHandler{original: &handlerRef{h: h}}
+	s.current.Store(s.original)
+	return s
+}
+
+func (s *SwappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.current.Load().h.ServeHTTP(w, r)
+}
+
+// Swap installs h and returns the previously active handler. In-flight
+// requests finish on the handler they started with.
+func (s *SwappableHandler) Swap(h http.Handler) http.Handler {
+	return s.current.Swap(&handlerRef{h: h}).h
+}
+
+// Restore reinstalls the handler the SwappableHandler was created with.
+func (s *SwappableHandler) Restore() {
+	s.current.Store(s.original)
+}
+
+// Swapped reports whether a handler other than the original is active.
+func (s *SwappableHandler) Swapped() bool {
+	return s.current.Load() != s.original
+}
+
+// DisabledHandler responds with a 503 AppError through the adapter. It is
+// the default responder for disabled kill switches.
+func DisabledHandler(adapter *HandlerAdapter) http.Handler {
+	return adapter.Handle(func(http.ResponseWriter, *http.Request) error {
+		return ServiceUnavailableError("this feature is currently disabled").WithCode("feature_disabled")
+	})
+}
+
+func NewKillSwitches(adapter *HandlerAdapter) *KillSwitches {
+	return &KillSwitches{
+		adapter:  adapter,
+		handlers: map[string]*SwappableHandler{},
+	}
+}
+
+// Register wraps h in a SwappableHandler under name. The returned handler is
+// what should be registered on the router.
+func (k *KillSwitches) Register(name string, h http.Handler) *SwappableHandler {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.handlers[name]; ok {
+		panic("httpx: kill switch registered twice: " + name)
+	}
+	s := NewSwappableHandler(h)
+	k.handlers[name] = s
+	return s
+}
+
+// Disable swaps the named route to responder, or to DisabledHandler when
+// responder is nil.
+func (k *KillSwitches) Disable(name string, responder http.Handler) error {
+	s, err := k.get(name)
+	if err != nil {
+		return err
+	}
+	if responder == nil {
+		responder = DisabledHandler(k.adapter)
+	}
+	s.Swap(responder)
+	return nil
+}
+
+func (k *KillSwitches) Enable(name string) error {
+	s, err := k.get(name)
+	if err != nil {
+		return err
+	}
+	s.Restore()
+	return nil
+}
+
+// State returns the registered switch names mapped to whether they are
+// enabled.
+func (k *KillSwitches) State() map[string]bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	state := make(map[string]bool, len(k.handlers))
+	for name, s := range k.handlers {
+		state[name] = !s.Swapped()
+	}
+	return state
+}
+
+// Names returns the registered switch names in sorted order.
+func (k *KillSwitches) Names() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	names := make([]string, 0, len(k.handlers))
+	for name := range k.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (k *KillSwitches) get(name string) (*SwappableHandler, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	s, ok := k.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown kill switch %q", name)
+	}
+	return s, nil
+}
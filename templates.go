@@ -0,0 +1,209 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+type (
+	// TemplateRenderer renders html/template pages composed of shared
+	// layouts and partials, and implements Renderer with an error page so
+	// server-rendered apps get consistent error responses.
+	//
+	// Each page is parsed together with every layout and partial and
+	// executed through Entry, so pages only {{define}} the blocks the
+	// layout leaves open. Parsed pages are cached unless Reload is set.
+	TemplateRenderer struct {
+		FS fs.FS
+		// Layouts and Partials are glob patterns, "layouts/*.html" and
+		// "partials/*.html" by default. Missing matches are fine.
+		Layouts  string
+		Partials string
+		// Pages is the directory of page templates, "pages" by default. A
+		// page name "users/show" resolves to "pages/users/show.html".
+		Pages string
+		// Entry is the template executed, "base" by default.
+		Entry string
+		// ErrorPage is the page used by Render500 and RenderAppError,
+		// "error" by default. It receives an ErrorPageData as Data.
+		ErrorPage string
+		Funcs     template.FuncMap
+		// Reload re-parses templates on every render, for development.
+		Reload bool
+
+		mu    sync.Mutex
+		cache map[string]*template.Template
+	}
+
+	// PageData is the value templates execute with: the handler's data plus
+	// request-scoped values injected from the context.
+	PageData struct {
+		Data      interface{}
+		RequestID string
+		Principal *Principal
+		CSRFToken string
+	}
+
+	// ErrorPageData is the Data of error pages.
+	ErrorPageData struct {
+		Status  int
+		Title   string
+		Message string
+		Code    string
+		// RetryAfter is AppError.RetryAfter.
+		RetryAfter time.Duration
+		// Method and Path identify the failed request.
+		Method string
+		Path   string
+		// Info holds the details of internal errors as passed to Render500.
+		Info *ErrorInfo
+	}
+)
+
+var _ RequestRenderer = (*TemplateRenderer)(nil)
+
+// Render executes page with data and writes it with status. The page is
+// rendered to a buffer first, so template errors are returned before
+// anything is written.
+func (t *TemplateRenderer) Render(w http.ResponseWriter, r *http.Request, status int, page string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.execute(r.Context(), &buf, page, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (t *TemplateRenderer) Render500(ctx context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	t.renderError(ctx, w, internalErrorPage(errInfo))
+}
+
+func (t *TemplateRenderer) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError) {
+	t.renderError(ctx, w, appErrorPage(appErr))
+}
+
+func (t *TemplateRenderer) Render500Request(w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo) {
+	data := internalErrorPage(errInfo)
+	data.Method, data.Path = r.Method, r.URL.Path
+	t.renderError(r.Context(), w, data)
+}
+
+func (t *TemplateRenderer) RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr AppError) {
+	data := appErrorPage(appErr)
+	data.Method, data.Path = r.Method, r.URL.Path
+	t.renderError(r.Context(), w, data)
+}
+
+func internalErrorPage(errInfo *ErrorInfo) ErrorPageData {
+	data := ErrorPageData{
+		Status: http.StatusInternalServerError,
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Info:   errInfo,
+	}
+	if errInfo != nil {
+		data.Message = errInfo.Message
+	}
+	return data
+}
+
+func appErrorPage(appErr AppError) ErrorPageData {
+	return ErrorPageData{
+		Status:     appErr.StatusCode,
+		Title:      http.StatusText(appErr.StatusCode),
+		Message:    appErr.Error(),
+		Code:       appErr.Code,
+		RetryAfter: appErr.RetryAfter,
+	}
+}
+
+func (t *TemplateRenderer) renderError(ctx context.Context, w http.ResponseWriter, data ErrorPageData) {
+	var buf bytes.Buffer
+	if err := t.execute(ctx, &buf, orDefault(t.ErrorPage, "error"), data); err != nil {
+		// A broken error page must not hide the original error.
+		http.Error(w, data.Title, data.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.Status)
+	buf.WriteTo(w)
+}
+
+func (t *TemplateRenderer) execute(ctx context.Context, buf *bytes.Buffer, page string, data interface{}) error {
+	tmpl, err := t.template(page)
+	if err != nil {
+		return err
+	}
+
+	pd := PageData{
+		Data:      data,
+		RequestID: RequestIDFromContext(ctx),
+		CSRFToken: CSRFToken(ctx),
+	}
+	pd.Principal, _ = PrincipalFromContext(ctx)
+
+	return tmpl.ExecuteTemplate(buf, orDefault(t.Entry, "base"), pd)
+}
+
+func (t *TemplateRenderer) template(page string) (*template.Template, error) {
+	if !t.Reload {
+		t.mu.Lock()
+		tmpl, ok := t.cache[page]
+		t.mu.Unlock()
+		if ok {
+			return tmpl, nil
+		}
+	}
+
+	tmpl, err := t.parse(page)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.Reload {
+		t.mu.Lock()
+		if t.cache == nil {
+			t.cache = map[string]*template.Template{}
+		}
+		t.cache[page] = tmpl
+		t.mu.Unlock()
+	}
+	return tmpl, nil
+}
+
+func (t *TemplateRenderer) parse(page string) (*template.Template, error) {
+	var files []string
+	for _, pattern := range []string{orDefault(t.Layouts, "layouts/*.html"), orDefault(t.Partials, "partials/*.html")} {
+		matches, err := fs.Glob(t.FS, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("template pattern %s: %w", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	files = append(files, path.Join(orDefault(t.Pages, "pages"), page+".html"))
+
+	tmpl := template.New(page).Funcs(FormFuncs())
+	if t.Funcs != nil {
+		tmpl = tmpl.Funcs(t.Funcs)
+	}
+	tmpl, err := tmpl.ParseFS(t.FS, files...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page %s: %w", page, err)
+	}
+	return tmpl, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
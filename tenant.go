@@ -0,0 +1,177 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Tenant is the customer a request is served for, with its overrides
+	// of the application defaults. Nil overrides keep the defaults.
+	Tenant struct {
+		ID string
+		// Renderer renders the tenant's errors, e.g. with its branding.
+		// It takes effect through TenantRenderer.
+		Renderer Renderer
+		// Reporter receives the tenant's errors. It takes effect through
+		// TenantReporter.
+		Reporter ErrorReporter
+		// Limiter caps the tenant's concurrent requests. Use one limiter
+		// per tenant, not one per request.
+		Limiter *ConcurrencyLimiter
+		// Attributes carries application specific data (plan, settings).
+		Attributes map[string]interface{}
+	}
+
+	// TenantSource extracts a tenant ID from the request.
+	TenantSource func(r *http.Request) (string, bool)
+
+	// TenantConfig configures TenantResolver.
+	TenantConfig struct {
+		// Sources are tried in order; the first ID found is used.
+		Sources []TenantSource
+		// Lookup resolves an ID to its Tenant, usually from a cache or
+		// database. It returns nil for unknown tenants. Nil accepts every
+		// ID as a Tenant without overrides.
+		Lookup func(ctx context.Context, id string) (*Tenant, error)
+		// Optional lets requests without a tenant ID through, for shared
+		// pages such as the apex site. Unknown IDs are still rejected.
+		Optional bool
+	}
+
+	// TenantRenderer renders errors with the request's Tenant.Renderer,
+	// falling back to Default.
+	TenantRenderer struct {
+		Default Renderer
+	}
+)
+
+var (
+	tenantKey = NewContextKey[*Tenant]("tenant")
+
+	_ RequestRenderer = TenantRenderer{}
+)
+
+// TenantFromSubdomain takes the ID from the label matched by a HostRouter
+// wildcard, see SubdomainFromContext.
+func TenantFromSubdomain() TenantSource {
+	return func(r *http.Request) (string, bool) {
+		return SubdomainFromContext(r.Context())
+	}
+}
+
+// TenantFromHeader takes the ID from a request header, for trusted callers
+// such as internal services or a gateway that already resolved it.
+func TenantFromHeader(name string) TenantSource {
+	return func(r *http.Request) (string, bool) {
+		id := strings.TrimSpace(r.Header.Get(name))
+		return id, id != ""
+	}
+}
+
+// TenantFromClaim takes the ID from a string claim of the request's JWT,
+// so install TenantResolver after the authentication middleware.
+func TenantFromClaim(name string) TenantSource {
+	return func(r *http.Request) (string, bool) {
+		id, ok := JWTClaim[string](r.Context(), name)
+		return id, ok && id != ""
+	}
+}
+
+// TenantResolver resolves the request's Tenant, stores it in the context
+// and tags error reports with its ID (see ReportContext). Requests without
+// a tenant ID are rejected with a 400 AppError (code "missing_tenant")
+// unless cfg.Optional is set, and unknown tenants with a 404 (code
+// "unknown_tenant"). Lookup errors are passed to the adapter as they are.
+//
+// The tenant's Limiter is applied here; its Renderer and Reporter take
+// effect through TenantRenderer and TenantReporter.
+func TenantResolver(adapter *HandlerAdapter, cfg TenantConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var id string
+			var found bool
+			for _, source := range cfg.Sources {
+				if id, found = source(r); found {
+					break
+				}
+			}
+			if !found {
+				if cfg.Optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				adapter.HandleError(w, r, BadRequestError("tenant required").WithCode("missing_tenant"))
+				return
+			}
+
+			t := &Tenant{ID: id}
+			if cfg.Lookup != nil {
+				var err error
+				if t, err = cfg.Lookup(r.Context(), id); err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+				if t == nil {
+					adapter.HandleError(w, r, NotFoundError("unknown tenant %q", id).WithCode("unknown_tenant"))
+					return
+				}
+			}
+
+			ctx := SetReportTag(WithTenant(r.Context(), t), "tenant", t.ID)
+			r = r.WithContext(ctx)
+			if t.Limiter != nil {
+				t.Limiter.serve(w, r, next)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return tenantKey.WithValue(ctx, t)
+}
+
+func TenantFromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := tenantKey.Value(ctx)
+	return t, ok && t != nil
+}
+
+// TenantReporter sends errors to the Reporter of the tenant they occurred
+// for, and to def otherwise.
+func TenantReporter(def ErrorReporter) ErrorReporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		if t, ok := TenantFromContext(ctx); ok && t.Reporter != nil {
+			t.Reporter.ReportError(ctx, err)
+			return
+		}
+		if def != nil {
+			def.ReportError(ctx, err)
+		}
+	})
+}
+
+func (tr TenantRenderer) Render500(ctx context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	tr.pick(ctx).Render500(ctx, w, errInfo)
+}
+
+func (tr TenantRenderer) RenderAppError(ctx context.Context, w http.ResponseWriter, appErr AppError) {
+	tr.pick(ctx).RenderAppError(ctx, w, appErr)
+}
+
+func (tr TenantRenderer) Render500Request(w http.ResponseWriter, r *http.Request, errInfo *ErrorInfo) {
+	Render500(tr.pick(r.Context()), w, r, errInfo)
+}
+
+func (tr TenantRenderer) RenderAppErrorRequest(w http.ResponseWriter, r *http.Request, appErr AppError) {
+	RenderAppError(tr.pick(r.Context()), w, r, appErr)
+}
+
+func (tr TenantRenderer) pick(ctx context.Context) Renderer {
+	if t, ok := TenantFromContext(ctx); ok && t.Renderer != nil {
+		return t.Renderer
+	}
+	return tr.Default
+}
@@ -0,0 +1,173 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// W3C Trace Context headers.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+type (
+	// Trace is the W3C trace context of a request, for correlating logs
+	// and error reports across services without a tracing SDK.
+	Trace struct {
+		// TraceID is the 32 hex digit ID shared by every service the
+		// request passes through.
+		TraceID string
+		// SpanID is the 16 hex digit ID of this server's span, sent as the
+		// parent ID on outbound requests.
+		SpanID string
+		// ParentID is the caller's span ID, empty when the trace started
+		// here.
+		ParentID string
+		// Sampled is the caller's sampling decision; false for traces
+		// started here.
+		Sampled bool
+		// State is the inbound tracestate, forwarded verbatim.
+		State string
+	}
+
+	traceLogHandler struct {
+		slog.Handler
+	}
+)
+
+var traceKey = NewContextKey[Trace]("trace")
+
+// TraceContext joins the trace of a well-formed inbound traceparent, or
+// starts a new one, and stores it in the request context with a new span
+// ID; see TraceFromContext. PropagateContext forwards it on outbound
+// calls, and SlogReporter and TraceLogHandler add its IDs to logs. Install
+// it next to RequestID, or rely on an OTel middleware that injects the
+// headers itself.
+func TraceContext() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := Trace{SpanID: randomHex(8)}
+			if tp := r.Header.Get(TraceparentHeader); validTraceparent(tp) {
+				flags, _ := hex.DecodeString(tp[53:])
+				t.TraceID, t.ParentID, t.Sampled = tp[3:35], tp[36:52], flags[0]&1 == 1
+				t.State = r.Header.Get(TracestateHeader)
+			} else {
+				t.TraceID = randomHex(16)
+			}
+			next.ServeHTTP(w, r.WithContext(WithTrace(r.Context(), t)))
+		})
+	}
+}
+
+func WithTrace(ctx context.Context, t Trace) context.Context {
+	return traceKey.WithValue(ctx, t)
+}
+
+// TraceFromContext returns the trace recorded by TraceContext.
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	return traceKey.Value(ctx)
+}
+
+// Traceparent formats the traceparent header for requests made within the
+// span.
+func (t Trace) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + flags
+}
+
+// PropagateContext copies the request ID and trace context of the inbound
+// request onto outbound requests made with its context, unless they set
+// those headers already.
+//
+//	client, _ := httpx.NewClient(base, httpx.WithClientMiddleware(httpx.PropagateContext()))
+//	err := client.Get("/items").Do(r.Context(), &items)
+func PropagateContext() ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			id := RequestIDFromContext(ctx)
+			trace, hasTrace := TraceFromContext(ctx)
+			setID := id != "" && req.Header.Get(RequestIDHeader) == ""
+			setTrace := hasTrace && req.Header.Get(TraceparentHeader) == ""
+			if !setID && !setTrace {
+				return next.RoundTrip(req)
+			}
+
+			req = cloneForHeaders(req)
+			if setID {
+				req.Header.Set(RequestIDHeader, id)
+			}
+			if setTrace {
+				req.Header.Set(TraceparentHeader, trace.Traceparent())
+				if trace.State != "" {
+					req.Header.Set(TracestateHeader, trace.State)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// TraceLogHandler adds the request ID and the trace and span IDs of the
+// context to records logged with the *Context methods of slog.Logger.
+//
+//	slog.SetDefault(slog.New(httpx.TraceLogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+func TraceLogHandler(h slog.Handler) slog.Handler {
+	return traceLogHandler{Handler: h}
+}
+
+func (h traceLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	r.AddAttrs(traceAttrs(ctx)...)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceLogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceLogHandler) WithGroup(name string) slog.Handler {
+	return traceLogHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// traceAttrs returns the trace and span IDs of ctx, if any.
+func traceAttrs(ctx context.Context) []slog.Attr {
+	t, ok := TraceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []slog.Attr{slog.String("trace_id", t.TraceID), slog.String("span_id", t.SpanID)}
+}
+
+// validTraceparent checks the version-00 layout
+// "00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>".
+func validTraceparent(tp string) bool {
+	if len(tp) != 55 || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return false
+	}
+	for i := 0; i < len(tp); i++ {
+		if i == 2 || i == 35 || i == 52 {
+			continue
+		}
+		c := tp[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return tp[:2] != "ff" && tp[3:35] != "00000000000000000000000000000000" && tp[36:52] != "0000000000000000"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,178 @@
+package httpx
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// TrafficConfig configures Debugger.RecordTraffic. Zero values select
+	// the defaults noted on each field.
+	TrafficConfig struct {
+		// Entries is the number of exchanges kept; 20 if zero.
+		Entries int
+		// MaxBody caps each captured body; larger bodies are replaced by a
+		// placeholder. 64 KiB if zero.
+		MaxBody int
+		// ContentTypes lists the media types whose bodies are captured;
+		// a trailing "/*" matches a whole type. JSON, XML, forms and text
+		// by default.
+		ContentTypes []string
+	}
+
+	// RecordedExchange is one request and response kept by RecordTraffic.
+	// Credentials in headers are masked.
+	RecordedExchange struct {
+		Time           time.Time     `json:"time"`
+		Duration       time.Duration `json:"duration"`
+		Method         string        `json:"method"`
+		URL            string        `json:"url"`
+		RequestHeader  http.Header   `json:"request_header"`
+		RequestBody    string        `json:"request_body,omitempty"`
+		Status         int           `json:"status"`
+		ResponseHeader http.Header   `json:"response_header"`
+		ResponseBody   string        `json:"response_body,omitempty"`
+	}
+
+	trafficRing struct {
+		mu      sync.Mutex
+		entries []RecordedExchange
+		next    int
+		size    int
+	}
+
+	captureReader struct {
+		io.ReadCloser
+		buf      bytes.Buffer
+		limit    int
+		overflow bool
+	}
+)
+
+var defaultTrafficTypes = []string{
+	"application/json", "application/problem+json", "application/xml",
+	"application/x-www-form-urlencoded", "text/*",
+}
+
+// sensitiveHeaders are masked in recorded exchanges.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// RecordTraffic captures recent requests and responses, bodies included,
+// for the debug endpoint. It records only when config.IsDevelopment() and
+// passes requests through untouched otherwise, so it can stay in the chain
+// in every environment.
+func (d *Debugger) RecordTraffic(config AppConfig, cfg TrafficConfig) Middleware {
+	if config == nil || !config.IsDevelopment() {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if cfg.Entries <= 0 {
+		cfg.Entries = 20
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 64 << 10
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = defaultTrafficTypes
+	}
+	d.mu.Lock()
+	d.traffic = &trafficRing{size: cfg.Entries}
+	d.mu.Unlock()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			var body *captureReader
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &captureReader{ReadCloser: r.Body, limit: cfg.MaxBody}
+				r.Body = body
+			}
+			rw := &recordWriter{ResponseWriter: w, limit: cfg.MaxBody}
+			next.ServeHTTP(rw, r)
+
+			e := RecordedExchange{
+				Time:          start,
+				Duration:      time.Since(start),
+				Method:        r.Method,
+				URL:           r.URL.String(),
+				RequestHeader: maskHeader(r.Header),
+			}
+			if body != nil {
+				e.RequestBody = capturedBody(cfg, r.Header, body.buf.Bytes(), body.overflow)
+			}
+			if status, header, respBody, ok := rw.recorded(); ok {
+				e.Status, e.ResponseHeader = status, maskHeader(header)
+				e.ResponseBody = capturedBody(cfg, header, respBody, false)
+			} else {
+				e.Status, e.ResponseHeader = rw.status, maskHeader(rw.header)
+				e.ResponseBody = capturedBody(cfg, rw.header, nil, true)
+			}
+			d.traffic.add(e)
+		})
+	}
+}
+
+func capturedBody(cfg TrafficConfig, h http.Header, body []byte, overflow bool) string {
+	switch {
+	case overflow:
+		return "[body larger than limit]"
+	case len(body) == 0:
+		return ""
+	}
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if h.Get("Content-Encoding") != "" || !mediaTypeAllowed(mt, cfg.ContentTypes) {
+		return "[" + cmp.Or(mt, "unknown type") + " body omitted]"
+	}
+	return string(body)
+}
+
+func maskHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for k := range h {
+		if slices.ContainsFunc(sensitiveHeaders, func(s string) bool { return strings.EqualFold(s, k) }) {
+			h[k] = []string{"[redacted]"}
+		}
+	}
+	return h
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if !r.overflow {
+		if r.buf.Len()+n > r.limit {
+			r.overflow = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+func (t *trafficRing) add(e RecordedExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.entries) < t.size {
+		t.entries = append(t.entries, e)
+	} else {
+		t.entries[t.next] = e
+	}
+	t.next = (t.next + 1) % t.size
+}
+
+// recent returns the kept exchanges, newest first.
+func (t *trafficRing) recent() []RecordedExchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]RecordedExchange, 0, len(t.entries))
+	for i := range len(t.entries) {
+		out = append(out, t.entries[(t.next-1-i+len(t.entries))%len(t.entries)])
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// UpstreamError is a failure of a dependency, such as a database or another
+// service, so outages are told apart from bugs. Returned from a handler,
+// it is reported with the service name and rendered as a 504 AppError
+// (code "upstream_timeout") when the dependency timed out and a 502 (code
+// "upstream_error") otherwise; the upstream's own message is not shown.
+//
+//	if err := db.QueryRowContext(ctx, q, id).Scan(&u); err != nil {
+//		return httpx.WrapUpstream("postgres", "load user", err)
+//	}
+type UpstreamError struct {
+	// Service names the dependency, e.g. "postgres" or "payments".
+	Service string
+	// Op describes the failed operation, e.g. "GET /charges/{id}".
+	Op  string
+	Err error
+	// Status is the dependency's HTTP status, 0 when it did not respond.
+	Status int
+}
+
+func (e *UpstreamError) Error() string {
+	msg := "upstream " + e.Service
+	if e.Op != "" {
+		msg += ": " + e.Op
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the dependency timed out, locally or on its side.
+func (e *UpstreamError) Timeout() bool {
+	var netErr net.Error
+	return errors.Is(e.Err, context.DeadlineExceeded) ||
+		errors.As(e.Err, &netErr) && netErr.Timeout() ||
+		e.Status == http.StatusGatewayTimeout
+}
+
+// appError is the response rendered for e.
+func (e *UpstreamError) appError() AppError {
+	if e.Timeout() {
+		return WrapStatus(e, http.StatusGatewayTimeout, "upstream timeout").WithCode("upstream_timeout")
+	}
+	return WrapStatus(e, http.StatusBadGateway, "").WithCode("upstream_error")
+}
+
+// WrapUpstream wraps err in an UpstreamError for service, taking the status
+// from Client errors. It returns nil for nil errors, and err itself when it
+// already is an UpstreamError or the caller's context was canceled.
+func WrapUpstream(service, op string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return err
+	}
+	e := &UpstreamError{Service: service, Op: op, Err: err}
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		e.Status = respErr.StatusCode
+	}
+	return e
+}
+
+func isUpstream(err error) bool {
+	_, ok := asUpstream(err)
+	return ok
+}
+
+// asUpstream finds the UpstreamError in err, unless err is an AppError
+// choosing its own response.
+func asUpstream(err error) (*UpstreamError, bool) {
+	if _, ok := err.(AppError); ok {
+		return nil, false
+	}
+	var upstreamErr *UpstreamError
+	ok := errors.As(err, &upstreamErr)
+	return upstreamErr, ok
+}
@@ -0,0 +1,60 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type (
+	// FieldError describes why a single input field was rejected.
+	FieldError struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+		Code    string `json:"code,omitempty"`
+	}
+
+	// ValidationError collects field errors of a rejected input. Wrap it with
+	// ValidationFailed to return it from a handler.
+	ValidationError struct {
+		Fields []FieldError `json:"fields"`
+	}
+)
+
+// ValidationFailed returns a 422 AppError carrying the field errors.
+func ValidationFailed(fields ...FieldError) AppError {
+	return AppError{
+		Err:        &ValidationError{Fields: fields},
+		StatusCode: http.StatusUnprocessableEntity,
+		Code:       "validation_failed",
+	}
+}
+
+func (v *ValidationError) Error() string {
+	msgs := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Add appends a field error.
+func (v *ValidationError) Add(field, message string) {
+	v.Fields = append(v.Fields, FieldError{Field: field, Message: message})
+}
+
+// ByField groups the messages by field name.
+func (v *ValidationError) ByField() map[string][]string {
+	m := make(map[string][]string, len(v.Fields))
+	for _, f := range v.Fields {
+		m[f.Field] = append(m[f.Field], f.Message)
+	}
+	return m
+}
+
+// AsValidationError extracts a ValidationError from err's chain.
+func AsValidationError(err error) (*ValidationError, bool) {
+	var v *ValidationError
+	ok := errors.As(err, &v)
+	return v, ok
+}
@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrNotReady is returned by Server.ReadinessCheck until warm-up completed.
+var ErrNotReady = errors.New("server not ready")
+
+// ErrDraining is returned by Server.ReadinessCheck once shutdown started.
+// It matches ErrNotReady with errors.Is.
+var ErrDraining = fmt.Errorf("%w: draining", ErrNotReady)
+
+type (
+	// WarmupOption configures a single warm-up hook.
+	WarmupOption func(*warmupHook)
+
+	warmupHook struct {
+		name     string
+		fn       func(context.Context) error
+		timeout  time.Duration
+		optional bool
+	}
+)
+
+// WithWarmupTimeout bounds a warm-up hook. Zero means no timeout besides the
+// context passed to Run.
+func WithWarmupTimeout(d time.Duration) WarmupOption {
+	return func(h *warmupHook) {
+		h.timeout = d
+	}
+}
+
+// WithWarmupOptional lets the server start even if the hook fails. The
+// failure is reported through the adapter.
+func WithWarmupOptional() WarmupOption {
+	return func(h *warmupHook) {
+		h.optional = true
+	}
+}
+
+// OnWarmup registers a hook (prime caches, compile templates, open pools)
+// run in registration order before the listener accepts traffic. A failing
+// hook aborts Run unless it is optional.
+func (s *Server) OnWarmup(name string, fn func(ctx context.Context) error, opts ...WarmupOption) {
+	h := warmupHook{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	s.warmup = append(s.warmup, h)
+}
+
+// Ready reports whether warm-up completed and the server is serving.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// ReadinessCheck has the signature of a healthcheck.Check, so readiness
+// probes only pass once warm-up completed and fail as soon as draining
+// began.
+func (s *Server) ReadinessCheck(context.Context) error {
+	if s.Draining() {
+		return ErrDraining
+	}
+	if !s.Ready() {
+		return ErrNotReady
+	}
+	return nil
+}
+
+func (s *Server) runWarmup(ctx context.Context) error {
+	for _, h := range s.warmup {
+		err := h.run(ctx)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("warm-up %s: %w", h.name, err)
+		if !h.optional {
+			return err
+		}
+		if s.Adapter != nil && s.Adapter.Reporter != nil {
+			s.Adapter.Reporter.ReportError(ctx, err)
+		} else {
+			log.Printf("httpx: %v", err)
+		}
+	}
+	return nil
+}
+
+func (h warmupHook) run(ctx context.Context) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	return h.fn(ctx)
+}
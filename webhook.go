@@ -0,0 +1,177 @@
+package httpx
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWebhookTolerance = 5 * time.Minute
+	DefaultWebhookMaxBody   = 1 << 20
+)
+
+type (
+	// WebhookScheme describes how a provider transmits its signature.
+	WebhookScheme struct {
+		// Hash is the HMAC hash; SHA-256 if nil.
+		Hash func() hash.Hash
+		// Signatures extracts the candidate signatures and, for schemes
+		// that sign a timestamp, the timestamp from the request. A zero
+		// timestamp disables the tolerance check.
+		Signatures func(r *http.Request) (ts time.Time, sigs [][]byte, err error)
+		// Payload returns the signed message; the raw body if nil.
+		Payload func(r *http.Request, ts time.Time, body []byte) []byte
+	}
+
+	// WebhookConfig configures VerifyWebhook and WebhookSignature.
+	WebhookConfig struct {
+		Scheme WebhookScheme
+		// Secrets are tried in order, so a provider secret can be rotated
+		// by adding the new one before the old one is retired.
+		Secrets [][]byte
+		// Tolerance bounds the age of signed timestamps; it defaults to
+		// DefaultWebhookTolerance.
+		Tolerance time.Duration
+		// MaxBody defaults to DefaultWebhookMaxBody.
+		MaxBody int64
+	}
+)
+
+// HeaderWebhookScheme signs the raw body and sends the hex encoded HMAC in
+// header, after prefix.
+func HeaderWebhookScheme(header, prefix string) WebhookScheme {
+	return WebhookScheme{
+		Signatures: func(r *http.Request) (time.Time, [][]byte, error) {
+			v, ok := strings.CutPrefix(r.Header.Get(header), prefix)
+			if !ok || v == "" {
+				return time.Time{}, nil, errors.New("missing signature")
+			}
+			sig, err := hex.DecodeString(v)
+			if err != nil {
+				return time.Time{}, nil, errors.New("malformed signature")
+			}
+			return time.Time{}, [][]byte{sig}, nil
+		},
+	}
+}
+
+var (
+	// GitHubWebhook verifies the X-Hub-Signature-256 header.
+	GitHubWebhook = HeaderWebhookScheme("X-Hub-Signature-256", "sha256=")
+
+	// StripeWebhook verifies the Stripe-Signature header, which carries a
+	// timestamp and one v1 signature per active secret.
+	StripeWebhook = WebhookScheme{
+		Signatures: func(r *http.Request) (time.Time, [][]byte, error) {
+			var ts time.Time
+			var sigs [][]byte
+			for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+				k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+				switch k {
+				case "t":
+					sec, err := strconv.ParseInt(v, 10, 64)
+					if err != nil {
+						return ts, nil, errors.New("invalid timestamp")
+					}
+					ts = time.Unix(sec, 0)
+				case "v1":
+					if sig, err := hex.DecodeString(v); err == nil {
+						sigs = append(sigs, sig)
+					}
+				}
+			}
+			if ts.IsZero() || len(sigs) == 0 {
+				return ts, nil, errors.New("missing signature")
+			}
+			return ts, sigs, nil
+		},
+		Payload: func(_ *http.Request, ts time.Time, body []byte) []byte {
+			return append([]byte(strconv.FormatInt(ts.Unix(), 10)+"."), body...)
+		},
+	}
+)
+
+// VerifyWebhook checks the signature of r over body. Failures are returned as
+// 401 AppErrors with code "invalid_signature".
+func VerifyWebhook(r *http.Request, body []byte, cfg WebhookConfig) error {
+	ts, sigs, err := cfg.Scheme.Signatures(r)
+	if err != nil {
+		return invalidSignatureError(err.Error())
+	}
+	if !ts.IsZero() {
+		tolerance := cmp.Or(cfg.Tolerance, DefaultWebhookTolerance)
+		if age := clockSince(ts); age > tolerance || age < -tolerance {
+			return invalidSignatureError("timestamp outside tolerance")
+		}
+	}
+
+	payload := body
+	if cfg.Scheme.Payload != nil {
+		payload = cfg.Scheme.Payload(r, ts, body)
+	}
+	newHash := cfg.Scheme.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	for _, secret := range cfg.Secrets {
+		m := hmac.New(newHash, secret)
+		m.Write(payload)
+		want := m.Sum(nil)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, want) {
+				return nil
+			}
+		}
+	}
+	return invalidSignatureError("signature mismatch")
+}
+
+func invalidSignatureError(detail string) AppError {
+	return UnauthorizedError("invalid webhook signature: %s", detail).WithCode("invalid_signature")
+}
+
+// WebhookSignature verifies webhook requests before they reach the handler.
+// The body is buffered for verification, or taken from BufferBody, and
+// replayed to the handler unchanged.
+func WebhookSignature(adapter *HandlerAdapter, cfg WebhookConfig) Middleware {
+	maxBody := cmp.Or(cfg.MaxBody, DefaultWebhookMaxBody)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBody {
+				adapter.HandleError(w, r, bodyTooLargeError(maxBody))
+				return
+			}
+			body, buffered := BufferedBody(r.Context())
+			if !buffered {
+				var err error
+				if body, err = io.ReadAll(io.LimitReader(r.Body, maxBody+1)); err != nil {
+					adapter.HandleError(w, r, err)
+					return
+				}
+			}
+			if int64(len(body)) > maxBody {
+				adapter.HandleError(w, r, bodyTooLargeError(maxBody))
+				return
+			}
+			if err := VerifyWebhook(r, body, cfg); err != nil {
+				adapter.HandleError(w, r, err)
+				return
+			}
+			if !ReplayBody(r) {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	adapter := NewDefaultHandlerAdapter(NewConfig())
+	oldSecret, newSecret := []byte("whsec_old"), []byte("whsec_new")
+	body := `{"event":"push"}`
+	sign := func(secret []byte, payload string) string {
+		m := hmac.New(sha256.New, secret)
+		m.Write([]byte(payload))
+		return hex.EncodeToString(m.Sum(nil))
+	}
+	stripe := func(ts time.Time, secrets ...[]byte) string {
+		header := fmt.Sprintf("t=%d", ts.Unix())
+		for _, secret := range secrets {
+			header += ",v1=" + sign(secret, fmt.Sprintf("%d.%s", ts.Unix(), body))
+		}
+		return header
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		scheme WebhookScheme
+		header string
+		value  string
+		body   string
+		status int
+	}{
+		{name: "github valid", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=" + sign(oldSecret, body), status: http.StatusOK},
+		{name: "github rotated secret", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=" + sign(newSecret, body), status: http.StatusOK},
+		{name: "github unknown secret", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=" + sign([]byte("other"), body), status: http.StatusUnauthorized},
+		{name: "github tampered body", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=" + sign(oldSecret, body), body: `{"event":"delete"}`, status: http.StatusUnauthorized},
+		{name: "github missing prefix", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: sign(oldSecret, body), status: http.StatusUnauthorized},
+		{name: "github malformed", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=zz", status: http.StatusUnauthorized},
+		{name: "github missing", scheme: GitHubWebhook, status: http.StatusUnauthorized},
+		{name: "stripe valid", scheme: StripeWebhook, header: "Stripe-Signature", value: stripe(now, oldSecret), status: http.StatusOK},
+		{name: "stripe one of several signatures", scheme: StripeWebhook, header: "Stripe-Signature", value: stripe(now, []byte("other"), newSecret), status: http.StatusOK},
+		{name: "stripe stale timestamp", scheme: StripeWebhook, header: "Stripe-Signature", value: stripe(now.Add(-DefaultWebhookTolerance-time.Minute), oldSecret), status: http.StatusUnauthorized},
+		{name: "stripe future timestamp", scheme: StripeWebhook, header: "Stripe-Signature", value: stripe(now.Add(DefaultWebhookTolerance+time.Minute), oldSecret), status: http.StatusUnauthorized},
+		{name: "stripe timestamp not signed", scheme: StripeWebhook, header: "Stripe-Signature", value: fmt.Sprintf("t=%d,v1=%s", now.Unix(), sign(oldSecret, body)), status: http.StatusUnauthorized},
+		{name: "stripe missing timestamp", scheme: StripeWebhook, header: "Stripe-Signature", value: "v1=" + sign(oldSecret, body), status: http.StatusUnauthorized},
+		{name: "body over limit", scheme: GitHubWebhook, header: "X-Hub-Signature-256", value: "sha256=" + sign(oldSecret, strings.Repeat("x", 65)), body: strings.Repeat("x", 65), status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			h := WebhookSignature(adapter, WebhookConfig{
+				Scheme:  tt.scheme,
+				Secrets: [][]byte{newSecret, oldSecret},
+				MaxBody: 64,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				received = string(b)
+			}))
+
+			sent := body
+			if tt.body != "" {
+				sent = tt.body
+			}
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(sent))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK && received != sent {
+				t.Errorf("handler read %q, want %q", received, sent)
+			}
+			if tt.status == http.StatusUnauthorized && !strings.Contains(w.Body.String(), "invalid_signature") {
+				t.Errorf("body %s lacks the invalid_signature code", w.Body)
+			}
+		})
+	}
+}
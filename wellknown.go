@@ -0,0 +1,202 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDiscoveryTTL is how long MountWellKnown caches the OIDC discovery
+// document.
+const DefaultDiscoveryTTL = time.Hour
+
+// robotsDisallowAll keeps crawlers off non-production deployments.
+const robotsDisallowAll = "User-agent: *\nDisallow: /\n"
+
+type (
+	// WellKnownConfig selects the endpoints MountWellKnown registers. Each
+	// is optional; robots.txt is always served.
+	WellKnownConfig struct {
+		// SecurityTxt is served at /.well-known/security.txt (RFC 9116)
+		// when it has a Contact.
+		SecurityTxt SecurityTxt
+		// ChangePassword is where /.well-known/change-password redirects
+		// password managers to.
+		ChangePassword string
+		// OIDCIssuer, such as "https://accounts.example.com", makes
+		// /.well-known/openid-configuration serve the issuer's discovery
+		// document, for clients that discover the identity provider
+		// through the API's origin.
+		OIDCIssuer string
+		// OIDCClient fetches the discovery document; http.DefaultClient
+		// if nil.
+		OIDCClient *http.Client
+		// DiscoveryTTL is how long the document is cached;
+		// DefaultDiscoveryTTL if zero.
+		DiscoveryTTL time.Duration
+		// Robots is the robots.txt of production; "User-agent: *" with an
+		// empty Disallow, allowing everything, if empty. Other
+		// environments always disallow everything.
+		Robots string
+	}
+
+	// SecurityTxt is the content of security.txt. Contact is required;
+	// Expires defaults to a year after MountWellKnown.
+	SecurityTxt struct {
+		Contact            []string
+		Expires            time.Time
+		Encryption         []string
+		Acknowledgments    []string
+		PreferredLanguages []string
+		Canonical          []string
+		Policy             []string
+		Hiring             []string
+	}
+
+	oidcDiscovery struct {
+		url    string
+		client *http.Client
+		ttl    time.Duration
+
+		mu        sync.Mutex
+		doc       []byte
+		fetchedAt time.Time
+	}
+)
+
+// MountWellKnown registers /robots.txt and the /.well-known/ endpoints
+// configured in cfg on router, served through adapter so failures render
+// like any other error. robots.txt disallows everything outside production,
+// as told by config, keeping staging and preview deployments out of search
+// engines.
+func MountWellKnown(router Router, adapter *HandlerAdapter, config AppConfig, cfg WellKnownConfig) {
+	robots := cfg.Robots
+	if robots == "" {
+		robots = "User-agent: *\nDisallow:\n"
+	}
+	if !isProduction(config) {
+		robots = robotsDisallowAll
+	}
+	router.Handle("GET /robots.txt", adapter.Handle(textHandler(robots)))
+
+	if len(cfg.SecurityTxt.Contact) > 0 {
+		router.Handle("GET /.well-known/security.txt", adapter.Handle(textHandler(cfg.SecurityTxt.String())))
+	}
+
+	if cfg.ChangePassword != "" {
+		router.Handle("GET /.well-known/change-password", adapter.Handle(func(w http.ResponseWriter, r *http.Request) error {
+			http.Redirect(w, r, cfg.ChangePassword, http.StatusFound)
+			return nil
+		}))
+	}
+
+	if cfg.OIDCIssuer != "" {
+		d := &oidcDiscovery{
+			url:    strings.TrimSuffix(cfg.OIDCIssuer, "/") + "/.well-known/openid-configuration",
+			client: cfg.OIDCClient,
+			ttl:    cfg.DiscoveryTTL,
+		}
+		if d.client == nil {
+			d.client = http.DefaultClient
+		}
+		if d.ttl <= 0 {
+			d.ttl = DefaultDiscoveryTTL
+		}
+		router.Handle("GET /.well-known/openid-configuration", adapter.Handle(d.serve))
+	}
+}
+
+// String renders s in the security.txt format.
+func (s SecurityTxt) String() string {
+	expires := s.Expires
+	if expires.IsZero() {
+		expires = clockNow().AddDate(1, 0, 0)
+	}
+
+	var b strings.Builder
+	field := func(name string, values []string) {
+		for _, v := range values {
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+	field("Contact", s.Contact)
+	field("Expires", []string{expires.UTC().Format(time.RFC3339)})
+	field("Encryption", s.Encryption)
+	field("Acknowledgments", s.Acknowledgments)
+	if len(s.PreferredLanguages) > 0 {
+		field("Preferred-Languages", []string{strings.Join(s.PreferredLanguages, ", ")})
+	}
+	field("Canonical", s.Canonical)
+	field("Policy", s.Policy)
+	field("Hiring", s.Hiring)
+	return b.String()
+}
+
+func textHandler(body string) HTTPHandlerExt {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err := io.WriteString(w, body)
+		return err
+	}
+}
+
+// serve answers with the cached discovery document, refetching it once
+// expired. A failed refetch keeps serving the previous document.
+func (d *oidcDiscovery) serve(w http.ResponseWriter, r *http.Request) error {
+	doc, err := d.document(r.Context())
+	if err != nil {
+		return WrapUpstream("oidc", "GET "+d.url, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(d.ttl/time.Second)))
+	_, err = w.Write(doc)
+	return err
+}
+
+func (d *oidcDiscovery) document(ctx context.Context) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.doc != nil && clockSince(d.fetchedAt) < d.ttl {
+		return d.doc, nil
+	}
+
+	doc, err := d.fetch(ctx)
+	if err != nil {
+		if d.doc != nil {
+			return d.doc, nil
+		}
+		return nil, err
+	}
+	d.doc, d.fetchedAt = doc, clockNow()
+	return doc, nil
+}
+
+func (d *oidcDiscovery) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// isProduction reports whether config is a production configuration:
+// neither development nor another environment it names.
+func isProduction(config AppConfig) bool {
+	if config == nil || config.IsDevelopment() {
+		return false
+	}
+	env, ok := config.(interface{ Environment() string })
+	return !ok || env.Environment() == EnvProduction
+}
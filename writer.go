@@ -0,0 +1,304 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// hookWriter calls beforeHeader once, right before the status line is
+// written, giving middleware a last chance to adjust headers based on the
+// final status.
+type hookWriter struct {
+	http.ResponseWriter
+	beforeHeader func(h http.Header, status int)
+	wroteHeader  bool
+}
+
+func (w *hookWriter) WriteHeader(status int) {
+	// Informational responses do not finalize the header.
+	if !w.wroteHeader && (status < 100 || status > 199) {
+		w.wroteHeader = true
+		w.beforeHeader(w.Header(), status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *hookWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hookWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *hookWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *hookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// deadlines and full duplex keep working through the wrapper.
+func (w *hookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusWriter sends a fixed status regardless of what the wrapped code
+// passes to WriteHeader.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(int) {
+	w.commit()
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) commit() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// commitWriter records whether, and with which status, the response
+// was committed, and how many body bytes were written.
+type commitWriter struct {
+	http.ResponseWriter
+	committed bool
+	status    int
+	written   int64
+	pooled    bool
+	// policy is applied to the header when the response is committed.
+	policy *HeaderPolicy
+}
+
+var commitWriterPool = sync.Pool{New: func() interface{} { return new(commitWriter) }}
+
+// newCommitWriter wraps w for a handler of a; releaseCommitWriter returns
+// it once the handler is done. Writers are pooled unless misuse detection
+// is on: it watches for writes after the handler returned, which must not
+// reach a writer already serving another request.
+func (a *HandlerAdapter) newCommitWriter(w http.ResponseWriter) *commitWriter {
+	if a.PanicOnMisuse || len(a.onMisuse) > 0 {
+		return &commitWriter{ResponseWriter: w, policy: a.HeaderPolicy}
+	}
+	cw := getCommitWriter(w)
+	cw.policy = a.HeaderPolicy
+	return cw
+}
+
+// getCommitWriter takes a commitWriter for w from the pool.
+func getCommitWriter(w http.ResponseWriter) *commitWriter {
+	cw := commitWriterPool.Get().(*commitWriter)
+	cw.ResponseWriter, cw.pooled = w, true
+	return cw
+}
+
+// releaseCommitWriter also applies the header policy to responses that
+// were never written, which net/http sends as an empty 200.
+func releaseCommitWriter(cw *commitWriter) {
+	if !cw.committed && cw.policy != nil {
+		cw.policy.apply(cw.Header())
+	}
+	if cw.pooled {
+		*cw = commitWriter{}
+		commitWriterPool.Put(cw)
+	}
+}
+
+func (w *commitWriter) WriteHeader(status int) {
+	if !w.committed && (status < 100 || status > 199) {
+		w.commit(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *commitWriter) Write(b []byte) (int, error) {
+	if !w.committed {
+		w.commit(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *commitWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *commitWriter) FlushError() error {
+	if !w.committed {
+		w.commit(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *commitWriter) commit(status int) {
+	w.committed = true
+	w.status = status
+	if w.policy != nil {
+		w.policy.apply(w.Header())
+	}
+}
+
+func (w *commitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *commitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordWriter passes the response through while keeping a copy of the
+// status, headers and up to limit bytes of body, so middleware can store
+// the response for replay. overflow is set once the body exceeds limit.
+type recordWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *recordWriter) WriteHeader(status int) {
+	w.ResponseWriter.WriteHeader(status)
+	// Cloned afterwards to include headers that outer wrappers add on
+	// WriteHeader, such as Cache-Control from a CachePolicy.
+	if w.status == 0 && (status < 100 || status > 199) {
+		w.status = status
+		w.header = w.Header().Clone()
+	}
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if w.body.Len()+len(b) > w.limit {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *recordWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *recordWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.overflow = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recorded returns the captured response, or false if it is incomplete.
+// Trailers set after the header was written are included in
+// http.TrailerPrefix form, so replaying the header sends them as trailers.
+func (w *recordWriter) recorded() (status int, header http.Header, body []byte, ok bool) {
+	if w.overflow {
+		return 0, nil, nil, false
+	}
+	if w.status == 0 {
+		w.status, w.header = http.StatusOK, w.Header().Clone()
+	}
+	for k, v := range trailers(w.Header()) {
+		w.header[http.TrailerPrefix+k] = v
+	}
+	return w.status, w.header, w.body.Bytes(), true
+}
+
+// perRequestHeaders describe the request that produced a response rather
+// than the response, so they are not replayed to other requests.
+var perRequestHeaders = []string{
+	"Date", RequestIDHeader, ErrorReferenceHeader, TraceparentHeader, TracestateHeader,
+	"Server-Timing", CacheStatusHeader, CoalescedHeader,
+}
+
+// deletePerRequestHeaders removes perRequestHeaders from h.
+func deletePerRequestHeaders(h http.Header) {
+	for _, name := range perRequestHeaders {
+		h.Del(name)
+	}
+}
+
+// trailers returns the trailer values set in h: those of keys declared in
+// the Trailer header and those set with http.TrailerPrefix.
+func trailers(h http.Header) http.Header {
+	t := http.Header{}
+	for _, v := range h.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if vals, ok := h[k]; ok {
+				t[k] = append([]string(nil), vals...)
+			}
+		}
+	}
+	for k, vals := range h {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			t[http.CanonicalHeaderKey(name)] = append([]string(nil), vals...)
+		}
+	}
+	return t
+}
+
+// deferTrailers moves values of declared trailers into http.TrailerPrefix
+// form. Writers that buffer the body and send the header only after the
+// handler returned call it first, as by then the handler has set its
+// trailers and net/http would otherwise send them as headers.
+func deferTrailers(h http.Header) {
+	for _, v := range h.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if vals, ok := h[k]; ok {
+				h[http.TrailerPrefix+k] = vals
+				delete(h, k)
+			}
+		}
+	}
+}
+
+// SetTrailer sets a response trailer. It works before or after the body
+// is written, without declaring the trailer up front, and through every
+// httpx writer. Trailers need a chunked HTTP/1.1 or an HTTP/2 response;
+// clients over other transports do not receive them.
+func SetTrailer(w http.ResponseWriter, name, value string) {
+	w.Header().Set(http.TrailerPrefix+http.CanonicalHeaderKey(name), value)
+}
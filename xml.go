@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+)
+
+type (
+	// XMLRenderer renders errors as application/xml for integrations that
+	// require it. 500 responses carry the ErrorInfo details when they are
+	// provided.
+	XMLRenderer struct{}
+
+	xmlError struct {
+		XMLName xml.Name `xml:"error"`
+		Status  int      `xml:"status"`
+		Message string   `xml:"message"`
+		Code    string   `xml:"code,omitempty"`
+		// RetryAfter is AppError.RetryAfter in seconds.
+		RetryAfter int    `xml:"retry_after,omitempty"`
+		Reference  string `xml:"reference,omitempty"`
+		Cause      string `xml:"cause,omitempty"`
+		Stack      string `xml:"stack,omitempty"`
+	}
+)
+
+var _ Renderer = XMLRenderer{}
+
+// XML writes v as an XML document with status. v is encoded before anything
+// is written, so encoding errors can still be rendered by the adapter.
+func XML(w http.ResponseWriter, status int, v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (XMLRenderer) Render500(_ context.Context, w http.ResponseWriter, errInfo *ErrorInfo) {
+	e := xmlError{
+		Status:  http.StatusInternalServerError,
+		Message: http.StatusText(http.StatusInternalServerError),
+	}
+	if errInfo != nil {
+		e.Message = errInfo.Message
+		e.Reference, e.Cause, e.Stack = errInfo.Reference, errInfo.Cause, errInfo.Stack
+	}
+	XML(w, e.Status, e)
+}
+
+func (XMLRenderer) RenderAppError(_ context.Context, w http.ResponseWriter, appErr AppError) {
+	XML(w, appErr.StatusCode, xmlError{
+		Status:     appErr.StatusCode,
+		Message:    appErr.Error(),
+		Code:       appErr.Code,
+		RetryAfter: retryAfterSeconds(appErr.RetryAfter),
+	})
+}
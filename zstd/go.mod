@@ -0,0 +1,10 @@
+module github.com/radim/httpx/zstd
+
+go 1.24
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/radim/httpx v0.0.0
+)
+
+replace github.com/radim/httpx => ../
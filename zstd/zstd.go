@@ -0,0 +1,97 @@
+// Package zstd provides zstd response encodings for the httpx Compress
+// middleware, including dictionary-compressed zstd ("dcz") for clients that
+// advertise a shared dictionary.
+package zstd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	kzstd "github.com/klauspost/compress/zstd"
+
+	"github.com/radim/httpx"
+)
+
+// dczMagic prefixes dictionary-compressed zstd streams, followed by the
+// SHA-256 of the dictionary.
+var dczMagic = []byte{0x5e, 0x2a, 0x4d, 0x18, 0x20, 0x00, 0x00, 0x00}
+
+type pooledEncoder struct {
+	*kzstd.Encoder
+	pool *sync.Pool
+}
+
+// Encoding returns the "zstd" encoding at the given level. Each response is
+// compressed on a single goroutine so that httpx.CompressConfig.MaxConcurrent
+// maps directly to CPU cores spent on compression.
+func Encoding(level kzstd.EncoderLevel) httpx.Encoding {
+	pool := encoderPool(kzstd.WithEncoderLevel(level))
+
+	return httpx.Encoding{
+		Name: "zstd",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return getEncoder(pool, w)
+		},
+	}
+}
+
+// DictionaryEncoding returns the "dcz" encoding using dict as the shared
+// dictionary. It is only negotiated when the client's Available-Dictionary
+// header matches dict.
+func DictionaryEncoding(dict []byte, level kzstd.EncoderLevel) httpx.Encoding {
+	hash := sha256.Sum256(dict)
+	available := ":" + base64.StdEncoding.EncodeToString(hash[:]) + ":"
+	pool := encoderPool(kzstd.WithEncoderLevel(level), kzstd.WithEncoderDictRaw(0, dict))
+
+	return httpx.Encoding{
+		Name: "dcz",
+		Accept: func(r *http.Request) bool {
+			return strings.TrimSpace(r.Header.Get("Available-Dictionary")) == available
+		},
+		Vary: []string{"Available-Dictionary"},
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			if _, err := w.Write(dczMagic); err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(hash[:]); err != nil {
+				return nil, err
+			}
+			return getEncoder(pool, w)
+		},
+	}
+}
+
+func encoderPool(opts ...kzstd.EOption) *sync.Pool {
+	opts = append(opts, kzstd.WithEncoderConcurrency(1))
+	return &sync.Pool{
+		New: func() interface{} {
+			enc, err := kzstd.NewWriter(nil, opts...)
+			if err != nil {
+				return err
+			}
+			return enc
+		},
+	}
+}
+
+func getEncoder(pool *sync.Pool, w io.Writer) (io.WriteCloser, error) {
+	switch v := pool.Get().(type) {
+	case error:
+		return nil, v
+	case *kzstd.Encoder:
+		v.Reset(w)
+		return &pooledEncoder{Encoder: v, pool: pool}, nil
+	}
+	panic("unreachable")
+}
+
+func (e *pooledEncoder) Close() error {
+	err := e.Encoder.Close()
+	e.Encoder.Reset(nil)
+	e.pool.Put(e.Encoder)
+	return err
+}